@@ -0,0 +1,60 @@
+// Package batch provides batch publishing and batched consumption for brokers
+package batch
+
+import (
+	"fmt"
+	"strings"
+
+	"go-micro.org/v5/broker"
+)
+
+// Publisher is implemented by brokers which can publish several messages
+// with a single call. The returned slice is nil if all messages were
+// published, otherwise it holds the error of every message by index.
+type Publisher interface {
+	PublishBatch(topic string, msgs []*broker.Message, opts ...broker.PublishOption) []error
+}
+
+// Errors holds the error of every message of a batch by index, nil entries
+// were published successfully.
+type Errors []error
+
+func (e Errors) Error() string {
+	var failed []string
+	for i, err := range e {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("message %d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("%d of %d messages failed: %s", len(failed), len(e), strings.Join(failed, "; "))
+}
+
+// Publish publishes msgs to topic using the native batch support of b if
+// it implements Publisher, or one by one otherwise. If any message failed
+// the returned error is of type Errors.
+func Publish(b broker.Broker, topic string, msgs []*broker.Message, opts ...broker.PublishOption) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if p, ok := b.(Publisher); ok {
+		errs = p.PublishBatch(topic, msgs, opts...)
+	} else {
+		for i, msg := range msgs {
+			if err := b.Publish(topic, msg, opts...); err != nil {
+				if errs == nil {
+					errs = make([]error, len(msgs))
+				}
+				errs[i] = err
+			}
+		}
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return Errors(errs)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package batch
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/broker"
+)
+
+func newBroker(t *testing.T) broker.Broker {
+	b := broker.NewMemoryBroker()
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Disconnect() })
+	return b
+}
+
+type failingBroker struct {
+	broker.Broker
+}
+
+func (f *failingBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	if string(msg.Body) == "bad" {
+		return errors.New("bad message")
+	}
+	return f.Broker.Publish(topic, msg, opts...)
+}
+
+func TestPublishErrors(t *testing.T) {
+	b := &failingBroker{Broker: newBroker(t)}
+
+	err := Publish(b, "test", []*broker.Message{
+		{Body: []byte("good")},
+		{Body: []byte("bad")},
+		{Body: []byte("good")},
+	})
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected batch errors, got %v", err)
+	}
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	if err := Publish(b, "test", []*broker.Message{{Body: []byte("good")}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	b := newBroker(t)
+
+	received := make(chan struct{}, 10)
+	if _, err := b.Subscribe("test", func(e broker.Event) error {
+		received <- struct{}{}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := NewBuffer(b, Size(3), Linger(time.Hour))
+	var results []*Result
+	for i := 0; i < 3; i++ {
+		results = append(results, buf.Publish("test", &broker.Message{Body: []byte("msg")}))
+	}
+	for _, r := range results {
+		if err := r.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(received) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(received))
+	}
+
+	r := buf.Publish("test", &broker.Message{Body: []byte("msg")})
+	select {
+	case <-r.Done():
+		t.Fatal("batch published before it was full")
+	default:
+	}
+	buf.Close()
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.Publish("test", &broker.Message{}).Err(); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestBufferLinger(t *testing.T) {
+	b := newBroker(t)
+
+	buf := NewBuffer(b, Size(100), Linger(10*time.Millisecond))
+	defer buf.Close()
+
+	r := buf.Publish("test", &broker.Message{Body: []byte("msg")})
+	select {
+	case <-r.Done():
+	case <-time.After(time.Second):
+		t.Fatal("batch was not published after linger")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	b := newBroker(t)
+
+	batches := make(chan int, 10)
+	sub, err := Subscribe(b, "test", func(events []broker.Event) error {
+		batches <- len(events)
+		return nil
+	}, Size(2), Linger(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := 0; i < 5; i++ {
+		if err := b.Publish("test", &broker.Message{Body: []byte("msg")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []int{2, 2, 1} {
+		select {
+		case got := <-batches:
+			if got != want {
+				t.Fatalf("expected batch of %d, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected batch of %d", want)
+		}
+	}
+}
+
+func TestUnsubscribeError(t *testing.T) {
+	b := newBroker(t)
+
+	sub, err := Subscribe(b, "test", func(events []broker.Event) error {
+		return errors.New("handler failed")
+	}, Size(10), Linger(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("test", &broker.Message{Body: []byte("msg")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the partial batch is handled on unsubscribe
+	time.Sleep(50 * time.Millisecond)
+	if err := sub.Unsubscribe(); err == nil || err.Error() != "handler failed" {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package batch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go-micro.org/v5/broker"
+)
+
+var (
+	DefaultSize   = 100
+	DefaultLinger = 10 * time.Millisecond
+
+	ErrClosed = errors.New("buffer closed")
+)
+
+// Result of a buffered publish.
+type Result struct {
+	done chan struct{}
+	err  error
+}
+
+// Done is closed once the message has been published or has failed.
+func (r *Result) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err waits for the message to be published and returns its error.
+func (r *Result) Err() error {
+	<-r.done
+	return r.err
+}
+
+type pending struct {
+	msgs    []*broker.Message
+	results []*Result
+	timer   *time.Timer
+}
+
+// Buffer collects published messages per topic and publishes them as a
+// batch once Size messages are buffered or Linger has passed.
+type Buffer struct {
+	b    broker.Broker
+	opts Options
+
+	sync.Mutex
+	closed  bool
+	pending map[string]*pending
+	wg      sync.WaitGroup
+}
+
+// NewBuffer returns a buffer publishing batches to b.
+func NewBuffer(b broker.Broker, opts ...Option) *Buffer {
+	return &Buffer{
+		b:       b,
+		opts:    newOptions(opts...),
+		pending: make(map[string]*pending),
+	}
+}
+
+// Publish adds msg to the batch of topic. The returned result reports the
+// error of this message once its batch has been published.
+func (b *Buffer) Publish(topic string, msg *broker.Message) *Result {
+	r := &Result{done: make(chan struct{})}
+
+	b.Lock()
+	if b.closed {
+		b.Unlock()
+		r.err = ErrClosed
+		close(r.done)
+		return r
+	}
+
+	p, ok := b.pending[topic]
+	if !ok {
+		p = &pending{}
+		b.pending[topic] = p
+		if b.opts.Linger > 0 {
+			p.timer = time.AfterFunc(b.opts.Linger, func() {
+				b.flushTopic(topic, p)
+			})
+		}
+	}
+	p.msgs = append(p.msgs, msg)
+	p.results = append(p.results, r)
+
+	var full *pending
+	if len(p.msgs) >= b.opts.Size {
+		full = b.take(topic, p)
+	}
+	b.Unlock()
+
+	if full != nil {
+		b.publish(topic, full)
+	}
+	return r
+}
+
+// Flush publishes all buffered messages and waits for the batches to finish.
+func (b *Buffer) Flush() {
+	b.Lock()
+	batches := make(map[string]*pending, len(b.pending))
+	for topic, p := range b.pending {
+		batches[topic] = b.take(topic, p)
+	}
+	b.Unlock()
+
+	for topic, p := range batches {
+		b.publish(topic, p)
+	}
+	b.wg.Wait()
+}
+
+// Close flushes the buffer. Messages published afterwards fail with ErrClosed.
+func (b *Buffer) Close() error {
+	b.Lock()
+	b.closed = true
+	b.Unlock()
+
+	b.Flush()
+	return nil
+}
+
+// take removes the pending batch of topic, the lock must be held.
+func (b *Buffer) take(topic string, p *pending) *pending {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	delete(b.pending, topic)
+	b.wg.Add(1)
+	return p
+}
+
+func (b *Buffer) flushTopic(topic string, p *pending) {
+	b.Lock()
+	if b.pending[topic] != p {
+		// already flushed because it filled up
+		b.Unlock()
+		return
+	}
+	b.take(topic, p)
+	b.Unlock()
+
+	b.publish(topic, p)
+}
+
+func (b *Buffer) publish(topic string, p *pending) {
+	defer b.wg.Done()
+
+	err := Publish(b.b, topic, p.msgs, b.opts.PublishOptions...)
+	errs, _ := err.(Errors)
+	for i, r := range p.results {
+		switch {
+		case errs != nil:
+			r.err = errs[i]
+		case err != nil:
+			r.err = err
+		}
+		close(r.done)
+	}
+}
@@ -0,0 +1,64 @@
+package batch
+
+import (
+	"time"
+
+	"go-micro.org/v5/broker"
+)
+
+// Options of the buffer and the batch subscriber.
+type Options struct {
+	// Size is the number of messages a batch is flushed at.
+	Size int
+	// Linger is how long the first message of a batch waits for more
+	// messages before the batch is flushed regardless of its size.
+	Linger time.Duration
+	// PublishOptions are passed to every batch publish of the buffer.
+	PublishOptions []broker.PublishOption
+	// SubscribeOptions are passed to the broker subscription.
+	SubscribeOptions []broker.SubscribeOption
+}
+
+type Option func(o *Options)
+
+// Size sets the number of messages a batch is flushed at.
+func Size(n int) Option {
+	return func(o *Options) {
+		o.Size = n
+	}
+}
+
+// Linger sets how long a batch waits to fill up before it is flushed.
+func Linger(d time.Duration) Option {
+	return func(o *Options) {
+		o.Linger = d
+	}
+}
+
+// PublishOptions sets the options passed to every batch publish.
+func PublishOptions(opts ...broker.PublishOption) Option {
+	return func(o *Options) {
+		o.PublishOptions = opts
+	}
+}
+
+// SubscribeOptions sets the options of the broker subscription.
+func SubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(o *Options) {
+		o.SubscribeOptions = opts
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Size:   DefaultSize,
+		Linger: DefaultLinger,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Size <= 0 {
+		options.Size = 1
+	}
+	return options
+}
@@ -0,0 +1,132 @@
+package batch
+
+import (
+	"sync"
+	"time"
+
+	"go-micro.org/v5/broker"
+	log "go-micro.org/v5/logger"
+)
+
+// Handler handles a batch of events. The events of a batch are acked once
+// the handler returns without error, unless auto ack was disabled.
+type Handler func(events []broker.Event) error
+
+type subscriber struct {
+	broker.Subscriber
+
+	opts    Options
+	handler Handler
+	autoAck bool
+
+	// serialises the calls of handler
+	hmtx sync.Mutex
+
+	sync.Mutex
+	events []broker.Event
+	timer  *time.Timer
+}
+
+// Subscribe subscribes handler to topic and delivers the events in batches
+// of Size, or fewer once Linger has passed since the first event of the
+// batch. The broker subscription acks manually, so events are only acked
+// after their batch was handled.
+func Subscribe(b broker.Broker, topic string, handler Handler, opts ...Option) (broker.Subscriber, error) {
+	options := newOptions(opts...)
+
+	subOpts := broker.SubscribeOptions{AutoAck: true}
+	for _, o := range options.SubscribeOptions {
+		o(&subOpts)
+	}
+
+	s := &subscriber{
+		opts:    options,
+		handler: handler,
+		autoAck: subOpts.AutoAck,
+	}
+
+	sub, err := b.Subscribe(topic, s.handle, append(options.SubscribeOptions, broker.DisableAutoAck())...)
+	if err != nil {
+		return nil, err
+	}
+	s.Subscriber = sub
+	return s, nil
+}
+
+// Unsubscribe unsubscribes from the broker and handles the partial batch.
+// An error of the handler is returned unless unsubscribing failed too.
+func (s *subscriber) Unsubscribe() error {
+	err := s.Subscriber.Unsubscribe()
+
+	s.Lock()
+	events := s.take()
+	s.Unlock()
+	if len(events) > 0 {
+		if derr := s.deliver(events); derr != nil && err == nil {
+			err = derr
+		}
+	}
+	return err
+}
+
+func (s *subscriber) handle(e broker.Event) error {
+	s.Lock()
+	s.events = append(s.events, e)
+	if len(s.events) == 1 && s.opts.Linger > 0 {
+		s.timer = time.AfterFunc(s.opts.Linger, s.flush)
+	}
+	var events []broker.Event
+	if len(s.events) >= s.opts.Size {
+		events = s.take()
+	}
+	s.Unlock()
+
+	if events == nil {
+		return nil
+	}
+	// handled in the broker's goroutine to apply back pressure
+	return s.deliver(events)
+}
+
+// flush handles the partial batch once Linger has passed. There is no
+// broker goroutine to return an error to, so it is logged.
+func (s *subscriber) flush() {
+	s.Lock()
+	events := s.take()
+	s.Unlock()
+	if len(events) == 0 {
+		return
+	}
+	if err := s.deliver(events); err != nil {
+		log.Errorf("[batch]: failed to handle batch of %d events on %s: %v", len(events), s.Topic(), err)
+	}
+}
+
+// take returns the current batch, the lock must be held.
+func (s *subscriber) take() []broker.Event {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	events := s.events
+	s.events = nil
+	return events
+}
+
+func (s *subscriber) deliver(events []broker.Event) error {
+	s.hmtx.Lock()
+	defer s.hmtx.Unlock()
+
+	if err := s.handler(events); err != nil {
+		return err
+	}
+	if !s.autoAck {
+		return nil
+	}
+	for _, e := range events {
+		if err := e.Ack(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Package delay provides delayed message delivery for brokers without native support
+package delay
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.org/v5/broker"
+	log "go-micro.org/v5/logger"
+	"go-micro.org/v5/store"
+	gosync "go-micro.org/v5/sync"
+)
+
+var (
+	DefaultPrefix   = "micro/delay/"
+	DefaultInterval = time.Second
+	// LockTTL is the ttl of the lock taken with a shared Sync.
+	LockTTL = time.Minute
+)
+
+type delayBroker struct {
+	broker.Broker
+	opts Options
+
+	sync.Mutex
+	exit chan struct{}
+}
+
+// record is a scheduled message as written to the store.
+type record struct {
+	Topic  string            `json:"topic"`
+	Header map[string]string `json:"header"`
+	Body   []byte            `json:"body"`
+}
+
+// NewBroker wraps b so that messages published with Delay or At are kept in
+// a store and published to b once they are due. Only the delay option is
+// honoured for scheduled messages, other publish options are dropped.
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	options := Options{
+		Store:    store.DefaultStore,
+		Prefix:   DefaultPrefix,
+		Interval: DefaultInterval,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &delayBroker{Broker: b, opts: options}
+}
+
+func (d *delayBroker) Connect() error {
+	if err := d.Broker.Connect(); err != nil {
+		return err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	if d.exit == nil {
+		d.exit = make(chan struct{})
+		go d.run(d.exit)
+	}
+	return nil
+}
+
+func (d *delayBroker) Disconnect() error {
+	d.Lock()
+	if d.exit != nil {
+		close(d.exit)
+		d.exit = nil
+	}
+	d.Unlock()
+	return d.Broker.Disconnect()
+}
+
+func (d *delayBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	options := broker.PublishOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	at, ok := FromContext(options.Context)
+	if !ok || !at.After(time.Now()) {
+		return d.Broker.Publish(topic, msg, opts...)
+	}
+
+	b, err := json.Marshal(&record{Topic: topic, Header: msg.Header, Body: msg.Body})
+	if err != nil {
+		return err
+	}
+
+	// keys sort by due time so the scheduler can stop at the first future one
+	key := fmt.Sprintf("%s%020d/%s", d.opts.Prefix, at.UnixNano(), uuid.New().String())
+	return d.opts.Store.Write(&store.Record{Key: key, Value: b})
+}
+
+func (d *delayBroker) String() string {
+	return d.Broker.String()
+}
+
+func (d *delayBroker) run(exit chan struct{}) {
+	t := time.NewTicker(d.opts.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+			d.deliver(time.Now())
+		}
+	}
+}
+
+// deliver publishes all messages due at now. Reading and deleting a message
+// is its claim, which is only atomic while the schedulers sharing the store
+// take turns through a shared Sync.
+func (d *delayBroker) deliver(now time.Time) {
+	if s := d.opts.Sync; s != nil {
+		// another scheduler is delivering, try again on the next tick
+		if err := s.Lock(d.opts.Prefix, gosync.LockTTL(LockTTL), gosync.LockWait(d.opts.Interval)); err == gosync.ErrLockTimeout {
+			return
+		} else if err != nil {
+			log.Errorf("[delay]: failed to lock scheduled messages: %v", err)
+			return
+		}
+		defer s.Unlock(d.opts.Prefix)
+	}
+
+	keys, err := d.opts.Store.List(store.ListPrefix(d.opts.Prefix))
+	if err != nil {
+		log.Errorf("[delay]: failed to list scheduled messages: %v", err)
+		return
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		at, ok := dueTime(strings.TrimPrefix(key, d.opts.Prefix))
+		if !ok {
+			continue
+		}
+		if at.After(now) {
+			return
+		}
+
+		recs, err := d.opts.Store.Read(key)
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil || len(recs) == 0 {
+			log.Errorf("[delay]: failed to read scheduled message %s: %v", key, err)
+			continue
+		}
+
+		var r record
+		if err := json.Unmarshal(recs[0].Value, &r); err != nil {
+			log.Errorf("[delay]: failed to unmarshal scheduled message %s: %v", key, err)
+			continue
+		}
+
+		// delete first so schedulers taking the lock next skip it
+		if err := d.opts.Store.Delete(key); err != nil {
+			log.Errorf("[delay]: failed to delete scheduled message %s: %v", key, err)
+			continue
+		}
+
+		if err := d.Broker.Publish(r.Topic, &broker.Message{Header: r.Header, Body: r.Body}); err != nil {
+			log.Errorf("[delay]: failed to publish scheduled message to %s: %v", r.Topic, err)
+			// put it back to retry on the next tick
+			if err := d.opts.Store.Write(recs[0]); err != nil {
+				log.Errorf("[delay]: failed to reschedule message %s: %v", key, err)
+			}
+		}
+	}
+}
+
+func dueTime(key string) (time.Time, bool) {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return time.Time{}, false
+	}
+	ns, err := strconv.ParseInt(key[:i], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
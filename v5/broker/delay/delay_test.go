@@ -0,0 +1,146 @@
+package delay
+
+import (
+	gosync "sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/store"
+	"go-micro.org/v5/sync"
+)
+
+// testSync is a process local sync.Sync.
+type testSync struct {
+	mu gosync.Mutex
+}
+
+func (s *testSync) Init(...sync.Option) error { return nil }
+func (s *testSync) Options() sync.Options     { return sync.Options{} }
+func (s *testSync) Leader(string, ...sync.LeaderOption) (sync.Leader, error) {
+	return nil, sync.ErrLockTimeout
+}
+func (s *testSync) Lock(string, ...sync.LockOption) error { s.mu.Lock(); return nil }
+func (s *testSync) Unlock(string) error                   { s.mu.Unlock(); return nil }
+func (s *testSync) String() string                        { return "test" }
+
+func TestDelayedPublish(t *testing.T) {
+	b := NewBroker(broker.NewMemoryBroker(), Store(store.NewMemoryStore()), Interval(time.Hour))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan *broker.Message, 2)
+	if _, err := b.Subscribe("test", func(e broker.Event) error {
+		received <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &broker.Message{Header: map[string]string{"foo": "bar"}, Body: []byte("hello")}
+	if err := b.Publish("test", msg, Delay(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	d := b.(*delayBroker)
+	d.deliver(time.Now())
+	select {
+	case <-received:
+		t.Fatal("message delivered before it was due")
+	default:
+	}
+
+	d.deliver(time.Now().Add(2 * time.Minute))
+	select {
+	case m := <-received:
+		if string(m.Body) != "hello" || m.Header["foo"] != "bar" {
+			t.Fatalf("unexpected message %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("due message was not delivered")
+	}
+
+	keys, err := d.opts.Store.List(store.ListPrefix(d.opts.Prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("expected scheduled message to be removed, got %v", keys)
+	}
+}
+
+func TestPublishWithoutDelay(t *testing.T) {
+	b := NewBroker(broker.NewMemoryBroker(), Store(store.NewMemoryStore()), Interval(time.Hour))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan struct{}, 1)
+	if _, err := b.Subscribe("test", func(e broker.Event) error {
+		received <- struct{}{}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("test", &broker.Message{Body: []byte("now")}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("message was not published immediately")
+	}
+}
+
+func TestSharedStore(t *testing.T) {
+	st := store.NewMemoryStore()
+	mb := broker.NewMemoryBroker()
+	if err := mb.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer mb.Disconnect()
+
+	var received int32
+	if _, err := mb.Subscribe("test", func(e broker.Event) error {
+		atomic.AddInt32(&received, 1)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testSync{}
+	var schedulers []*delayBroker
+	for i := 0; i < 4; i++ {
+		b := NewBroker(mb, Store(st), Interval(time.Hour), Sync(s))
+		schedulers = append(schedulers, b.(*delayBroker))
+	}
+
+	const n = 20
+	for i := 0; i < n; i++ {
+		if err := schedulers[0].Publish("test", &broker.Message{Body: []byte("hello")}, Delay(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg gosync.WaitGroup
+	due := time.Now().Add(2 * time.Minute)
+	for _, d := range schedulers {
+		wg.Add(1)
+		go func(d *delayBroker) {
+			defer wg.Done()
+			d.deliver(due)
+		}(d)
+	}
+	wg.Wait()
+
+	// the memory broker delivers asynchronously
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&received); got != n {
+		t.Fatalf("expected %d messages delivered once, got %d", n, got)
+	}
+}
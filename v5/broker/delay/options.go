@@ -0,0 +1,82 @@
+package delay
+
+import (
+	"context"
+	"time"
+
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/store"
+	"go-micro.org/v5/sync"
+)
+
+type deliverAtKey struct{}
+
+// Delay delivers the published message once d has elapsed.
+func Delay(d time.Duration) broker.PublishOption {
+	return At(time.Now().Add(d))
+}
+
+// At delivers the published message at t.
+func At(t time.Time) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, deliverAtKey{}, t)
+	}
+}
+
+// FromContext returns the delivery time set with Delay or At. Brokers with
+// native support for delayed messages use it to read the portable option.
+func FromContext(ctx context.Context) (time.Time, bool) {
+	if ctx == nil {
+		return time.Time{}, false
+	}
+	t, ok := ctx.Value(deliverAtKey{}).(time.Time)
+	return t, ok
+}
+
+// Options of the store backed scheduler.
+type Options struct {
+	// Store holds the scheduled messages until they are due.
+	Store store.Store
+	// Prefix of the keys the scheduled messages are written under.
+	Prefix string
+	// Interval the store is polled for due messages.
+	Interval time.Duration
+	// Sync serialises the schedulers sharing the store.
+	Sync sync.Sync
+}
+
+type Option func(o *Options)
+
+// Store sets the store scheduled messages are kept in. It defaults to
+// store.DefaultStore, use a persistent store to survive restarts.
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Prefix sets the key prefix of scheduled messages.
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// Interval sets how often the store is polled for due messages.
+func Interval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
+
+// Sync sets the lock schedulers sharing a store take while claiming due
+// messages. Without it a message may be delivered by more than one of them,
+// so it is required once several processes share a store.
+func Sync(s sync.Sync) Option {
+	return func(o *Options) {
+		o.Sync = s
+	}
+}
@@ -150,7 +150,7 @@ func (b *pubsubBroker) Options() broker.Options {
 
 // Publish checks if the topic exists and then publishes via google pubsub.
 func (b *pubsubBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) (err error) {
-	t := b.client.Topic(topic)
+	t := b.topic(topic)
 	ctx := context.Background()
 
 	m := &pubsub.Message{
@@ -172,6 +172,71 @@ func (b *pubsubBroker) Publish(topic string, msg *broker.Message, opts ...broker
 	return
 }
 
+// PublishBatch publishes msgs through a single topic, so they are bundled
+// according to the topic's publish settings.
+func (b *pubsubBroker) PublishBatch(topic string, msgs []*broker.Message, opts ...broker.PublishOption) []error {
+	ctx := context.Background()
+	t := b.topic(topic)
+	defer t.Stop()
+
+	results := make([]*pubsub.PublishResult, len(msgs))
+	for i, msg := range msgs {
+		results[i] = t.Publish(ctx, &pubsub.Message{
+			ID:         "m-" + uuid.New().String(),
+			Data:       msg.Body,
+			Attributes: msg.Header,
+		})
+	}
+
+	var failed, notFound bool
+	errs := make([]error, len(msgs))
+	for i, r := range results {
+		if _, errs[i] = r.Get(ctx); errs[i] != nil {
+			failed = true
+			notFound = notFound || status.Code(errs[i]) == codes.NotFound
+		}
+	}
+	if !failed {
+		return nil
+	}
+
+	// create Topic if not exists and retry the failed messages
+	if notFound {
+		log.Infof("Topic not exists. creating Topic: %s", topic)
+		if _, err := b.client.CreateTopic(ctx, topic); err != nil {
+			return errs
+		}
+		var retry []*broker.Message
+		var index []int
+		for i, err := range errs {
+			if err != nil {
+				retry = append(retry, msgs[i])
+				index = append(index, i)
+			}
+		}
+		rerrs := b.PublishBatch(topic, retry, opts...)
+		for j, i := range index {
+			errs[i] = nil
+			if rerrs != nil {
+				errs[i] = rerrs[j]
+			}
+		}
+		if rerrs == nil {
+			return nil
+		}
+	}
+	return errs
+}
+
+// topic returns a handle of the topic with the configured publish settings.
+func (b *pubsubBroker) topic(topic string) *pubsub.Topic {
+	t := b.client.Topic(topic)
+	if s, ok := b.options.Context.Value(publishSettingsKey{}).(pubsub.PublishSettings); ok {
+		t.PublishSettings = s
+	}
+	return t
+}
+
 // Subscribe registers a subscription to the given topic against the google pubsub api.
 func (b *pubsubBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
 	options := broker.SubscribeOptions{
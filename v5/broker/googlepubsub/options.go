@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"cloud.google.com/go/pubsub"
 	"go-micro.org/v5/broker"
 	"google.golang.org/api/option"
 )
@@ -20,6 +21,8 @@ type createSubscription struct{}
 
 type deleteSubscription struct{}
 
+type publishSettingsKey struct{}
+
 // ClientOption is a broker Option which allows google pubsub client options to be
 // set for the client.
 func ClientOption(c ...option.ClientOption) broker.Option {
@@ -31,6 +34,17 @@ func ClientOption(c ...option.ClientOption) broker.Option {
 	}
 }
 
+// PublishSettings sets the batching settings (count, byte and delay
+// thresholds) of the topics published to.
+func PublishSettings(s pubsub.PublishSettings) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, publishSettingsKey{}, s)
+	}
+}
+
 // ProjectID provides an option which sets the google project id.
 func ProjectID(id string) broker.Option {
 	return func(o *broker.Options) {
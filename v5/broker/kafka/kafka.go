@@ -42,6 +42,7 @@ type publication struct {
 	km   *sarama.ConsumerMessage
 	m    *broker.Message
 	sess sarama.ConsumerGroupSession
+	mark func()
 }
 
 func init() {
@@ -57,6 +58,10 @@ func (p *publication) Message() *broker.Message {
 }
 
 func (p *publication) Ack() error {
+	if p.mark != nil {
+		p.mark()
+		return nil
+	}
 	p.sess.MarkMessage(p.km, "")
 	return nil
 }
@@ -111,14 +116,21 @@ func (k *kBroker) Connect() error {
 	}
 	k.scMutex.Unlock()
 
-	pconfig := k.getBrokerConfig()
+	// copy the config so the partitioner is not wrapped again on reconnect
+	pconfig := *k.getBrokerConfig()
 	// For implementation reasons, the SyncProducer requires
 	// `Producer.Return.Errors` and `Producer.Return.Successes`
 	// to be set to true in its configuration.
 	pconfig.Producer.Return.Successes = true
 	pconfig.Producer.Return.Errors = true
+	// honour partitions set with PublishPartition
+	pconfig.Producer.Partitioner = newPartitioner(pconfig.Producer.Partitioner)
+	if b, ok := k.opts.Context.Value(producerBatchingKey{}).(producerBatching); ok {
+		pconfig.Producer.Flush.Messages = b.messages
+		pconfig.Producer.Flush.Frequency = b.frequency
+	}
 
-	c, err := sarama.NewClient(k.addrs, pconfig)
+	c, err := sarama.NewClient(k.addrs, &pconfig)
 	if err != nil {
 		return err
 	}
@@ -218,16 +230,16 @@ func (k *kBroker) Options() broker.Options {
 }
 
 func (k *kBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
-	b, err := k.opts.Codec.Marshal(msg)
+	options := broker.PublishOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	produceMsg, err := k.newProducerMessage(topic, msg, options)
 	if err != nil {
 		return err
 	}
 
-	var produceMsg = &sarama.ProducerMessage{
-		Topic:    topic,
-		Value:    sarama.ByteEncoder(b),
-		Metadata: msg,
-	}
 	if k.ap != nil {
 		k.ap.Input() <- produceMsg
 		return nil
@@ -239,9 +251,98 @@ func (k *kBroker) Publish(topic string, msg *broker.Message, opts ...broker.Publ
 	return errors.New(`no connection resources available`)
 }
 
-func (k *kBroker) getSaramaConsumerGroup(groupID string) (sarama.ConsumerGroup, error) {
-	config := k.getClusterConfig()
-	cg, err := sarama.NewConsumerGroup(k.addrs, groupID, config)
+// PublishBatch publishes msgs with a single call of the producer. With an
+// async producer the messages are queued and errors are reported on the
+// errors channel.
+func (k *kBroker) PublishBatch(topic string, msgs []*broker.Message, opts ...broker.PublishOption) []error {
+	options := broker.PublishOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var failed bool
+	errs := make([]error, len(msgs))
+	index := make(map[*sarama.ProducerMessage]int, len(msgs))
+	produceMsgs := make([]*sarama.ProducerMessage, 0, len(msgs))
+	for i, msg := range msgs {
+		produceMsg, err := k.newProducerMessage(topic, msg, options)
+		if err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+		index[produceMsg] = i
+		produceMsgs = append(produceMsgs, produceMsg)
+	}
+
+	switch {
+	case k.ap != nil:
+		for _, produceMsg := range produceMsgs {
+			k.ap.Input() <- produceMsg
+		}
+	case k.p != nil:
+		err := k.p.SendMessages(produceMsgs)
+		if perrs, ok := err.(sarama.ProducerErrors); ok {
+			for _, perr := range perrs {
+				errs[index[perr.Msg]] = perr.Err
+			}
+			failed = true
+		} else if err != nil {
+			for _, i := range index {
+				errs[i] = err
+			}
+			failed = true
+		}
+	default:
+		err := errors.New(`no connection resources available`)
+		for i := range errs {
+			errs[i] = err
+		}
+		failed = true
+	}
+
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
+func (k *kBroker) newProducerMessage(topic string, msg *broker.Message, options broker.PublishOptions) (*sarama.ProducerMessage, error) {
+	b, err := k.opts.Codec.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var produceMsg = &sarama.ProducerMessage{
+		Topic:     topic,
+		Value:     sarama.ByteEncoder(b),
+		Metadata:  msg,
+		Partition: -1,
+	}
+	if options.Context != nil {
+		if key, ok := options.Context.Value(publishKey{}).([]byte); ok {
+			produceMsg.Key = sarama.ByteEncoder(key)
+		}
+		if partition, ok := options.Context.Value(publishPartitionKey{}).(int32); ok {
+			produceMsg.Partition = partition
+		}
+	}
+	// record headers are only supported since kafka 0.11
+	if k.c != nil && k.c.Config().Version.IsAtLeast(sarama.V0_11_0_0) {
+		for hk, hv := range msg.Header {
+			produceMsg.Headers = append(produceMsg.Headers, sarama.RecordHeader{Key: []byte(hk), Value: []byte(hv)})
+		}
+	}
+	return produceMsg, nil
+}
+
+func (k *kBroker) getSaramaConsumerGroup(groupID string, manual bool) (sarama.ConsumerGroup, error) {
+	// copy the config so auto commit is only disabled for this group
+	config := *k.getClusterConfig()
+	if manual {
+		config.Consumer.Offsets.AutoCommit.Enable = false
+	}
+	cg, err := sarama.NewConsumerGroup(k.addrs, groupID, &config)
 	if err != nil {
 		return nil, err
 	}
@@ -260,7 +361,7 @@ func (k *kBroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 		o(&opt)
 	}
 	// we need to create a new client per consumer
-	cg, err := k.getSaramaConsumerGroup(opt.Queue)
+	cg, err := k.getSaramaConsumerGroup(opt.Queue, manualCommit(opt))
 	if err != nil {
 		return nil, err
 	}
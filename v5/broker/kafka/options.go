@@ -2,6 +2,8 @@ package kafka
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"go-micro.org/v5/broker"
@@ -39,6 +41,20 @@ func AsyncProducer(errors chan<- *sarama.ProducerError, successes chan<- *sarama
 	return opt
 }
 
+type producerBatchingKey struct{}
+
+type producerBatching struct {
+	messages  int
+	frequency time.Duration
+}
+
+// ProducerBatching makes the producer wait for messages messages or
+// frequency, whichever comes first, before sending a batch to kafka.
+// It mainly benefits the async producer and PublishBatch.
+func ProducerBatching(messages int, frequency time.Duration) broker.Option {
+	return setBrokerOption(producerBatchingKey{}, producerBatching{messages: messages, frequency: frequency})
+}
+
 type subscribeContextKey struct{}
 
 // SubscribeContext set the context for broker.SubscribeOption.
@@ -52,6 +68,52 @@ func SubscribeConfig(c *sarama.Config) broker.SubscribeOption {
 	return setSubscribeOption(subscribeConfigKey{}, c)
 }
 
+type publishKey struct{}
+type publishPartitionKey struct{}
+
+// PublishKey sets the message key used by the partitioner. Messages with
+// the same key are written to the same partition.
+func PublishKey(key []byte) broker.PublishOption {
+	return setPublishOption(publishKey{}, key)
+}
+
+// PublishPartition writes the message to an explicit partition, bypassing
+// the configured partitioner.
+func PublishPartition(partition int32) broker.PublishOption {
+	return setPublishOption(publishPartitionKey{}, partition)
+}
+
+type batchCommitKey struct{}
+
+type batchCommit struct {
+	size     int
+	interval time.Duration
+}
+
+// CommitAfterHandle commits the offset of every message synchronously as
+// soon as it and every earlier message of its partition have been acked.
+// Sarama's periodic auto commit is disabled.
+func CommitAfterHandle() broker.SubscribeOption {
+	return setSubscribeOption(batchCommitKey{}, batchCommit{size: 1})
+}
+
+// BatchCommit commits acked offsets once size messages have been acked or
+// interval has elapsed, whichever comes first. As with CommitAfterHandle,
+// only offsets below which every message has been acked are committed and
+// sarama's periodic auto commit is disabled.
+func BatchCommit(size int, interval time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(batchCommitKey{}, batchCommit{size: size, interval: interval})
+}
+
+type orderedConcurrencyKey struct{}
+
+// OrderedConcurrency handles up to n messages of a partition concurrently.
+// Messages with the same key are always handled in order by the same worker,
+// and offsets are only committed once all previous messages are acked.
+func OrderedConcurrency(n int) broker.SubscribeOption {
+	return setSubscribeOption(orderedConcurrencyKey{}, n)
+}
+
 // consumerGroupHandler is the implementation of sarama.ConsumerGroupHandler.
 type consumerGroupHandler struct {
 	handler broker.Handler
@@ -64,48 +126,89 @@ type consumerGroupHandler struct {
 func (*consumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
 func (*consumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
 func (h *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for msg := range claim.Messages() {
-		var m broker.Message
-		p := &publication{m: &m, t: msg.Topic, km: msg, cg: h.cg, sess: sess}
-		eh := h.kopts.ErrorHandler
-
-		if err := h.kopts.Codec.Unmarshal(msg.Value, &m); err != nil {
-			p.err = err
-			p.m.Body = msg.Value
-			if eh != nil {
-				eh(p)
-			} else {
-				log.Errorf("[kafka]: failed to unmarshal: %v", err)
-			}
-			continue
+	c := newCommitter(sess.Commit, h.subopts)
+	defer c.stop()
+
+	n, _ := subscribeValue(h.subopts, orderedConcurrencyKey{}).(int)
+	if c == nil && n <= 1 {
+		for msg := range claim.Messages() {
+			msg := msg
+			h.handle(sess, msg, func() { sess.MarkMessage(msg, "") })
 		}
+		return nil
+	}
 
-		if p.m.Body == nil {
-			p.m.Body = msg.Value
-		}
-		// if we don't have headers, create empty map
-		if m.Header == nil {
-			m.Header = make(map[string]string)
-		}
-		for _, header := range msg.Headers {
-			m.Header[string(header.Key)] = string(header.Value)
-		}
-		m.Header["Micro-Topic"] = msg.Topic // only for RPC server, it somehow inspect Header for topic
-		if _, ok := m.Header["Content-Type"]; !ok {
-			m.Header["Content-Type"] = "application/json" // default to json codec
+	t := &offsetTracker{mark: func(msg *sarama.ConsumerMessage) {
+		sess.MarkMessage(msg, "")
+		c.marked()
+	}}
+	if n > 1 {
+		return h.consumeOrdered(sess, claim, t, n)
+	}
+	for msg := range claim.Messages() {
+		tm := t.add(msg)
+		h.handle(sess, msg, func() { t.ack(tm) })
+	}
+	return nil
+}
+
+// handle decodes a single record and passes it to the subscriber handler.
+// mark is called when the message is acked, either by the handler, after it
+// returned or automatically. Messages which fail to decode, or whose handler
+// fails without an ErrorHandler, are skipped by marking them, so they don't
+// hold back the offsets of their partition.
+func (h *consumerGroupHandler) handle(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, mark func()) {
+	var m broker.Message
+	var once sync.Once
+	p := &publication{m: &m, t: msg.Topic, km: msg, cg: h.cg, sess: sess, mark: func() { once.Do(mark) }}
+	eh := h.kopts.ErrorHandler
+
+	if err := h.kopts.Codec.Unmarshal(msg.Value, &m); err != nil {
+		p.err = err
+		p.m.Body = msg.Value
+		if eh != nil {
+			eh(p)
+		} else {
+			log.Errorf("[kafka]: failed to unmarshal: %v", err)
 		}
+		// it never will decode, skip it
+		p.Ack()
+		return
+	}
+
+	if p.m.Body == nil {
+		p.m.Body = msg.Value
+	}
+	// if we don't have headers, create empty map
+	if m.Header == nil {
+		m.Header = make(map[string]string)
+	}
+	for _, header := range msg.Headers {
+		m.Header[string(header.Key)] = string(header.Value)
+	}
+	m.Header["Micro-Topic"] = msg.Topic // only for RPC server, it somehow inspect Header for topic
+	if _, ok := m.Header["Content-Type"]; !ok {
+		m.Header["Content-Type"] = "application/json" // default to json codec
+	}
 
-		err := h.handler(p)
-		if err == nil && h.subopts.AutoAck {
-			sess.MarkMessage(msg, "")
-		} else if err != nil {
-			p.err = err
-			if eh != nil {
-				eh(p)
-			} else {
-				log.Errorf("[kafka]: subscriber error: %v", err)
-			}
+	err := h.handler(p)
+	if err == nil && h.subopts.AutoAck {
+		p.Ack()
+	} else if err != nil {
+		p.err = err
+		if eh != nil {
+			eh(p)
+		} else {
+			log.Errorf("[kafka]: subscriber error: %v", err)
+			p.Ack()
 		}
 	}
-	return nil
+}
+
+// subscribeValue returns the value stored for key in the subscribe context.
+func subscribeValue(opts broker.SubscribeOptions, key interface{}) interface{} {
+	if opts.Context == nil {
+		return nil
+	}
+	return opts.Context.Value(key)
 }
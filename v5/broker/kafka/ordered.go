@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"go-micro.org/v5/broker"
+)
+
+// partitioner honours partitions set with PublishPartition and delegates
+// all other messages to the configured partitioner.
+type partitioner struct {
+	sarama.Partitioner
+}
+
+func newPartitioner(constructor sarama.PartitionerConstructor) sarama.PartitionerConstructor {
+	if constructor == nil {
+		constructor = sarama.NewHashPartitioner
+	}
+	return func(topic string) sarama.Partitioner {
+		return &partitioner{Partitioner: constructor(topic)}
+	}
+}
+
+func (p *partitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if msg.Partition >= 0 {
+		return msg.Partition, nil
+	}
+	msg.Partition = 0
+	return p.Partitioner.Partition(msg, numPartitions)
+}
+
+// committer commits marked offsets according to the subscription's commit
+// mode. It is only used once sarama's auto commit has been disabled.
+type committer struct {
+	commit func()
+	size   int
+	exit   chan struct{}
+
+	mu      sync.Mutex
+	pending int
+}
+
+// manualCommit returns whether the subscription commits its own offsets
+// rather than leaving them to sarama's auto commit.
+func manualCommit(opts broker.SubscribeOptions) bool {
+	_, ok := subscribeValue(opts, batchCommitKey{}).(batchCommit)
+	return ok
+}
+
+// newCommitter returns a committer calling commit for the subscription's
+// commit mode, or nil if offsets are auto committed.
+func newCommitter(commit func(), opts broker.SubscribeOptions) *committer {
+	b, ok := subscribeValue(opts, batchCommitKey{}).(batchCommit)
+	if !ok {
+		return nil
+	}
+	c := &committer{commit: commit, size: b.size, exit: make(chan struct{})}
+	if b.interval > 0 {
+		go c.run(b.interval)
+	}
+	return c
+}
+
+// marked is called every time an offset has been marked on the session.
+func (c *committer) marked() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.pending++
+	full := c.size > 0 && c.pending >= c.size
+	c.mu.Unlock()
+	if full {
+		c.flush()
+	}
+}
+
+func (c *committer) flush() {
+	c.mu.Lock()
+	if c.pending == 0 {
+		c.mu.Unlock()
+		return
+	}
+	c.pending = 0
+	c.mu.Unlock()
+	c.commit()
+}
+
+func (c *committer) run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.exit:
+			return
+		case <-t.C:
+			c.flush()
+		}
+	}
+}
+
+func (c *committer) stop() {
+	if c == nil {
+		return
+	}
+	close(c.exit)
+	c.flush()
+}
+
+type trackedMessage struct {
+	msg   *sarama.ConsumerMessage
+	acked bool
+}
+
+// offsetTracker keeps the in flight messages of a partition in offset order
+// and marks the low watermark of acked offsets, so an offset is only marked
+// once it and every message before it have been acked. A message which is
+// never acked holds back the partition until it is redelivered by the next
+// session.
+type offsetTracker struct {
+	mark func(*sarama.ConsumerMessage)
+
+	sync.Mutex
+	inflight []*trackedMessage
+}
+
+func (t *offsetTracker) add(msg *sarama.ConsumerMessage) *trackedMessage {
+	tm := &trackedMessage{msg: msg}
+	t.Lock()
+	t.inflight = append(t.inflight, tm)
+	t.Unlock()
+	return tm
+}
+
+// ack may be called at any time, also after the handler returned.
+func (t *offsetTracker) ack(tm *trackedMessage) {
+	t.Lock()
+	tm.acked = true
+	var mark *sarama.ConsumerMessage
+	for len(t.inflight) > 0 && t.inflight[0].acked {
+		mark = t.inflight[0].msg
+		t.inflight = t.inflight[1:]
+	}
+	t.Unlock()
+
+	// sarama ignores offsets lower than the one already marked
+	if mark != nil {
+		t.mark(mark)
+	}
+}
+
+// consumeOrdered dispatches the messages of a claim to n workers. Messages
+// with the same key always go to the same worker, keyless messages are
+// spread by offset.
+func (h *consumerGroupHandler) consumeOrdered(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim, t *offsetTracker, n int) error {
+	var wg sync.WaitGroup
+	workers := make([]chan *trackedMessage, n)
+	for i := range workers {
+		ch := make(chan *trackedMessage, 16)
+		workers[i] = ch
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tm := range ch {
+				tm := tm
+				h.handle(sess, tm.msg, func() { t.ack(tm) })
+			}
+		}()
+	}
+
+	for msg := range claim.Messages() {
+		tm := t.add(msg)
+		workers[workerFor(msg, n)] <- tm
+	}
+
+	for _, ch := range workers {
+		close(ch)
+	}
+	wg.Wait()
+	return nil
+}
+
+func workerFor(msg *sarama.ConsumerMessage, n int) int {
+	if len(msg.Key) == 0 {
+		return int(msg.Offset % int64(n))
+	}
+	h := fnv.New32a()
+	h.Write(msg.Key)
+	return int(h.Sum32() % uint32(n))
+}
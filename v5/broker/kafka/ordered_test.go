@@ -0,0 +1,204 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/codec/json"
+)
+
+type fixedPartitioner struct {
+	partition int32
+}
+
+func (p *fixedPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	return p.partition, nil
+}
+
+func (p *fixedPartitioner) RequiresConsistency() bool {
+	return true
+}
+
+func TestPartitioner(t *testing.T) {
+	p := newPartitioner(func(topic string) sarama.Partitioner {
+		return &fixedPartitioner{partition: 3}
+	})("topic")
+
+	msg := &sarama.ProducerMessage{Topic: "topic", Partition: 5}
+	if got, err := p.Partition(msg, 8); err != nil || got != 5 {
+		t.Fatalf("Expected explicit partition 5, got %d: %v", got, err)
+	}
+
+	msg = &sarama.ProducerMessage{Topic: "topic", Partition: -1}
+	if got, err := p.Partition(msg, 8); err != nil || got != 3 {
+		t.Fatalf("Expected partition 3 of the wrapped partitioner, got %d: %v", got, err)
+	}
+
+	if newPartitioner(nil)("topic") == nil {
+		t.Fatal("Expected the hash partitioner by default")
+	}
+}
+
+func TestWorkerFor(t *testing.T) {
+	a := &sarama.ConsumerMessage{Key: []byte("a"), Offset: 1}
+	b := &sarama.ConsumerMessage{Key: []byte("a"), Offset: 2}
+	if workerFor(a, 8) != workerFor(b, 8) {
+		t.Fatal("Expected messages with the same key to go to the same worker")
+	}
+
+	seen := make(map[int]bool)
+	for i := int64(0); i < 4; i++ {
+		seen[workerFor(&sarama.ConsumerMessage{Offset: i}, 4)] = true
+	}
+	if len(seen) != 4 {
+		t.Fatalf("Expected keyless messages to be spread over 4 workers, got %d", len(seen))
+	}
+}
+
+func TestOffsetTracker(t *testing.T) {
+	var marked []int64
+	tr := &offsetTracker{mark: func(msg *sarama.ConsumerMessage) {
+		marked = append(marked, msg.Offset)
+	}}
+
+	var tms []*trackedMessage
+	for i := int64(0); i < 4; i++ {
+		tms = append(tms, tr.add(&sarama.ConsumerMessage{Offset: i}))
+	}
+
+	// later offsets are held back by an un-acked one
+	tr.ack(tms[1])
+	tr.ack(tms[2])
+	if len(marked) != 0 {
+		t.Fatalf("Expected nothing marked before offset 0 is acked, got %v", marked)
+	}
+
+	// an ack after the handler returned still advances the watermark
+	tr.ack(tms[0])
+	if len(marked) != 1 || marked[0] != 2 {
+		t.Fatalf("Expected offset 2 marked, got %v", marked)
+	}
+
+	tr.ack(tms[0])
+	tr.ack(tms[3])
+	if len(marked) != 2 || marked[1] != 3 {
+		t.Fatalf("Expected offset 3 marked, got %v", marked)
+	}
+	if len(tr.inflight) != 0 {
+		t.Fatalf("Expected no messages in flight, got %d", len(tr.inflight))
+	}
+}
+
+func TestCommitter(t *testing.T) {
+	if c := newCommitter(func() {}, broker.SubscribeOptions{}); c != nil {
+		t.Fatal("Expected no committer without a commit mode")
+	}
+
+	var mu sync.Mutex
+	var commits int
+	commit := func() {
+		mu.Lock()
+		commits++
+		mu.Unlock()
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return commits
+	}
+
+	opts := broker.SubscribeOptions{Context: context.Background()}
+	CommitAfterHandle()(&opts)
+	if !manualCommit(opts) {
+		t.Fatal("Expected CommitAfterHandle to disable auto commit")
+	}
+	c := newCommitter(commit, opts)
+	c.marked()
+	c.marked()
+	if got := count(); got != 2 {
+		t.Fatalf("Expected a commit per mark, got %d", got)
+	}
+	c.stop()
+
+	commits = 0
+	opts = broker.SubscribeOptions{Context: context.Background()}
+	BatchCommit(3, 20*time.Millisecond)(&opts)
+	c = newCommitter(commit, opts)
+	c.marked()
+	c.marked()
+	if got := count(); got != 0 {
+		t.Fatalf("Expected no commit before the batch is full, got %d", got)
+	}
+	c.marked()
+	if got := count(); got != 1 {
+		t.Fatalf("Expected a commit once the batch is full, got %d", got)
+	}
+
+	c.marked()
+	time.Sleep(60 * time.Millisecond)
+	if got := count(); got != 2 {
+		t.Fatalf("Expected a commit after the interval, got %d", got)
+	}
+
+	c.marked()
+	c.stop()
+	if got := count(); got != 3 {
+		t.Fatalf("Expected a commit on stop, got %d", got)
+	}
+}
+
+func TestHandleSkips(t *testing.T) {
+	var marked []int64
+	tr := &offsetTracker{mark: func(msg *sarama.ConsumerMessage) {
+		marked = append(marked, msg.Offset)
+	}}
+
+	h := &consumerGroupHandler{
+		kopts:   broker.Options{Codec: json.Marshaler{}},
+		subopts: broker.SubscribeOptions{AutoAck: true},
+		handler: func(p broker.Event) error {
+			if string(p.Message().Body) == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	handle := func(offset int64, value string) {
+		msg := &sarama.ConsumerMessage{Offset: offset, Value: []byte(value)}
+		tm := tr.add(msg)
+		h.handle(nil, msg, func() { tr.ack(tm) })
+	}
+
+	// an undecodable message doesn't hold back the partition
+	handle(0, "not json")
+	handle(1, `{"body":"b2s="}`)
+	if len(marked) != 1 || marked[0] != 1 {
+		t.Fatalf("Expected offset 1 marked past the undecodable message, got %v", marked)
+	}
+
+	// nor does a failed one without an error handler
+	handle(2, `{"body":"ZmFpbA=="}`)
+	if len(marked) != 2 || marked[1] != 2 {
+		t.Fatalf("Expected the failed message to be skipped, got %v", marked)
+	}
+
+	// with an error handler, failures are left to it
+	var handled int
+	h.kopts.ErrorHandler = func(p broker.Event) error {
+		handled++
+		return nil
+	}
+	handle(3, `{"body":"ZmFpbA=="}`)
+	handle(4, "not json")
+	if handled != 2 || len(marked) != 2 {
+		t.Fatalf("Expected the error handler to keep the failed message, got %d handled and %v marked", handled, marked)
+	}
+	if len(tr.inflight) != 2 {
+		t.Fatalf("Expected the failed message and the acked undecodable one in flight, got %d", len(tr.inflight))
+	}
+}
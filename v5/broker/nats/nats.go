@@ -187,6 +187,48 @@ func (n *natsBroker) Publish(topic string, msg *broker.Message, opts ...broker.P
 	return n.conn.Publish(topic, b)
 }
 
+// PublishBatch publishes msgs and flushes the connection once, so the
+// messages are written to the server together.
+func (n *natsBroker) PublishBatch(topic string, msgs []*broker.Message, opts ...broker.PublishOption) []error {
+	n.RLock()
+	defer n.RUnlock()
+
+	errs := make([]error, len(msgs))
+	if n.conn == nil {
+		err := errors.New("not connected")
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	var failed bool
+	for i, msg := range msgs {
+		b, err := n.opts.Codec.Marshal(msg)
+		if err == nil {
+			err = n.conn.Publish(topic, b)
+		}
+		if err != nil {
+			errs[i] = err
+			failed = true
+		}
+	}
+
+	if err := n.conn.Flush(); err != nil {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+		failed = true
+	}
+
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
 func (n *natsBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
 	n.RLock()
 	if n.conn == nil {
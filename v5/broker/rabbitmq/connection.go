@@ -6,7 +6,10 @@ package rabbitmq
 
 import (
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -35,6 +38,15 @@ var (
 	DefaultConfirmPublish  = false
 	DefaultWithoutExchange = false
 
+	// DefaultDelayLevels are the delay queues of delayed messages. A message
+	// goes to the queue of the smallest level not below its delay, so all
+	// delays share a few queues.
+	DefaultDelayLevels = []time.Duration{
+		time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+		time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute,
+		time.Hour, 2 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+	}
+
 	// The amqp library does not seem to set these when using amqp.DialConfig
 	// (even though it says so in the comments) so we set them manually to make
 	// sure to not brake any existing functionality.
@@ -298,3 +310,42 @@ func (r *rabbitMQConn) Publish(exchange, key string, msg amqp.Publishing) error
 	}
 	return r.ExchangeChannel.Publish(exchange, key, msg)
 }
+
+// PublishDelayed publishes msg to the delay queue of the exchange, key and
+// the level of d. Messages expire after d and are then dead lettered to the
+// exchange with the original key. As expired messages only leave from the
+// head of a queue, a message may be held back by an earlier one of the same
+// level, by at most the gap to the level below. Idle delay queues remove
+// themselves.
+func (r *rabbitMQConn) PublishDelayed(exchange, key string, msg amqp.Publishing, d time.Duration, levels []time.Duration) error {
+	queue, level, err := delayQueue(exchange, key, d, levels)
+	if err != nil {
+		return err
+	}
+	args := amqp.Table{
+		"x-dead-letter-exchange":    exchange,
+		"x-dead-letter-routing-key": key,
+		"x-expires":                 (level + time.Minute).Milliseconds(),
+	}
+
+	// redeclare on every publish, publishing alone does not reset x-expires
+	if err := r.Channel.DeclareDurableQueue(queue, args); err != nil {
+		return err
+	}
+	msg.Expiration = strconv.FormatInt(d.Milliseconds(), 10)
+	return r.Channel.Publish("", queue, msg)
+}
+
+// delayQueue returns the name and level of the delay queue for a delay of d,
+// levels being sorted in ascending order.
+func delayQueue(exchange, key string, d time.Duration, levels []time.Duration) (string, time.Duration, error) {
+	for _, level := range levels {
+		if d <= level {
+			return fmt.Sprintf("%s.delay.%s.%d", exchange, key, level.Milliseconds()), level, nil
+		}
+	}
+	if len(levels) == 0 {
+		return "", 0, errors.New("rabbitmq: no delay levels")
+	}
+	return "", 0, fmt.Errorf("rabbitmq: delay of %v exceeds the maximum of %v", d, levels[len(levels)-1])
+}
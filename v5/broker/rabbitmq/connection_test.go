@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/streadway/amqp"
 	"go-micro.org/v5/logger"
@@ -109,3 +110,30 @@ func TestNewRabbitMQPrefetchConfirmPublish(t *testing.T) {
 		}
 	}
 }
+
+func TestDelayQueue(t *testing.T) {
+	levels := []time.Duration{time.Second, 5 * time.Second, time.Minute}
+
+	first, level, err := delayQueue("micro", "topic", 1200*time.Millisecond, levels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "micro.delay.topic.5000"; first != want || level != 5*time.Second {
+		t.Fatalf("Expected queue %s of level 5s, got %s of level %v", want, first, level)
+	}
+
+	// nearby delays share the queue
+	for _, d := range []time.Duration{1201 * time.Millisecond, 3 * time.Second, 5 * time.Second} {
+		if queue, _, _ := delayQueue("micro", "topic", d, levels); queue != first {
+			t.Fatalf("Expected queue %s for a delay of %v, got %s", first, d, queue)
+		}
+	}
+
+	if queue, _, _ := delayQueue("micro", "topic", 500*time.Millisecond, levels); queue != "micro.delay.topic.1000" {
+		t.Fatalf("Expected the 1s queue for a delay of 500ms, got %s", queue)
+	}
+
+	if _, _, err := delayQueue("micro", "topic", 2*time.Minute, levels); err == nil {
+		t.Fatal("Expected an error for a delay above the largest level")
+	}
+}
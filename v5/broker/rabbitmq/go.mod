@@ -4,6 +4,7 @@ go 1.19
 
 require (
 	github.com/google/uuid v1.6.0
+	github.com/open-micro/plugins/v5/broker/delay v0.0.0-00010101000000-000000000000
 	github.com/streadway/amqp v1.0.0
 	go-micro.org/v5 v5.0.1
 )
@@ -49,3 +50,5 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/broker/delay => ../delay
@@ -2,6 +2,7 @@ package rabbitmq
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"go-micro.org/v5/broker"
@@ -33,6 +34,7 @@ type userID struct{}
 type appID struct{}
 type externalAuth struct{}
 type durableExchange struct{}
+type delayLevelsKey struct{}
 
 // ServerDurableQueue provide durable queue option for micro.RegisterSubscriber
 func ServerDurableQueue() server.SubscriberOption {
@@ -54,6 +56,14 @@ func DurableExchange() broker.Option {
 	return setBrokerOption(durableExchange{}, true)
 }
 
+// DelayLevels sets the delay queues of messages published with delay.Delay
+// or delay.At. Delays above the largest level are rejected.
+func DelayLevels(levels ...time.Duration) broker.Option {
+	levels = append([]time.Duration(nil), levels...)
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	return setBrokerOption(delayLevelsKey{}, levels)
+}
+
 // Headers adds headers used by the headers exchange.
 func Headers(h map[string]interface{}) broker.SubscribeOption {
 	return setSubscribeOption(headersKey{}, h)
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/open-micro/plugins/v5/broker/delay"
 	"github.com/streadway/amqp"
 	"go-micro.org/v5/broker"
 	"go-micro.org/v5/logger"
@@ -241,6 +242,16 @@ func (r *rbroker) Publish(topic string, msg *broker.Message, opts ...broker.Publ
 		return errors.New("connection is nil")
 	}
 
+	if at, ok := delay.FromContext(options.Context); ok {
+		if d := time.Until(at); d >= time.Millisecond {
+			var exchange string
+			if !r.getWithoutExchange() {
+				exchange = r.conn.exchange.Name
+			}
+			return r.conn.PublishDelayed(exchange, topic, m, d, r.getDelayLevels())
+		}
+	}
+
 	return r.conn.Publish(r.conn.exchange.Name, topic, m)
 }
 
@@ -401,6 +412,13 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 	}
 }
 
+func (r *rbroker) getDelayLevels() []time.Duration {
+	if levels, ok := r.opts.Context.Value(delayLevelsKey{}).([]time.Duration); ok {
+		return levels
+	}
+	return DefaultDelayLevels
+}
+
 func (r *rbroker) getExchange() Exchange {
 	ex := DefaultExchange
 
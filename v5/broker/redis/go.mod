@@ -4,6 +4,8 @@ go 1.19
 
 require (
 	github.com/gomodule/redigo v1.8.5
+	github.com/google/uuid v1.6.0
+	github.com/open-micro/plugins/v5/broker/delay v0.0.0-00010101000000-000000000000
 	go-micro.org/v5 v5.0.1
 )
 
@@ -22,7 +24,6 @@ require (
 	github.com/go-git/go-git/v5 v5.12.0 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/imdario/mergo v0.3.16 // indirect
 	github.com/jbenet/go-context v0.0.0-20150711004518-d14ea06fba99 // indirect
 	github.com/kevinburke/ssh_config v1.2.0 // indirect
@@ -49,3 +50,5 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/broker/delay => ../delay
@@ -14,6 +14,11 @@ var (
 	DefaultReadTimeout    = 5 * time.Second
 	DefaultWriteTimeout   = 5 * time.Second
 
+	// DefaultDelayedKey is the sorted set delayed messages are kept in.
+	DefaultDelayedKey = "micro:broker:delayed"
+	// DefaultDelayInterval is how often due delayed messages are published.
+	DefaultDelayInterval = time.Second
+
 	optionsKey = optionsKeyType{}
 )
 
@@ -25,6 +30,8 @@ type brokerOptions struct {
 	connectTimeout time.Duration
 	readTimeout    time.Duration
 	writeTimeout   time.Duration
+	delayedKey     string
+	delayInterval  time.Duration
 }
 
 type optionsKeyType struct{}
@@ -70,3 +77,20 @@ func IdleTimeout(d time.Duration) broker.Option {
 		bo.idleTimeout = d
 	}
 }
+
+// DelayedKey sets the sorted set messages published with delay.Delay are
+// kept in until they are due.
+func DelayedKey(key string) broker.Option {
+	return func(o *broker.Options) {
+		bo := o.Context.Value(optionsKey).(*brokerOptions)
+		bo.delayedKey = key
+	}
+}
+
+// DelayInterval sets how often due delayed messages are published.
+func DelayInterval(d time.Duration) broker.Option {
+	return func(o *broker.Options) {
+		bo := o.Context.Value(optionsKey).(*brokerOptions)
+		bo.delayInterval = d
+	}
+}
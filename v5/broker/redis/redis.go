@@ -8,12 +8,29 @@ import (
 	"time"
 
 	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+	"github.com/open-micro/plugins/v5/broker/delay"
 	"go-micro.org/v5/broker"
 	"go-micro.org/v5/codec"
 	"go-micro.org/v5/codec/json"
+	log "go-micro.org/v5/logger"
 	"go-micro.org/v5/util/cmd"
 )
 
+// deliverScript atomically moves due messages from the delayed set to their
+// topic. Members are encoded as topic NUL id NUL payload.
+var deliverScript = redis.NewScript(1, `
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, m in ipairs(due) do
+	redis.call('ZREM', KEYS[1], m)
+	local topic, payload = string.match(m, '^([^%z]*)%z[^%z]*%z(.*)$')
+	if topic then
+		redis.call('PUBLISH', topic, payload)
+	end
+end
+return #due
+`)
+
 func init() {
 	cmd.DefaultBrokers["redis"] = NewBroker
 }
@@ -120,6 +137,7 @@ type redisBroker struct {
 	pool  *redis.Pool
 	opts  broker.Options
 	bopts *brokerOptions
+	exit  chan struct{}
 }
 
 // String returns the name of the broker implementation.
@@ -190,11 +208,18 @@ func (b *redisBroker) Connect() error {
 		},
 	}
 
+	b.exit = make(chan struct{})
+	go b.deliverDelayed(b.pool, b.exit)
+
 	return nil
 }
 
 // Disconnect closes the connection pool.
 func (b *redisBroker) Disconnect() error {
+	if b.pool == nil {
+		return nil
+	}
+	close(b.exit)
 	err := b.pool.Close()
 	b.pool = nil
 	b.addr = ""
@@ -203,11 +228,24 @@ func (b *redisBroker) Disconnect() error {
 
 // Publish publishes a message.
 func (b *redisBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	var options broker.PublishOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
 	v, err := b.opts.Codec.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
+	if at, ok := delay.FromContext(options.Context); ok && at.After(time.Now()) {
+		member := topic + "\x00" + uuid.New().String() + "\x00" + string(v)
+		conn := b.pool.Get()
+		_, err = conn.Do("ZADD", b.bopts.delayedKey, at.UnixMilli(), member)
+		conn.Close()
+		return err
+	}
+
 	conn := b.pool.Get()
 	_, err = redis.Int(conn.Do("PUBLISH", topic, v))
 	conn.Close()
@@ -215,6 +253,27 @@ func (b *redisBroker) Publish(topic string, msg *broker.Message, opts ...broker.
 	return err
 }
 
+// deliverDelayed periodically publishes the delayed messages that are due
+// until exit is closed.
+func (b *redisBroker) deliverDelayed(pool *redis.Pool, exit chan struct{}) {
+	t := time.NewTicker(b.bopts.delayInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+			conn := pool.Get()
+			_, err := deliverScript.Do(conn, b.bopts.delayedKey, time.Now().UnixMilli(), 100)
+			conn.Close()
+			if err != nil {
+				log.Errorf("[redis]: failed to publish delayed messages: %v", err)
+			}
+		}
+	}
+}
+
 // Subscribe returns a subscriber for the topic and handler.
 func (b *redisBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
 	var options broker.SubscribeOptions
@@ -252,6 +311,8 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 		connectTimeout: DefaultConnectTimeout,
 		readTimeout:    DefaultReadTimeout,
 		writeTimeout:   DefaultWriteTimeout,
+		delayedKey:     DefaultDelayedKey,
+		delayInterval:  DefaultDelayInterval,
 	}
 
 	// Initialize with empty broker options.
@@ -6,7 +6,9 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/open-micro/plugins/v5/broker/delay"
 	"go-micro.org/v5/broker"
 )
 
@@ -106,3 +108,65 @@ func TestBroker(t *testing.T) {
 		t.Fatalf("expected %v, got %v", exp, actual)
 	}
 }
+
+func TestDelayedPublish(t *testing.T) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL not defined")
+	}
+
+	b := NewBroker(broker.Addrs(url), DelayedKey("micro:broker:delayed:test"), DelayInterval(50*time.Millisecond))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	msgs := make(chan string, 1)
+	s := subscribe(t, b, "delayed", func(p broker.Event) error {
+		msgs <- string(p.Message().Body)
+		return nil
+	})
+	defer unsubscribe(t, s)
+
+	start := time.Now()
+	if err := b.Publish("delayed", &broker.Message{Body: []byte("later")}, delay.Delay(300*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-msgs:
+		if msg != "later" {
+			t.Fatalf("expected later, got %s", msg)
+		}
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+			t.Fatalf("delayed message delivered after %v", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("delayed message was not delivered")
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	b := NewBroker()
+
+	// disconnecting before connecting is a no-op
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL not defined")
+	}
+
+	b = NewBroker(broker.Addrs(url))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+}
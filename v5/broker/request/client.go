@@ -0,0 +1,129 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/client"
+	"go-micro.org/v5/errors"
+	"go-micro.org/v5/metadata"
+)
+
+// requestClient is a client.Client sending calls as requests over the
+// broker. The service name is used as topic, so services only need to
+// Serve their name on the same broker.
+type requestClient struct {
+	// client used to build requests and messages and to publish
+	client.Client
+
+	sync.Mutex
+	r         *Requester
+	connected bool
+}
+
+// NewClient returns a client calling services over the broker set with
+// client.Broker. Streaming is not supported.
+func NewClient(opts ...client.Option) client.Client {
+	return &requestClient{Client: client.NewClient(opts...)}
+}
+
+func (c *requestClient) Init(opts ...client.Option) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.Client.Init(opts...); err != nil {
+		return err
+	}
+	// the broker may have changed, subscribe again on the next call
+	if c.r != nil {
+		c.r.Close()
+		c.r = nil
+	}
+	c.connected = false
+	return nil
+}
+
+func (c *requestClient) requester() (*Requester, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	b := c.Options().Broker
+	if b == nil {
+		b = broker.DefaultBroker
+	}
+	// connect again on the next call if it failed
+	if !c.connected {
+		if err := b.Connect(); err != nil {
+			return nil, err
+		}
+		c.connected = true
+	}
+	if c.r == nil {
+		c.r = NewRequester(b)
+	}
+	return c.r, nil
+}
+
+func (c *requestClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	callOpts := c.Options().CallOptions
+	for _, o := range opts {
+		o(&callOpts)
+	}
+
+	if _, ok := ctx.Deadline(); !ok && callOpts.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, callOpts.RequestTimeout)
+		defer cancel()
+	}
+
+	cf, ok := defaultCodecs[req.ContentType()]
+	if !ok {
+		return errors.InternalServerError("go.micro.client", "unsupported content type %s", req.ContentType())
+	}
+
+	b, err := cf.Marshal(req.Body())
+	if err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	header := make(map[string]string)
+	if md, ok := metadata.FromContext(ctx); ok {
+		for k, v := range md {
+			header[k] = v
+		}
+	}
+	header["Content-Type"] = req.ContentType()
+	header["Micro-Service"] = req.Service()
+	header["Micro-Endpoint"] = req.Endpoint()
+
+	r, err := c.requester()
+	if err != nil {
+		return errors.InternalServerError("go.micro.client", "broker connect error: %v", err)
+	}
+
+	msg, err := r.Request(ctx, req.Service(), &broker.Message{Header: header, Body: b})
+	if err != nil {
+		if ctx.Err() != nil {
+			return errors.New("go.micro.client", fmt.Sprintf("%v", ctx.Err()), 408)
+		}
+		if _, ok := err.(*errors.Error); ok {
+			return err
+		}
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+
+	if err := cf.Unmarshal(msg.Body, rsp); err != nil {
+		return errors.InternalServerError("go.micro.client", err.Error())
+	}
+	return nil
+}
+
+func (c *requestClient) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	return nil, errors.InternalServerError("go.micro.client", "streaming is not supported over the broker")
+}
+
+func (c *requestClient) String() string {
+	return "request"
+}
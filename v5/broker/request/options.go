@@ -0,0 +1,40 @@
+package request
+
+import (
+	"time"
+
+	"go-micro.org/v5/broker"
+)
+
+// Options of a Requester.
+type Options struct {
+	// ReplyTopic the requester receives replies on, unique per process by default.
+	ReplyTopic string
+	// Timeout of requests whose context has no deadline.
+	Timeout time.Duration
+	// SubscribeOptions used to subscribe to the reply topic.
+	SubscribeOptions []broker.SubscribeOption
+}
+
+type Option func(o *Options)
+
+// ReplyTopic sets the topic replies are received on.
+func ReplyTopic(t string) Option {
+	return func(o *Options) {
+		o.ReplyTopic = t
+	}
+}
+
+// Timeout sets the timeout of requests whose context has no deadline.
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// SubscribeOptions sets the options used to subscribe to the reply topic.
+func SubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(o *Options) {
+		o.SubscribeOptions = opts
+	}
+}
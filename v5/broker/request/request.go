@@ -0,0 +1,193 @@
+// Package request provides request/reply messaging on top of a broker
+package request
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.org/v5/broker"
+	merrors "go-micro.org/v5/errors"
+)
+
+const (
+	// CorrelationHeader carries the id matching a reply to its request.
+	CorrelationHeader = "Micro-Correlation-Id"
+	// ReplyToHeader carries the topic the reply is published to.
+	ReplyToHeader = "Micro-Reply-To"
+	// DeadlineHeader carries the request deadline in unix nanoseconds.
+	DeadlineHeader = "Micro-Deadline"
+	// ErrorHeader carries the error returned by the responder.
+	ErrorHeader = "Micro-Error"
+)
+
+var (
+	DefaultTimeout = 5 * time.Second
+
+	ErrClosed = errors.New("requester closed")
+)
+
+// Requester publishes requests and waits for the correlated replies on a
+// reply topic of its own.
+type Requester struct {
+	b    broker.Broker
+	opts Options
+
+	// serialises subscribing to the reply topic
+	subMu sync.Mutex
+
+	sync.Mutex
+	sub     broker.Subscriber
+	closed  bool
+	pending map[string]chan *broker.Message
+}
+
+// NewRequester returns a requester publishing to b. The reply topic is
+// subscribed to with the first request.
+func NewRequester(b broker.Broker, opts ...Option) *Requester {
+	options := Options{
+		ReplyTopic: "micro.reply." + uuid.New().String(),
+		Timeout:    DefaultTimeout,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &Requester{
+		b:       b,
+		opts:    options,
+		pending: make(map[string]chan *broker.Message),
+	}
+}
+
+// ReplyTopic returns the topic replies are received on.
+func (r *Requester) ReplyTopic() string {
+	return r.opts.ReplyTopic
+}
+
+// Request publishes msg to topic and waits for the reply until the context
+// is done. Errors returned by the responder are returned as *errors.Error.
+func (r *Requester) Request(ctx context.Context, topic string, msg *broker.Message, opts ...broker.PublishOption) (*broker.Message, error) {
+	if _, ok := ctx.Deadline(); !ok && r.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		defer cancel()
+	}
+
+	id := uuid.New().String()
+	ch := make(chan *broker.Message, 1)
+
+	if err := r.subscribe(); err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	if r.closed {
+		r.Unlock()
+		return nil, ErrClosed
+	}
+	r.pending[id] = ch
+	r.Unlock()
+
+	defer func() {
+		r.Lock()
+		delete(r.pending, id)
+		r.Unlock()
+	}()
+
+	header := make(map[string]string, len(msg.Header)+3)
+	for k, v := range msg.Header {
+		header[k] = v
+	}
+	header[CorrelationHeader] = id
+	header[ReplyToHeader] = r.opts.ReplyTopic
+	if deadline, ok := ctx.Deadline(); ok {
+		header[DeadlineHeader] = strconv.FormatInt(deadline.UnixNano(), 10)
+	}
+
+	if err := r.b.Publish(topic, &broker.Message{Header: header, Body: msg.Body}, opts...); err != nil {
+		return nil, err
+	}
+
+	select {
+	case rsp := <-ch:
+		if v, ok := rsp.Header[ErrorHeader]; ok {
+			return nil, merrors.Parse(v)
+		}
+		return rsp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// subscribe subscribes to the reply topic unless it already is. The lock
+// guarding the pending requests is not held while subscribing, so replies
+// keep being handled.
+func (r *Requester) subscribe() error {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	r.Lock()
+	closed, subscribed := r.closed, r.sub != nil
+	r.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if subscribed {
+		return nil
+	}
+
+	sub, err := r.b.Subscribe(r.opts.ReplyTopic, r.handle, r.opts.SubscribeOptions...)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	if r.closed {
+		r.Unlock()
+		sub.Unsubscribe()
+		return ErrClosed
+	}
+	r.sub = sub
+	r.Unlock()
+	return nil
+}
+
+// Close unsubscribes from the reply topic. Pending requests run into their
+// deadline.
+func (r *Requester) Close() error {
+	r.Lock()
+	r.closed = true
+	sub := r.sub
+	r.sub = nil
+	r.Unlock()
+
+	if sub == nil {
+		return nil
+	}
+	return sub.Unsubscribe()
+}
+
+func (r *Requester) handle(e broker.Event) error {
+	msg := e.Message()
+	if msg == nil {
+		return nil
+	}
+
+	r.Lock()
+	ch, ok := r.pending[msg.Header[CorrelationHeader]]
+	r.Unlock()
+	if !ok {
+		// late reply to a request that already timed out
+		return nil
+	}
+
+	select {
+	case ch <- msg:
+	default:
+		// duplicate delivery, the first reply wins
+	}
+	return nil
+}
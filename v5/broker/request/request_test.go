@@ -0,0 +1,132 @@
+package request
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/client"
+	"go-micro.org/v5/errors"
+	"go-micro.org/v5/metadata"
+)
+
+func newBroker(t *testing.T) broker.Broker {
+	b := broker.NewMemoryBroker()
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Disconnect() })
+	return b
+}
+
+func TestRequestReply(t *testing.T) {
+	b := newBroker(t)
+
+	sub, err := Serve(b, "echo", func(ctx context.Context, req *broker.Message) (*broker.Message, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the request deadline to be propagated")
+		}
+		md, _ := metadata.FromContext(ctx)
+		return &broker.Message{Header: map[string]string{"Foo": md["Foo"]}, Body: req.Body}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	r := NewRequester(b)
+	defer r.Close()
+
+	rsp, err := r.Request(context.Background(), "echo", &broker.Message{
+		Header: map[string]string{"Foo": "bar"},
+		Body:   []byte("hello"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rsp.Body) != "hello" || rsp.Header["Foo"] != "bar" {
+		t.Fatalf("unexpected reply %+v", rsp)
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	b := newBroker(t)
+
+	sub, err := Serve(b, "fail", func(ctx context.Context, req *broker.Message) (*broker.Message, error) {
+		return nil, errors.BadRequest("test", "invalid request")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	r := NewRequester(b)
+	defer r.Close()
+
+	_, err = r.Request(context.Background(), "fail", &broker.Message{})
+	if e, ok := err.(*errors.Error); !ok || e.Code != 400 {
+		t.Fatalf("expected bad request error, got %v", err)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	b := newBroker(t)
+
+	r := NewRequester(b, Timeout(50*time.Millisecond))
+	defer r.Close()
+
+	_, err := r.Request(context.Background(), "nobody", &broker.Message{})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestClientCall(t *testing.T) {
+	b := newBroker(t)
+
+	sub, err := Serve(b, "greeter", func(ctx context.Context, req *broker.Message) (*broker.Message, error) {
+		if req.Header["Micro-Endpoint"] != "Greeter.Hello" {
+			return nil, errors.NotFound("greeter", "unknown endpoint %s", req.Header["Micro-Endpoint"])
+		}
+		return &broker.Message{Body: []byte(`{"msg":"hello john"}`)}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	c := NewClient(client.Broker(b), client.ContentType("application/json"))
+
+	var rsp struct {
+		Msg string `json:"msg"`
+	}
+	req := c.NewRequest("greeter", "Greeter.Hello", map[string]string{"name": "john"})
+	if err := c.Call(context.Background(), req, &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Msg != "hello john" {
+		t.Fatalf("unexpected response %+v", rsp)
+	}
+}
+
+func TestProtoCodecNonProto(t *testing.T) {
+	var c protoCodec
+	if _, err := c.Marshal(map[string]string{"foo": "bar"}); err == nil {
+		t.Fatal("expected an error marshalling a non proto value")
+	}
+	var v map[string]string
+	if err := c.Unmarshal([]byte{}, &v); err == nil {
+		t.Fatal("expected an error unmarshalling into a non proto value")
+	}
+}
+
+func TestRequestClosed(t *testing.T) {
+	r := NewRequester(newBroker(t))
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Request(context.Background(), "echo", &broker.Message{}); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
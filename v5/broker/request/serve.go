@@ -0,0 +1,69 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go-micro.org/v5/broker"
+	merrors "go-micro.org/v5/errors"
+	"go-micro.org/v5/metadata"
+)
+
+// Handler handles a request and returns the reply.
+type Handler func(ctx context.Context, req *broker.Message) (*broker.Message, error)
+
+// Serve subscribes to topic and replies to every request with the result of
+// fn. The request headers are passed to fn as context metadata, and requests
+// whose deadline has already passed are dropped.
+func Serve(b broker.Broker, topic string, fn Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(topic, func(e broker.Event) error {
+		req := e.Message()
+		if req == nil {
+			return nil
+		}
+
+		ctx := metadata.NewContext(context.Background(), req.Header)
+		if v, err := strconv.ParseInt(req.Header[DeadlineHeader], 10, 64); err == nil {
+			deadline := time.Unix(0, v)
+			if time.Now().After(deadline) {
+				// nobody is waiting for the reply anymore
+				return nil
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+
+		rsp, err := fn(ctx, req)
+		if len(req.Header[ReplyToHeader]) == 0 {
+			// fire and forget
+			return err
+		}
+		return Reply(b, req, rsp, err)
+	}, opts...)
+}
+
+// Reply publishes rsp, or err if it is not nil, to the reply topic of req.
+func Reply(b broker.Broker, req, rsp *broker.Message, err error) error {
+	replyTo := req.Header[ReplyToHeader]
+	if len(replyTo) == 0 {
+		return errors.New("request has no reply topic")
+	}
+
+	msg := &broker.Message{Header: make(map[string]string)}
+	if rsp != nil {
+		for k, v := range rsp.Header {
+			msg.Header[k] = v
+		}
+		msg.Body = rsp.Body
+	}
+	msg.Header[CorrelationHeader] = req.Header[CorrelationHeader]
+	if err != nil {
+		msg.Header[ErrorHeader] = merrors.FromError(err).Error()
+		msg.Body = nil
+	}
+
+	return b.Publish(replyTo, msg)
+}
@@ -0,0 +1,157 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"go-micro.org/v5/broker"
+	log "go-micro.org/v5/logger"
+)
+
+type schemaBroker struct {
+	broker.Broker
+	registry Registry
+	opts     Options
+
+	sync.RWMutex
+	// compiled validators by schema id, schemas are immutable
+	validators map[string]Validator
+}
+
+// NewBroker wraps b so that published messages are stamped with the
+// schema of their topic and validated against it, and received messages are
+// validated against the schema they were stamped with.
+func NewBroker(b broker.Broker, r Registry, opts ...Option) broker.Broker {
+	options := Options{
+		Subject:           func(topic string) string { return topic },
+		ValidatePublish:   true,
+		ValidateSubscribe: true,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &schemaBroker{
+		Broker:     b,
+		registry:   r,
+		opts:       options,
+		validators: make(map[string]Validator),
+	}
+}
+
+func (s *schemaBroker) validator(sc *Schema) (Validator, error) {
+	s.RLock()
+	v, ok := s.validators[sc.ID]
+	s.RUnlock()
+	if ok {
+		return v, nil
+	}
+
+	v, err := NewValidator(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	s.validators[sc.ID] = v
+	s.Unlock()
+	return v, nil
+}
+
+func (s *schemaBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	options := broker.PublishOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	sc, err := s.registry.Get(s.opts.Subject(topic), versionFromContext(options.Context))
+	if err == ErrNotFound && !s.opts.Required {
+		return s.Broker.Publish(topic, msg, opts...)
+	} else if err != nil {
+		return fmt.Errorf("schema for topic %s: %w", topic, err)
+	}
+
+	if s.opts.ValidatePublish {
+		v, err := s.validator(sc)
+		if err != nil {
+			return err
+		}
+		if err := v.Validate(msg.Body); err != nil {
+			return err
+		}
+	}
+
+	header := make(map[string]string, len(msg.Header)+3)
+	for k, v := range msg.Header {
+		header[k] = v
+	}
+	header[IDHeader] = sc.ID
+	header[SubjectHeader] = sc.Subject
+	header[VersionHeader] = strconv.Itoa(sc.Version)
+
+	return s.Broker.Publish(topic, &broker.Message{Header: header, Body: msg.Body}, opts...)
+}
+
+func (s *schemaBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if !s.opts.ValidateSubscribe {
+		return s.Broker.Subscribe(topic, h, opts...)
+	}
+
+	options := broker.NewSubscribeOptions(opts...)
+
+	return s.Broker.Subscribe(topic, func(e broker.Event) error {
+		if err := s.check(e.Message()); err != nil {
+			return s.reject(e, err, options.AutoAck)
+		}
+		return h(e)
+	}, opts...)
+}
+
+// check validates a received message against the schema it was stamped with.
+func (s *schemaBroker) check(msg *broker.Message) error {
+	id := msg.Header[IDHeader]
+	if len(id) == 0 {
+		if s.opts.Required {
+			return fmt.Errorf("%w: missing %s header", ErrInvalidMessage, IDHeader)
+		}
+		return nil
+	}
+
+	sc, err := s.registry.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("schema %s: %w", id, err)
+	}
+	v, err := s.validator(sc)
+	if err != nil {
+		return err
+	}
+	return v.Validate(msg.Body)
+}
+
+func (s *schemaBroker) reject(e broker.Event, err error, autoAck bool) error {
+	if len(s.opts.DeadLetter) == 0 {
+		return err
+	}
+
+	msg := e.Message()
+	header := make(map[string]string, len(msg.Header)+2)
+	for k, v := range msg.Header {
+		header[k] = v
+	}
+	header[ErrorHeader] = err.Error()
+	header[TopicHeader] = e.Topic()
+
+	if perr := s.Broker.Publish(s.opts.DeadLetter, &broker.Message{Header: header, Body: msg.Body}); perr != nil {
+		log.Errorf("schema: failed to dead letter message from %s: %v", e.Topic(), perr)
+		return err
+	}
+	// the handler never sees the message to ack it
+	if !autoAck {
+		return e.Ack()
+	}
+	return nil
+}
+
+func (s *schemaBroker) String() string {
+	return "schema"
+}
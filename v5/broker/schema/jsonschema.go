@@ -0,0 +1,393 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// jsonSchema is the subset of JSON Schema supported for validation: type,
+// properties, required, additionalProperties, items, enum, minimum,
+// maximum, minLength, maxLength, pattern, minItems and maxItems. Schemas
+// using other keywords are rejected, as they would not be enforced.
+type jsonSchema struct {
+	Type                 typeList               `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *additional            `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// typeList is a JSON Schema type, either a single type or a list.
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = typeList{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*t = l
+	return nil
+}
+
+func (t typeList) has(typ string) bool {
+	for _, v := range t {
+		if v == typ || (typ == "integer" && v == "number") {
+			return true
+		}
+	}
+	return false
+}
+
+// additional is the additionalProperties keyword, either a bool or a schema.
+type additional struct {
+	denied bool
+	schema *jsonSchema
+}
+
+func (a *additional) UnmarshalJSON(b []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(b, &allowed); err == nil {
+		a.denied = !allowed
+		return nil
+	}
+	a.schema = &jsonSchema{}
+	return json.Unmarshal(b, a.schema)
+}
+
+// keywords are the supported keywords, and the annotations which don't
+// affect validation.
+var keywords = map[string]bool{
+	"type":                 true,
+	"properties":           true,
+	"required":             true,
+	"additionalProperties": true,
+	"items":                true,
+	"enum":                 true,
+	"minimum":              true,
+	"maximum":              true,
+	"minLength":            true,
+	"maxLength":            true,
+	"pattern":              true,
+	"minItems":             true,
+	"maxItems":             true,
+
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+}
+
+// checkKeywords returns an error for the first unsupported keyword of a
+// schema or its subschemas.
+func checkKeywords(def json.RawMessage, path string) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(def, &m); err != nil {
+		return fmt.Errorf("schema at %s is not an object", path)
+	}
+
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if !keywords[k] {
+			return fmt.Errorf("unsupported keyword %q at %s", k, path)
+		}
+	}
+
+	if props, ok := m["properties"]; ok {
+		var pm map[string]json.RawMessage
+		if err := json.Unmarshal(props, &pm); err != nil {
+			return fmt.Errorf("properties at %s is not an object", path)
+		}
+		for name, p := range pm {
+			if err := checkKeywords(p, path+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if items, ok := m["items"]; ok {
+		if err := checkKeywords(items, path+".items"); err != nil {
+			return err
+		}
+	}
+	if ap, ok := m["additionalProperties"]; ok {
+		var allowed bool
+		if json.Unmarshal(ap, &allowed) != nil {
+			if err := checkKeywords(ap, path+".additionalProperties"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func parseJSONSchema(def []byte) (*jsonSchema, error) {
+	if err := checkKeywords(def, "$"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	s := &jsonSchema{}
+	if err := json.Unmarshal(def, s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	return s, nil
+}
+
+func compileJSONSchema(def []byte) (Validator, error) {
+	s, err := parseJSONSchema(def)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *jsonSchema) compile() error {
+	if len(s.Pattern) > 0 {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	for _, p := range s.Properties {
+		if err := p.compile(); err != nil {
+			return err
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.schema != nil {
+		if err := s.AdditionalProperties.schema.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+func (s *jsonSchema) Validate(data []byte) error {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	if err := d.Decode(&v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if err := s.validate(v, "$"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return nil
+}
+
+func jsonType(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if t == math.Trunc(t) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func (s *jsonSchema) validate(v interface{}, path string) error {
+	if len(s.Type) > 0 && !s.Type.has(jsonType(v)) {
+		return fmt.Errorf("%s: expected %v, got %s", path, []string(s.Type), jsonType(v))
+	}
+
+	if len(s.Enum) > 0 {
+		var found bool
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, v, s.Enum)
+		}
+	}
+
+	switch t := v.(type) {
+	case float64:
+		if s.Minimum != nil && t < *s.Minimum {
+			return fmt.Errorf("%s: %v is less than %v", path, t, *s.Minimum)
+		}
+		if s.Maximum != nil && t > *s.Maximum {
+			return fmt.Errorf("%s: %v is greater than %v", path, t, *s.Maximum)
+		}
+	case string:
+		n := utf8.RuneCountInString(t)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fmt.Errorf("%s: shorter than %d", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fmt.Errorf("%s: longer than %d", path, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(t) {
+			return fmt.Errorf("%s: does not match %s", path, s.Pattern)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(t) < *s.MinItems {
+			return fmt.Errorf("%s: fewer than %d items", path, *s.MinItems)
+		}
+		if s.MaxItems != nil && len(t) > *s.MaxItems {
+			return fmt.Errorf("%s: more than %d items", path, *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range t {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, r := range s.Required {
+			if _, ok := t[r]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, r)
+			}
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p, ok := s.Properties[k]
+			if !ok && s.AdditionalProperties != nil {
+				if s.AdditionalProperties.denied {
+					return fmt.Errorf("%s: unexpected property %q", path, k)
+				}
+				p = s.AdditionalProperties.schema
+			}
+			if p == nil {
+				continue
+			}
+			if err := p.validate(t[k], path+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkJSONSchema checks that data valid for the writer schema is also
+// valid for the reader schema, as far as it can be told from the keywords.
+func checkJSONSchema(reader, writer *Schema) error {
+	r, err := parseJSONSchema(reader.Definition)
+	if err != nil {
+		return err
+	}
+	w, err := parseJSONSchema(writer.Definition)
+	if err != nil {
+		return err
+	}
+	return accepts(r, w, "$")
+}
+
+func accepts(r, w *jsonSchema, path string) error {
+	if r == nil {
+		return nil
+	}
+	if w == nil {
+		w = &jsonSchema{}
+	}
+
+	if len(r.Type) > 0 {
+		if len(w.Type) == 0 {
+			return fmt.Errorf("%s: type %v is not restricted by the writer", path, []string(r.Type))
+		}
+		for _, t := range w.Type {
+			if !r.Type.has(t) {
+				return fmt.Errorf("%s: type %s is not accepted", path, t)
+			}
+		}
+	}
+
+	if len(r.Enum) > 0 {
+		if len(w.Enum) == 0 {
+			return fmt.Errorf("%s: values are not restricted by the writer", path)
+		}
+		for _, v := range w.Enum {
+			var found bool
+			for _, e := range r.Enum {
+				if reflect.DeepEqual(e, v) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("%s: value %v is not accepted", path, v)
+			}
+		}
+	}
+
+	required := make(map[string]bool, len(w.Required))
+	for _, p := range w.Required {
+		required[p] = true
+	}
+	for _, p := range r.Required {
+		if !required[p] {
+			return fmt.Errorf("%s: property %q is required but may be missing", path, p)
+		}
+	}
+
+	for name, wp := range w.Properties {
+		rp, ok := r.Properties[name]
+		if !ok {
+			if r.AdditionalProperties == nil {
+				continue
+			}
+			if r.AdditionalProperties.denied {
+				return fmt.Errorf("%s: property %q is not accepted", path, name)
+			}
+			rp = r.AdditionalProperties.schema
+		}
+		if err := accepts(rp, wp, path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	if r.Items != nil {
+		if err := accepts(r.Items, w.Items, path+"[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"context"
+	"time"
+
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/store"
+)
+
+// RegistryOptions of the store backed registry.
+type RegistryOptions struct {
+	// Store the schemas are kept in.
+	Store store.Store
+	// Prefix of the keys the schemas are written under.
+	Prefix string
+	// Compatibility of subjects without one set.
+	Compatibility Compatibility
+	// CacheTTL is how long the latest version of a subject is cached, zero
+	// disables caching it. Versions are always cached.
+	CacheTTL time.Duration
+}
+
+type RegistryOption func(o *RegistryOptions)
+
+// Store sets the store schemas are kept in, defaults to store.DefaultStore.
+func Store(s store.Store) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.Store = s
+	}
+}
+
+// Prefix sets the key prefix of schemas.
+func Prefix(p string) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.Prefix = p
+	}
+}
+
+// CompatibilityLevel sets the compatibility of subjects without one
+// set, defaults to Backward.
+func CompatibilityLevel(c Compatibility) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.Compatibility = c
+	}
+}
+
+// CacheTTL sets how long the latest version of a subject is cached,
+// defaults to DefaultCacheTTL.
+func CacheTTL(d time.Duration) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.CacheTTL = d
+	}
+}
+
+// Options of the validating broker.
+type Options struct {
+	// Subject returns the subject of a topic, the topic itself by default.
+	Subject func(topic string) string
+	// ValidatePublish validates messages before they are published.
+	ValidatePublish bool
+	// ValidateSubscribe validates messages before they are handled.
+	ValidateSubscribe bool
+	// Required rejects messages on topics without a registered schema and
+	// received messages without a schema header.
+	Required bool
+	// DeadLetter is the topic rejected messages are published to. Rejected
+	// messages are returned to the broker as handler errors when empty.
+	DeadLetter string
+}
+
+type Option func(o *Options)
+
+// Subject sets the function mapping topics to subjects.
+func Subject(fn func(topic string) string) Option {
+	return func(o *Options) {
+		o.Subject = fn
+	}
+}
+
+// ValidatePublish enables or disables validation on publish.
+func ValidatePublish(b bool) Option {
+	return func(o *Options) {
+		o.ValidatePublish = b
+	}
+}
+
+// ValidateSubscribe enables or disables validation on receive.
+func ValidateSubscribe(b bool) Option {
+	return func(o *Options) {
+		o.ValidateSubscribe = b
+	}
+}
+
+// Required rejects messages without a schema.
+func Required() Option {
+	return func(o *Options) {
+		o.Required = true
+	}
+}
+
+// DeadLetter publishes rejected messages to topic with the ErrorHeader set.
+func DeadLetter(topic string) Option {
+	return func(o *Options) {
+		o.DeadLetter = topic
+	}
+}
+
+type versionKey struct{}
+
+// Version publishes the message with a version of the subject other than
+// the latest.
+func Version(v int) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, versionKey{}, v)
+	}
+}
+
+func versionFromContext(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	v, _ := ctx.Value(versionKey{}).(int)
+	return v
+}
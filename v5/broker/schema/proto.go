@@ -0,0 +1,145 @@
+package schema
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type protoValidator struct {
+	desc protoreflect.MessageDescriptor
+}
+
+// parseProto resolves the message descriptor from a serialized
+// FileDescriptorSet as produced by protoc --descriptor_set_out with
+// --include_imports.
+func parseProto(def []byte, name string) (protoreflect.MessageDescriptor, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(def, set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: message %q: %v", ErrInvalidFormat, name, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not a message", ErrInvalidFormat, name)
+	}
+	return md, nil
+}
+
+func compileProto(def []byte, name string) (Validator, error) {
+	md, err := parseProto(def, name)
+	if err != nil {
+		return nil, err
+	}
+	return &protoValidator{desc: md}, nil
+}
+
+func (p *protoValidator) Validate(data []byte) error {
+	msg := dynamicpb.NewMessage(p.desc)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if err := proto.CheckInitialized(msg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return nil
+}
+
+// checkProto checks that messages written with the writer descriptor can be
+// decoded with the reader descriptor. Fields are matched by number.
+func checkProto(reader, writer *Schema) error {
+	r, err := parseProto(reader.Definition, reader.MessageName)
+	if err != nil {
+		return err
+	}
+	w, err := parseProto(writer.Definition, writer.MessageName)
+	if err != nil {
+		return err
+	}
+	return checkMessage(r, w, map[[2]protoreflect.FullName]bool{})
+}
+
+func checkMessage(r, w protoreflect.MessageDescriptor, seen map[[2]protoreflect.FullName]bool) error {
+	pair := [2]protoreflect.FullName{r.FullName(), w.FullName()}
+	if seen[pair] {
+		return nil
+	}
+	seen[pair] = true
+
+	rf := r.Fields()
+	wf := w.Fields()
+
+	for i := 0; i < rf.Len(); i++ {
+		f := rf.Get(i)
+		if f.Cardinality() == protoreflect.Required && wf.ByNumber(f.Number()) == nil {
+			return fmt.Errorf("%s: required field %d is not written", r.FullName(), f.Number())
+		}
+	}
+
+	for i := 0; i < wf.Len(); i++ {
+		f := wf.Get(i)
+		g := rf.ByNumber(f.Number())
+		if g == nil {
+			// unknown fields are skipped by the reader
+			continue
+		}
+		if f.IsMap() != g.IsMap() || f.IsList() != g.IsList() {
+			return fmt.Errorf("%s: field %d changed cardinality", r.FullName(), f.Number())
+		}
+		if kindGroup(f.Kind()) != kindGroup(g.Kind()) {
+			return fmt.Errorf("%s: field %d changed type from %s to %s", r.FullName(), f.Number(), f.Kind(), g.Kind())
+		}
+		if f.IsMap() {
+			if kindGroup(f.MapKey().Kind()) != kindGroup(g.MapKey().Kind()) {
+				return fmt.Errorf("%s: field %d changed map key type", r.FullName(), f.Number())
+			}
+			f, g = f.MapValue(), g.MapValue()
+			if kindGroup(f.Kind()) != kindGroup(g.Kind()) {
+				return fmt.Errorf("%s: field %d changed map value type", r.FullName(), f.Number())
+			}
+		}
+		if f.Message() != nil && g.Message() != nil {
+			if err := checkMessage(g.Message(), f.Message(), seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// kindGroup groups kinds sharing a wire encoding that decode into each other.
+func kindGroup(k protoreflect.Kind) string {
+	switch k {
+	case protoreflect.Int32Kind, protoreflect.Uint32Kind, protoreflect.Int64Kind,
+		protoreflect.Uint64Kind, protoreflect.BoolKind, protoreflect.EnumKind:
+		return "varint"
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "zigzag"
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		return "fixed32"
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind:
+		return "fixed64"
+	case protoreflect.FloatKind:
+		return "float"
+	case protoreflect.DoubleKind:
+		return "double"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "bytes"
+	case protoreflect.MessageKind:
+		return "message"
+	case protoreflect.GroupKind:
+		return "group"
+	}
+	return k.String()
+}
@@ -0,0 +1,254 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.org/v5/store"
+)
+
+var (
+	DefaultPrefix        = "micro/schema/"
+	DefaultCompatibility = Backward
+	// DefaultCacheTTL is how long the latest version of a subject is
+	// cached. Registering through the registry invalidates it at once,
+	// registrations of other processes are seen once it expires.
+	DefaultCacheTTL = 30 * time.Second
+)
+
+type storeRegistry struct {
+	opts RegistryOptions
+
+	// serialises registration so versions are assigned in order
+	sync.Mutex
+
+	mu sync.RWMutex
+	// schemas by version and id key, versions are immutable
+	schemas map[string]*Schema
+	// latest version by subject
+	latest map[string]*latestSchema
+}
+
+type latestSchema struct {
+	schema  *Schema
+	expires time.Time
+}
+
+// NewRegistry returns a Registry keeping schemas in a store.
+func NewRegistry(opts ...RegistryOption) Registry {
+	options := RegistryOptions{
+		Store:         store.DefaultStore,
+		Prefix:        DefaultPrefix,
+		Compatibility: DefaultCompatibility,
+		CacheTTL:      DefaultCacheTTL,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &storeRegistry{
+		opts:    options,
+		schemas: make(map[string]*Schema),
+		latest:  make(map[string]*latestSchema),
+	}
+}
+
+func (r *storeRegistry) versionKey(subject string, version int) string {
+	return fmt.Sprintf("%ssubjects/%s/%08d", r.opts.Prefix, subject, version)
+}
+
+func (r *storeRegistry) idKey(id string) string {
+	return r.opts.Prefix + "ids/" + id
+}
+
+func (r *storeRegistry) configKey(subject string) string {
+	return r.opts.Prefix + "config/" + subject
+}
+
+func (r *storeRegistry) read(key string) (*Schema, error) {
+	recs, err := r.opts.Store.Read(key)
+	if err == store.ErrNotFound || (err == nil && len(recs) == 0) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	s := &Schema{}
+	if err := json.Unmarshal(recs[0].Value, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// cached reads key once and keeps the schema, which never changes.
+func (r *storeRegistry) cached(key string) (*Schema, error) {
+	r.mu.RLock()
+	s, ok := r.schemas[key]
+	r.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	s, err := r.read(key)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.schemas[key] = s
+	r.mu.Unlock()
+	return s, nil
+}
+
+func (r *storeRegistry) write(key string, s *Schema) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return r.opts.Store.Write(&store.Record{Key: key, Value: b})
+}
+
+func (r *storeRegistry) Register(s *Schema) (*Schema, error) {
+	if len(s.Subject) == 0 {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidFormat)
+	}
+	// compiling checks the definition is valid
+	if _, err := NewValidator(s); err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	versions, err := r.Versions(s.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *Schema
+	for _, v := range versions {
+		prev, err := r.read(r.versionKey(s.Subject, v))
+		if err != nil {
+			return nil, err
+		}
+		if prev.Format == s.Format && prev.MessageName == s.MessageName && bytes.Equal(prev.Definition, s.Definition) {
+			return prev, nil
+		}
+		latest = prev
+	}
+
+	c, err := r.Compatibility(s.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckCompatibility(latest, s, c); err != nil {
+		return nil, err
+	}
+
+	next := &Schema{
+		ID:          uuid.New().String(),
+		Subject:     s.Subject,
+		Version:     1,
+		Format:      s.Format,
+		Definition:  s.Definition,
+		MessageName: s.MessageName,
+		Created:     time.Now(),
+	}
+	if latest != nil {
+		next.Version = latest.Version + 1
+	}
+
+	if err := r.write(r.idKey(next.ID), next); err != nil {
+		return nil, err
+	}
+	if err := r.write(r.versionKey(next.Subject, next.Version), next); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	delete(r.latest, next.Subject)
+	r.mu.Unlock()
+	return next, nil
+}
+
+func (r *storeRegistry) Get(subject string, version int) (*Schema, error) {
+	if version > 0 {
+		return r.cached(r.versionKey(subject, version))
+	}
+
+	r.mu.RLock()
+	l, ok := r.latest[subject]
+	r.mu.RUnlock()
+	if ok && time.Now().Before(l.expires) {
+		return l.schema, nil
+	}
+
+	versions, err := r.Versions(subject)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	s, err := r.cached(r.versionKey(subject, versions[len(versions)-1]))
+	if err != nil {
+		return nil, err
+	}
+
+	if r.opts.CacheTTL > 0 {
+		r.mu.Lock()
+		r.latest[subject] = &latestSchema{schema: s, expires: time.Now().Add(r.opts.CacheTTL)}
+		r.mu.Unlock()
+	}
+	return s, nil
+}
+
+func (r *storeRegistry) GetByID(id string) (*Schema, error) {
+	return r.cached(r.idKey(id))
+}
+
+func (r *storeRegistry) Versions(subject string) ([]int, error) {
+	prefix := r.versionKey(subject, 0)
+	prefix = prefix[:len(prefix)-8]
+
+	keys, err := r.opts.Store.List(store.ListPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int, 0, len(keys))
+	for _, k := range keys {
+		v, err := strconv.Atoi(strings.TrimPrefix(k, prefix))
+		if err != nil {
+			// a key of a subject sharing the prefix
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+func (r *storeRegistry) SetCompatibility(subject string, c Compatibility) error {
+	switch c {
+	case None, Backward, Forward, Full:
+	default:
+		return fmt.Errorf("unknown compatibility %q", c)
+	}
+	return r.opts.Store.Write(&store.Record{Key: r.configKey(subject), Value: []byte(c)})
+}
+
+func (r *storeRegistry) Compatibility(subject string) (Compatibility, error) {
+	recs, err := r.opts.Store.Read(r.configKey(subject))
+	if err == store.ErrNotFound || (err == nil && len(recs) == 0) {
+		return r.opts.Compatibility, nil
+	} else if err != nil {
+		return "", err
+	}
+	return Compatibility(recs[0].Value), nil
+}
@@ -0,0 +1,141 @@
+// Package schema provides a schema registry and schema validation for broker messages
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Format of a schema definition.
+type Format string
+
+const (
+	// JSONSchema definitions are JSON Schema documents.
+	JSONSchema Format = "json-schema"
+	// Protobuf definitions are serialized FileDescriptorSets, MessageName
+	// selects the message type.
+	Protobuf Format = "protobuf"
+)
+
+// Compatibility a new version of a subject is checked for against the
+// latest registered version.
+type Compatibility string
+
+const (
+	// None disables compatibility checks.
+	None Compatibility = "none"
+	// Backward allows consumers of the new version to read data written
+	// with the previous version.
+	Backward Compatibility = "backward"
+	// Forward allows consumers of the previous version to read data written
+	// with the new version.
+	Forward Compatibility = "forward"
+	// Full is both backward and forward compatible.
+	Full Compatibility = "full"
+)
+
+const (
+	// IDHeader carries the id of the schema a message was written with.
+	IDHeader = "Micro-Schema-Id"
+	// SubjectHeader carries the subject of the schema.
+	SubjectHeader = "Micro-Schema-Subject"
+	// VersionHeader carries the version of the schema.
+	VersionHeader = "Micro-Schema-Version"
+	// ErrorHeader carries the validation error of a dead lettered message.
+	ErrorHeader = "Micro-Schema-Error"
+	// TopicHeader carries the original topic of a dead lettered message.
+	TopicHeader = "Micro-Schema-Topic"
+)
+
+var (
+	ErrNotFound       = errors.New("schema not found")
+	ErrIncompatible   = errors.New("schema is incompatible")
+	ErrInvalidFormat  = errors.New("invalid schema format")
+	ErrInvalidMessage = errors.New("message does not match schema")
+)
+
+// Schema is a registered version of a subject.
+type Schema struct {
+	// ID uniquely identifies the schema across subjects.
+	ID string `json:"id"`
+	// Subject the schema belongs to, usually the topic.
+	Subject string `json:"subject"`
+	// Version of the schema within the subject, starting at 1.
+	Version int `json:"version"`
+	// Format of the definition.
+	Format Format `json:"format"`
+	// Definition of the schema.
+	Definition []byte `json:"definition"`
+	// MessageName is the full name of the protobuf message.
+	MessageName string `json:"message_name,omitempty"`
+	// Created is when the version was registered.
+	Created time.Time `json:"created"`
+}
+
+// Registry stores the versions of schemas per subject.
+type Registry interface {
+	// Register adds a new version of the subject after checking it is
+	// compatible with the latest version. Registering a definition equal to
+	// an existing version returns that version.
+	Register(s *Schema) (*Schema, error)
+	// Get returns a version of the subject, version 0 is the latest.
+	Get(subject string, version int) (*Schema, error)
+	// GetByID returns the schema with the id.
+	GetByID(id string) (*Schema, error)
+	// Versions returns the registered versions of the subject.
+	Versions(subject string) ([]int, error)
+	// SetCompatibility sets the compatibility new versions of the subject
+	// are checked for.
+	SetCompatibility(subject string, c Compatibility) error
+	// Compatibility returns the compatibility of the subject.
+	Compatibility(subject string) (Compatibility, error)
+}
+
+// Validator validates message bodies.
+type Validator interface {
+	Validate(data []byte) error
+}
+
+// NewValidator compiles the definition of s.
+func NewValidator(s *Schema) (Validator, error) {
+	switch s.Format {
+	case JSONSchema:
+		return compileJSONSchema(s.Definition)
+	case Protobuf:
+		return compileProto(s.Definition, s.MessageName)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, s.Format)
+}
+
+// CheckCompatibility checks that next is compatible with prev.
+func CheckCompatibility(prev, next *Schema, c Compatibility) error {
+	if c == None || prev == nil {
+		return nil
+	}
+	if prev.Format != next.Format {
+		return fmt.Errorf("%w: format changed from %s to %s", ErrIncompatible, prev.Format, next.Format)
+	}
+
+	var check func(reader, writer *Schema) error
+	switch next.Format {
+	case JSONSchema:
+		check = checkJSONSchema
+	case Protobuf:
+		check = checkProto
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidFormat, next.Format)
+	}
+
+	if c == Backward || c == Full {
+		if err := check(next, prev); err != nil {
+			return fmt.Errorf("%w: not backward compatible: %v", ErrIncompatible, err)
+		}
+	}
+	if c == Forward || c == Full {
+		if err := check(prev, next); err != nil {
+			return fmt.Errorf("%w: not forward compatible: %v", ErrIncompatible, err)
+		}
+	}
+	return nil
+}
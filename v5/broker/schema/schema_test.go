@@ -0,0 +1,365 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/store"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	orderV1 = `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "string", "minLength": 1},
+			"amount": {"type": "integer", "minimum": 0}
+		},
+		"required": ["id", "amount"]
+	}`
+	// adds an optional property
+	orderV2 = `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "string", "minLength": 1},
+			"amount": {"type": "integer", "minimum": 0},
+			"currency": {"type": "string", "enum": ["EUR", "USD"]}
+		},
+		"required": ["id", "amount"]
+	}`
+	// requires a property older publishers don't write
+	orderV3 = `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "string"},
+			"amount": {"type": "integer"},
+			"customer": {"type": "string"}
+		},
+		"required": ["id", "amount", "customer"]
+	}`
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(Store(store.NewMemoryStore()))
+
+	v1, err := r.Register(&Schema{Subject: "orders", Format: JSONSchema, Definition: []byte(orderV1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.Version != 1 || len(v1.ID) == 0 {
+		t.Fatalf("unexpected schema %+v", v1)
+	}
+
+	again, err := r.Register(&Schema{Subject: "orders", Format: JSONSchema, Definition: []byte(orderV1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != v1.ID {
+		t.Fatalf("expected equal definition to return version 1, got %+v", again)
+	}
+
+	v2, err := r.Register(&Schema{Subject: "orders", Format: JSONSchema, Definition: []byte(orderV2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2.Version != 2 {
+		t.Fatalf("expected version 2, got %d", v2.Version)
+	}
+
+	if _, err := r.Register(&Schema{Subject: "orders", Format: JSONSchema, Definition: []byte(orderV3)}); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected incompatible error, got %v", err)
+	}
+
+	if err := r.SetCompatibility("orders", None); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Register(&Schema{Subject: "orders", Format: JSONSchema, Definition: []byte(orderV3)}); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := r.Get("orders", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != 3 {
+		t.Fatalf("expected latest version 3, got %d", latest.Version)
+	}
+	byID, err := r.GetByID(v2.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byID.Version != 2 {
+		t.Fatalf("expected version 2, got %d", byID.Version)
+	}
+	versions, err := r.Versions("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("expected 3 versions, got %v", versions)
+	}
+	if _, err := r.Get("payments", 0); err != ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+// countingStore counts the store reads and lists.
+type countingStore struct {
+	store.Store
+	calls int
+}
+
+func (s *countingStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	s.calls++
+	return s.Store.Read(key, opts...)
+}
+
+func (s *countingStore) List(opts ...store.ListOption) ([]string, error) {
+	s.calls++
+	return s.Store.List(opts...)
+}
+
+func TestRegistryCache(t *testing.T) {
+	st := &countingStore{Store: store.NewMemoryStore()}
+	r := NewRegistry(Store(st))
+
+	v1, err := r.Register(&Schema{Subject: "orders", Format: JSONSchema, Definition: []byte(orderV1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get("orders", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := st.calls
+	for i := 0; i < 3; i++ {
+		latest, err := r.Get("orders", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latest.ID != v1.ID {
+			t.Fatalf("expected version 1, got %+v", latest)
+		}
+	}
+	if st.calls != calls {
+		t.Fatalf("expected the latest version to be cached, got %d store calls", st.calls-calls)
+	}
+
+	v2, err := r.Register(&Schema{Subject: "orders", Format: JSONSchema, Definition: []byte(orderV2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := r.Get("orders", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.ID != v2.ID {
+		t.Fatalf("expected registering to invalidate the latest version, got %+v", latest)
+	}
+
+	if _, err := r.Get("orders", 1); err != nil {
+		t.Fatal(err)
+	}
+	calls = st.calls
+	if _, err := r.Get("orders", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.GetByID(v1.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.GetByID(v1.ID); err != nil {
+		t.Fatal(err)
+	}
+	if st.calls != calls+1 {
+		t.Fatalf("expected versions to be cached, got %d store calls", st.calls-calls)
+	}
+}
+
+func TestForwardCompatibility(t *testing.T) {
+	prev := &Schema{Format: JSONSchema, Definition: []byte(orderV2)}
+	// dropping an optional property is fine for readers of v2
+	next := &Schema{Format: JSONSchema, Definition: []byte(orderV1)}
+	if err := CheckCompatibility(prev, next, Forward); err != nil {
+		t.Fatal(err)
+	}
+	// readers of v1 don't require the new customer property
+	if err := CheckCompatibility(&Schema{Format: JSONSchema, Definition: []byte(orderV1)}, &Schema{Format: JSONSchema, Definition: []byte(orderV3)}, Forward); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckCompatibility(&Schema{Format: JSONSchema, Definition: []byte(orderV1)}, &Schema{Format: JSONSchema, Definition: []byte(orderV3)}, Full); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected incompatible error, got %v", err)
+	}
+}
+
+func TestJSONSchemaValidate(t *testing.T) {
+	v, err := NewValidator(&Schema{Format: JSONSchema, Definition: []byte(orderV2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"id": "1", "amount": 10}`, true},
+		{`{"id": "1", "amount": 10, "currency": "EUR"}`, true},
+		{`{"id": "1"}`, false},
+		{`{"id": "", "amount": 10}`, false},
+		{`{"id": "1", "amount": 1.5}`, false},
+		{`{"id": "1", "amount": -1}`, false},
+		{`{"id": "1", "amount": 10, "currency": "GBP"}`, false},
+		{`not json`, false},
+	}
+
+	for _, d := range testData {
+		err := v.Validate([]byte(d.body))
+		if d.valid && err != nil {
+			t.Fatalf("expected %s to be valid, got %v", d.body, err)
+		}
+		if !d.valid && !errors.Is(err, ErrInvalidMessage) {
+			t.Fatalf("expected %s to be invalid, got %v", d.body, err)
+		}
+	}
+}
+
+func TestJSONSchemaKeywords(t *testing.T) {
+	r := NewRegistry(Store(store.NewMemoryStore()))
+
+	for _, def := range []string{
+		`{"$ref": "#/definitions/order"}`,
+		`{"type": "object", "oneOf": [{"required": ["id"]}]}`,
+		`{"type": "object", "properties": {"id": {"type": "string", "format": "uuid"}}}`,
+		`{"type": "array", "items": {"const": 1}}`,
+		`{"type": "object", "additionalProperties": {"not": {"type": "string"}}}`,
+	} {
+		_, err := r.Register(&Schema{Subject: "keywords", Format: JSONSchema, Definition: []byte(def)})
+		if !errors.Is(err, ErrInvalidFormat) {
+			t.Fatalf("expected %s to be rejected, got %v", def, err)
+		}
+	}
+
+	def := `{"$schema": "http://json-schema.org/draft-07/schema#", "title": "order", "type": "object", "additionalProperties": false}`
+	if _, err := r.Register(&Schema{Subject: "keywords", Format: JSONSchema, Definition: []byte(def)}); err != nil {
+		t.Fatalf("expected annotations to be accepted, got %v", err)
+	}
+}
+
+func descriptorSet(t *testing.T, fd protoreflect.FileDescriptor) []byte {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(fd)},
+	}
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestProtobuf(t *testing.T) {
+	ts := &Schema{
+		Format:      Protobuf,
+		Definition:  descriptorSet(t, timestamppb.File_google_protobuf_timestamp_proto),
+		MessageName: "google.protobuf.Timestamp",
+	}
+	v, err := NewValidator(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := proto.Marshal(timestamppb.New(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Validate(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Validate([]byte{0xff}); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("expected invalid message, got %v", err)
+	}
+
+	str := &Schema{
+		Format:      Protobuf,
+		Definition:  descriptorSet(t, wrapperspb.File_google_protobuf_wrappers_proto),
+		MessageName: "google.protobuf.StringValue",
+	}
+	if err := CheckCompatibility(ts, str, Backward); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected incompatible error, got %v", err)
+	}
+	if err := CheckCompatibility(ts, ts, Full); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	r := NewRegistry(Store(store.NewMemoryStore()))
+	if _, err := r.Register(&Schema{Subject: "orders", Format: JSONSchema, Definition: []byte(orderV1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	mb := broker.NewMemoryBroker()
+	b := NewBroker(mb, r, DeadLetter("orders.dlq"))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan *broker.Message, 1)
+	if _, err := b.Subscribe("orders", func(e broker.Event) error {
+		received <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	dead := make(chan *broker.Message, 1)
+	if _, err := mb.Subscribe("orders.dlq", func(e broker.Event) error {
+		dead <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("orders", &broker.Message{Body: []byte(`{"id": "1"}`)}); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("expected invalid message on publish, got %v", err)
+	}
+
+	if err := b.Publish("orders", &broker.Message{Body: []byte(`{"id": "1", "amount": 10}`)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-received:
+		if m.Header[VersionHeader] != "1" || m.Header[SubjectHeader] != "orders" {
+			t.Fatalf("expected schema headers, got %v", m.Header)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("valid message was not received")
+	}
+
+	// bypass the wrapper to publish an invalid stamped message
+	latest, _ := r.Get("orders", 0)
+	if err := mb.Publish("orders", &broker.Message{
+		Header: map[string]string{IDHeader: latest.ID},
+		Body:   []byte(`{"amount": 10}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-dead:
+		if len(m.Header[ErrorHeader]) == 0 || m.Header[TopicHeader] != "orders" {
+			t.Fatalf("expected error headers, got %v", m.Header)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("invalid message was not dead lettered")
+	}
+	select {
+	case <-received:
+		t.Fatal("invalid message was handled")
+	default:
+	}
+}
@@ -2,6 +2,7 @@ package segmentio
 
 import (
 	"context"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 	"go-micro.org/v5/broker"
@@ -10,6 +11,9 @@ import (
 var (
 	DefaultReaderConfig = kafka.WriterConfig{}
 	DefaultWriterConfig = kafka.ReaderConfig{}
+	// BatchTimeout is how long a batch writer waits for a partition's
+	// batch to fill before sending it. PublishBatch pays it at most once.
+	BatchTimeout = 10 * time.Millisecond
 )
 
 type readerConfigKey struct{}
@@ -41,3 +45,47 @@ type subscribeWriterConfigKey struct{}
 func SubscribeWriterConfig(c kafka.WriterConfig) broker.SubscribeOption {
 	return setSubscribeOption(subscribeWriterConfigKey{}, c)
 }
+
+type publishKey struct{}
+type publishPartitionKey struct{}
+
+// PublishKey sets the message key used by the balancer. Messages with the
+// same key are written to the same partition by the hash balancers.
+func PublishKey(key []byte) broker.PublishOption {
+	return setPublishOption(publishKey{}, key)
+}
+
+// PublishPartition writes the message to an explicit partition, bypassing
+// the configured balancer.
+func PublishPartition(partition int) broker.PublishOption {
+	return setPublishOption(publishPartitionKey{}, partition)
+}
+
+type batchCommitKey struct{}
+
+type batchCommit struct {
+	size     int
+	interval time.Duration
+}
+
+// CommitAfterHandle commits the offset of every message synchronously as
+// soon as it and every earlier message of its partition have been acked.
+func CommitAfterHandle() broker.SubscribeOption {
+	return setSubscribeOption(batchCommitKey{}, batchCommit{size: 1})
+}
+
+// BatchCommit commits acked offsets once size messages have been acked or
+// interval has elapsed, whichever comes first. As with CommitAfterHandle,
+// only offsets below which every message has been acked are committed.
+func BatchCommit(size int, interval time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(batchCommitKey{}, batchCommit{size: size, interval: interval})
+}
+
+type orderedConcurrencyKey struct{}
+
+// OrderedConcurrency handles up to n messages of a partition concurrently.
+// Messages with the same key are always handled in order by the same worker,
+// and offsets are only committed once all previous messages are acked.
+func OrderedConcurrency(n int) broker.SubscribeOption {
+	return setSubscribeOption(orderedConcurrencyKey{}, n)
+}
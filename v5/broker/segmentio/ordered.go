@@ -0,0 +1,222 @@
+package segmentio
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/logger"
+)
+
+// balancer honours partitions set with PublishPartition and delegates
+// all other messages to the configured balancer.
+type balancer struct {
+	kafka.Balancer
+}
+
+func newBalancer(b kafka.Balancer) kafka.Balancer {
+	if b == nil {
+		b = &kafka.RoundRobin{}
+	}
+	return &balancer{Balancer: b}
+}
+
+func (b *balancer) Balance(msg kafka.Message, partitions ...int) int {
+	if msg.Partition >= 0 {
+		return msg.Partition
+	}
+	msg.Partition = 0
+	return b.Balancer.Balance(msg, partitions...)
+}
+
+// committer commits offsets of a single partition according to the
+// subscription's commit mode. By default every marked offset is committed.
+type committer struct {
+	commit func(offset int64) error
+	size   int
+	logger logger.Logger
+	exit   chan struct{}
+
+	// serialises commits so offsets are committed in order
+	commitMu sync.Mutex
+
+	mu      sync.Mutex
+	offset  int64
+	pending int
+}
+
+// manualCommit returns whether a commit mode has been set, in which case
+// offsets are only committed up to the low watermark of acked messages.
+func manualCommit(opts broker.SubscribeOptions) bool {
+	_, ok := subscribeValue(opts, batchCommitKey{}).(batchCommit)
+	return ok
+}
+
+func newCommitter(commit func(offset int64) error, opts broker.SubscribeOptions, log logger.Logger) *committer {
+	c := &committer{
+		commit: commit,
+		size:   1,
+		logger: log,
+		exit:   make(chan struct{}),
+	}
+	if b, ok := subscribeValue(opts, batchCommitKey{}).(batchCommit); ok {
+		c.size = b.size
+		if b.interval > 0 {
+			go c.run(b.interval)
+		}
+	}
+	return c
+}
+
+// mark records offset as handled and commits it once the batch is full.
+func (c *committer) mark(offset int64) error {
+	c.mu.Lock()
+	if offset > c.offset || c.pending == 0 {
+		c.offset = offset
+	}
+	c.pending++
+	full := c.size > 0 && c.pending >= c.size
+	c.mu.Unlock()
+	if full {
+		return c.flush()
+	}
+	return nil
+}
+
+func (c *committer) flush() error {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	c.mu.Lock()
+	if c.pending == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	c.pending = 0
+	offset := c.offset
+	c.mu.Unlock()
+	return c.commit(offset)
+}
+
+func (c *committer) run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.exit:
+			return
+		case <-t.C:
+			if err := c.flush(); err != nil {
+				c.logger.Logf(logger.ErrorLevel, "[segmentio]: unable to commit msg: %v", err)
+			}
+		}
+	}
+}
+
+func (c *committer) stop() {
+	close(c.exit)
+	if err := c.flush(); err != nil {
+		c.logger.Logf(logger.TraceLevel, "[segmentio]: unable to commit msg: %v", err)
+	}
+}
+
+type trackedMessage struct {
+	msg   kafka.Message
+	acked bool
+}
+
+// offsetTracker keeps the in flight messages of a partition in offset order
+// and marks the low watermark of acked offsets, so an offset is only marked
+// once it and every message before it have been acked. A message which is
+// never acked holds back the partition until it is redelivered by the next
+// generation.
+type offsetTracker struct {
+	mark func(offset int64) error
+
+	sync.Mutex
+	inflight []*trackedMessage
+}
+
+func (t *offsetTracker) add(msg kafka.Message) *trackedMessage {
+	tm := &trackedMessage{msg: msg}
+	t.Lock()
+	t.inflight = append(t.inflight, tm)
+	t.Unlock()
+	return tm
+}
+
+// ack may be called at any time, also after the handler returned. The
+// offset is marked after releasing the lock as marking may commit it.
+func (t *offsetTracker) ack(tm *trackedMessage) error {
+	t.Lock()
+	tm.acked = true
+	mark := int64(-1)
+	for len(t.inflight) > 0 && t.inflight[0].acked {
+		mark = t.inflight[0].msg.Offset
+		t.inflight = t.inflight[1:]
+	}
+	t.Unlock()
+
+	if mark < 0 {
+		return nil
+	}
+	return t.mark(mark)
+}
+
+// orderedDispatcher hands the messages of a partition to n workers. Messages
+// with the same key always go to the same worker, keyless messages are
+// spread by offset.
+type orderedDispatcher struct {
+	h       *cgHandler
+	t       *offsetTracker
+	workers []chan *trackedMessage
+	wg      sync.WaitGroup
+}
+
+func newOrderedDispatcher(h *cgHandler, t *offsetTracker, n int) *orderedDispatcher {
+	d := &orderedDispatcher{h: h, t: t, workers: make([]chan *trackedMessage, n)}
+	for i := range d.workers {
+		ch := make(chan *trackedMessage, 16)
+		d.workers[i] = ch
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for tm := range ch {
+				tm := tm
+				d.h.handle(tm.msg, func() error { return d.t.ack(tm) })
+			}
+		}()
+	}
+	return d
+}
+
+func (d *orderedDispatcher) dispatch(msg kafka.Message) {
+	tm := d.t.add(msg)
+	d.workers[workerFor(msg, len(d.workers))] <- tm
+}
+
+func (d *orderedDispatcher) close() {
+	for _, ch := range d.workers {
+		close(ch)
+	}
+	d.wg.Wait()
+}
+
+func workerFor(msg kafka.Message, n int) int {
+	if len(msg.Key) == 0 {
+		return int(msg.Offset % int64(n))
+	}
+	h := fnv.New32a()
+	h.Write(msg.Key)
+	return int(h.Sum32() % uint32(n))
+}
+
+// subscribeValue returns the value stored for key in the subscribe context.
+func subscribeValue(opts broker.SubscribeOptions, key interface{}) interface{} {
+	if opts.Context == nil {
+		return nil
+	}
+	return opts.Context.Value(key)
+}
@@ -0,0 +1,120 @@
+package segmentio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	kafka "github.com/segmentio/kafka-go"
+	"go-micro.org/v5/broker"
+	"go-micro.org/v5/codec/json"
+	"go-micro.org/v5/logger"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	var marked []int64
+	tr := &offsetTracker{mark: func(offset int64) error {
+		marked = append(marked, offset)
+		return nil
+	}}
+
+	var tms []*trackedMessage
+	for i := int64(0); i < 3; i++ {
+		tms = append(tms, tr.add(kafka.Message{Offset: i}))
+	}
+
+	tr.ack(tms[2])
+	if len(marked) != 0 {
+		t.Fatalf("Expected nothing marked before offset 0 is acked, got %v", marked)
+	}
+	tr.ack(tms[0])
+	tr.ack(tms[1])
+	if len(marked) != 2 || marked[0] != 0 || marked[1] != 2 {
+		t.Fatalf("Expected offsets 0 and 2 marked, got %v", marked)
+	}
+}
+
+func TestCommitter(t *testing.T) {
+	var commits []int64
+	commit := func(offset int64) error {
+		commits = append(commits, offset)
+		return nil
+	}
+
+	// commits every mark by default
+	c := newCommitter(commit, broker.SubscribeOptions{}, logger.DefaultLogger)
+	c.mark(1)
+	c.mark(2)
+	c.stop()
+	if len(commits) != 2 {
+		t.Fatalf("Expected a commit per mark, got %v", commits)
+	}
+
+	commits = nil
+	opts := broker.SubscribeOptions{Context: context.Background()}
+	BatchCommit(2, 0)(&opts)
+	if !manualCommit(opts) {
+		t.Fatal("Expected BatchCommit to track the low watermark")
+	}
+	c = newCommitter(commit, opts, logger.DefaultLogger)
+	c.mark(3)
+	if len(commits) != 0 {
+		t.Fatalf("Expected no commit before the batch is full, got %v", commits)
+	}
+	c.mark(4)
+	c.mark(5)
+	c.stop()
+	if len(commits) != 2 || commits[0] != 4 || commits[1] != 5 {
+		t.Fatalf("Expected offsets 4 and 5 committed, got %v", commits)
+	}
+}
+
+func TestHandleSkips(t *testing.T) {
+	var marked []int64
+	tr := &offsetTracker{mark: func(offset int64) error {
+		marked = append(marked, offset)
+		return nil
+	}}
+
+	h := &cgHandler{
+		brokerOpts: broker.Options{Codec: json.Marshaler{}},
+		subOpts:    broker.SubscribeOptions{AutoAck: true},
+		logger:     logger.DefaultLogger,
+		handler: func(p broker.Event) error {
+			if string(p.Message().Body) == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	handle := func(offset int64, value string) {
+		msg := kafka.Message{Offset: offset, Value: []byte(value)}
+		tm := tr.add(msg)
+		h.handle(msg, func() error { return tr.ack(tm) })
+	}
+
+	// an undecodable message doesn't hold back the partition
+	handle(0, "not json")
+	handle(1, `{"body":"b2s="}`)
+	if len(marked) != 2 || marked[1] != 1 {
+		t.Fatalf("Expected offset 1 marked past the undecodable message, got %v", marked)
+	}
+
+	// nor does a failed one without an error handler
+	handle(2, `{"body":"ZmFpbA=="}`)
+	if len(marked) != 3 || marked[2] != 2 {
+		t.Fatalf("Expected the failed message to be skipped, got %v", marked)
+	}
+
+	// with an error handler, failures are left to it
+	var handled int
+	h.brokerOpts.ErrorHandler = func(p broker.Event) error {
+		handled++
+		return nil
+	}
+	handle(3, `{"body":"ZmFpbA=="}`)
+	handle(4, "not json")
+	if handled != 2 || len(marked) != 3 {
+		t.Fatalf("Expected the error handler to keep the failed message, got %d handled and %v marked", handled, marked)
+	}
+}
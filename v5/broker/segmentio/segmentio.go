@@ -22,6 +22,8 @@ type kBroker struct {
 	writerConfig kafka.WriterConfig
 
 	writers map[string]*kafka.Writer
+	// writers of batches
+	batchWriters map[string]*kafka.Writer
 
 	connected bool
 	sync.RWMutex
@@ -53,8 +55,8 @@ type publication struct {
 	generation *kafka.Generation
 	reader     *kafka.Reader
 	km         kafka.Message
-	offsets    map[string]map[int]int64 // for commit offsets
 	logger     logger.Logger
+	ack        func() error
 }
 
 func init() {
@@ -70,8 +72,7 @@ func (p *publication) Message() *broker.Message {
 }
 
 func (p *publication) Ack() error {
-	p.logger.Logf(logger.TraceLevel, "commit offset %#+v\n", p.offsets)
-	return p.generation.CommitOffsets(p.offsets)
+	return p.ack()
 }
 
 func (p *publication) Error() error {
@@ -150,9 +151,11 @@ func (k *kBroker) Disconnect() error {
 
 	k.Lock()
 	defer k.Unlock()
-	for _, writer := range k.writers {
-		if err := writer.Close(); err != nil {
-			return err
+	for _, writers := range []map[string]*kafka.Writer{k.writers, k.batchWriters} {
+		for _, writer := range writers {
+			if err := writer.Close(); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -183,71 +186,217 @@ func (k *kBroker) Options() broker.Options {
 }
 
 func (k *kBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
-	var cached bool
+	options := broker.PublishOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
 
-	buf, err := k.opts.Codec.Marshal(msg)
+	kmsg, err := k.newMessage(msg, options)
 	if err != nil {
 		return err
 	}
+	return k.write(topic, []kafka.Message{kmsg})
+}
 
-	kmsg := kafka.Message{Value: buf}
+// PublishBatch writes msgs with a single call of a writer sized for the
+// batch, so each partition gets them in one produce request.
+func (k *kBroker) PublishBatch(topic string, msgs []*broker.Message, opts ...broker.PublishOption) []error {
+	options := broker.PublishOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
 
-	k.Lock()
-	writer, ok := k.writers[topic]
-	if !ok {
-		cfg := k.writerConfig
-		cfg.Topic = topic
-		if err = cfg.Validate(); err != nil {
-			k.Unlock()
-			return err
+	var failed bool
+	errs := make([]error, len(msgs))
+	index := make([]int, 0, len(msgs))
+	kmsgs := make([]kafka.Message, 0, len(msgs))
+	for i, msg := range msgs {
+		kmsg, err := k.newMessage(msg, options)
+		if err != nil {
+			errs[i] = err
+			failed = true
+			continue
 		}
-		writer = kafka.NewWriter(cfg)
-		k.writers[topic] = writer
-	} else {
-		cached = true
+		index = append(index, i)
+		kmsgs = append(kmsgs, kmsg)
 	}
-	k.Unlock()
 
-	err = writer.WriteMessages(k.opts.Context, kmsg)
-	if err != nil {
-		switch cached {
-		case false:
-			// non cached case, we can try to wait on some errors, but not timeout
-			if kerr, ok := err.(kafka.Error); ok {
-				if kerr.Temporary() && !kerr.Timeout() {
-					// additional chanse to publish message
-					time.Sleep(200 * time.Millisecond)
-					err = writer.WriteMessages(k.opts.Context, kmsg)
-				}
+	err := k.write(topic, kmsgs)
+	if werrs, ok := err.(kafka.WriteErrors); ok {
+		for j, werr := range werrs {
+			if werr != nil {
+				errs[index[j]] = werr
+				failed = true
 			}
-		case true:
-			// cached case, try to recreate writer and try again after that
-			k.Lock()
-			// close older writer to free memory
-			if err = writer.Close(); err != nil {
-				k.Unlock()
-				return err
-			}
-			delete(k.writers, topic)
-			k.Unlock()
+		}
+	} else if err != nil {
+		for _, i := range index {
+			errs[i] = err
+		}
+		failed = true
+	}
 
-			cfg := k.writerConfig
-			cfg.Topic = topic
-			if err = cfg.Validate(); err != nil {
-				return err
-			}
-			writer := kafka.NewWriter(cfg)
-			if err = writer.WriteMessages(k.opts.Context, kmsg); err == nil {
-				k.Lock()
-				k.writers[topic] = writer
-				k.Unlock()
-			}
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
+// write writes kmsgs to topic. A new writer gets a second chance on
+// temporary errors, a cached one is recreated and the failed messages are
+// written again.
+func (k *kBroker) write(topic string, kmsgs []kafka.Message) error {
+	if len(kmsgs) == 0 {
+		return nil
+	}
+
+	writer, cached, err := k.getWriter(topic, len(kmsgs))
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteMessages(k.opts.Context, kmsgs...)
+	if err == nil {
+		return nil
+	}
+
+	if !cached {
+		// non cached case, we can try to wait on some errors, but not timeout
+		if kerr, ok := err.(kafka.Error); ok && kerr.Temporary() && !kerr.Timeout() {
+			// additional chanse to publish message
+			time.Sleep(200 * time.Millisecond)
+			err = writer.WriteMessages(k.opts.Context, kmsgs...)
 		}
+		return err
 	}
 
+	// cached case, try to recreate writer and try again after that
+	if cerr := k.dropWriter(topic, writer); cerr != nil {
+		return cerr
+	}
+	if writer, _, cerr := k.getWriter(topic, len(kmsgs)); cerr != nil {
+		return cerr
+	} else if err = retryFailed(k.opts.Context, writer, kmsgs, err); err != nil {
+		k.dropWriter(topic, writer)
+	}
 	return err
 }
 
+// retryFailed writes the messages which failed with err once more. The
+// returned write errors are those of kmsgs.
+func retryFailed(ctx context.Context, writer *kafka.Writer, kmsgs []kafka.Message, err error) error {
+	werrs, ok := err.(kafka.WriteErrors)
+	if !ok {
+		return writer.WriteMessages(ctx, kmsgs...)
+	}
+
+	var index []int
+	var failed []kafka.Message
+	for i, werr := range werrs {
+		if werr != nil {
+			index = append(index, i)
+			failed = append(failed, kmsgs[i])
+		}
+	}
+
+	err = writer.WriteMessages(ctx, failed...)
+	if err == nil {
+		return nil
+	}
+	rerrs, ok := err.(kafka.WriteErrors)
+	for j, i := range index {
+		if ok {
+			werrs[i] = rerrs[j]
+		} else {
+			werrs[i] = err
+		}
+	}
+	return werrs
+}
+
+// getWriter returns the writer of topic and whether it was cached. Single
+// messages are written synchronously one at a time, batches get a writer
+// of their own with a batch size of at least n.
+func (k *kBroker) getWriter(topic string, n int) (*kafka.Writer, bool, error) {
+	k.Lock()
+	defer k.Unlock()
+
+	writers := k.writers
+	if n > 1 {
+		writers = k.batchWriters
+	}
+
+	writer, ok := writers[topic]
+	if ok && writer.BatchSize >= n {
+		return writer, true, nil
+	}
+	if ok {
+		// too small for the batch, replace it with a larger one
+		if err := writer.Close(); err != nil {
+			return nil, false, err
+		}
+		delete(writers, topic)
+	}
+
+	cfg := k.writerConfig
+	cfg.Topic = topic
+	if n > 1 {
+		cfg.BatchSize = batchSize(n)
+		cfg.BatchTimeout = BatchTimeout
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, false, err
+	}
+	writer = kafka.NewWriter(cfg)
+	writers[topic] = writer
+	return writer, false, nil
+}
+
+// dropWriter closes writer and removes it from the cache.
+func (k *kBroker) dropWriter(topic string, writer *kafka.Writer) error {
+	k.Lock()
+	defer k.Unlock()
+
+	for _, writers := range []map[string]*kafka.Writer{k.writers, k.batchWriters} {
+		if writers[topic] == writer {
+			delete(writers, topic)
+		}
+	}
+	// close older writer to free memory
+	return writer.Close()
+}
+
+// batchSize rounds n up to a power of two, so a few writers serve batches
+// of any size.
+func batchSize(n int) int {
+	size := 1
+	for size < n {
+		size *= 2
+	}
+	return size
+}
+
+func (k *kBroker) newMessage(msg *broker.Message, options broker.PublishOptions) (kafka.Message, error) {
+	buf, err := k.opts.Codec.Marshal(msg)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	kmsg := kafka.Message{Value: buf, Partition: -1}
+	if options.Context != nil {
+		if key, ok := options.Context.Value(publishKey{}).([]byte); ok {
+			kmsg.Key = key
+		}
+		if partition, ok := options.Context.Value(publishPartitionKey{}).(int); ok {
+			kmsg.Partition = partition
+		}
+	}
+	for hk, hv := range msg.Header {
+		kmsg.Headers = append(kmsg.Headers, kafka.Header{Key: hk, Value: []byte(hv)})
+	}
+	return kmsg, nil
+}
+
 func (k *kBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
 	opt := broker.SubscribeOptions{
 		AutoAck: true,
@@ -360,10 +509,34 @@ type cgHandler struct {
 }
 
 func (h *cgHandler) run(ctx context.Context) {
-	offsets := make(map[string]map[int]int64)
-	offsets[h.reader.Config().Topic] = make(map[int]int64)
+	cfg := h.reader.Config()
+	commit := func(offset int64) error {
+		offsets := map[string]map[int]int64{cfg.Topic: {cfg.Partition: offset}}
+		h.logger.Logf(logger.TraceLevel, "commit offset %#+v\n", offsets)
+		return h.generation.CommitOffsets(offsets)
+	}
+	c := newCommitter(commit, h.subOpts, h.logger)
+	defer c.stop()
 
 	defer h.reader.Close()
+
+	dispatch := func(msg kafka.Message) {
+		h.handle(msg, func() error { return c.mark(msg.Offset) })
+	}
+	n, _ := subscribeValue(h.subOpts, orderedConcurrencyKey{}).(int)
+	if manualCommit(h.subOpts) || n > 1 {
+		t := &offsetTracker{mark: c.mark}
+		dispatch = func(msg kafka.Message) {
+			tm := t.add(msg)
+			h.handle(msg, func() error { return t.ack(tm) })
+		}
+		if n > 1 {
+			d := newOrderedDispatcher(h, t, n)
+			defer d.close()
+			dispatch = d.dispatch
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -379,39 +552,68 @@ func (h *cgHandler) run(ctx context.Context) {
 				h.logger.Log(logger.TraceLevel, "[segmentio] generation ended")
 				return
 			case nil:
-				var m broker.Message
-				eh := h.brokerOpts.ErrorHandler
-				offsets[msg.Topic][msg.Partition] = msg.Offset
-				p := &publication{topic: msg.Topic, generation: h.generation, m: &m, offsets: offsets, logger: h.logger}
-
-				if err := h.brokerOpts.Codec.Unmarshal(msg.Value, &m); err != nil {
-					p.err = err
-					p.m.Body = msg.Value
-					if eh != nil {
-						eh(p)
-					} else {
-						h.logger.Logf(logger.ErrorLevel, "[segmentio]: failed to unmarshal: %v", err)
-					}
-					continue
-				}
-				err = h.handler(p)
-				if err == nil && h.subOpts.AutoAck {
-					if err = p.Ack(); err != nil {
-						h.logger.Logf(logger.ErrorLevel, "[segmentio]: unable to commit msg: %v", err)
-					}
-				} else if err != nil {
-					p.err = err
-					if eh != nil {
-						eh(p)
-					} else {
-						h.logger.Logf(logger.ErrorLevel, "[segmentio]: subscriber error: %v", err)
-					}
-				}
+				dispatch(msg)
 			}
 		}
 	}
 }
 
+// handle decodes a single message and passes it to the subscriber handler.
+// ack is called when the message is acked, either by the handler, after it
+// returned or automatically. Messages which fail to decode, or whose handler
+// fails without an ErrorHandler, are skipped by acking them, so they don't
+// hold back the offsets of their partition.
+func (h *cgHandler) handle(msg kafka.Message, ack func() error) {
+	var m broker.Message
+	var once sync.Once
+	var ackErr error
+	eh := h.brokerOpts.ErrorHandler
+	p := &publication{topic: msg.Topic, generation: h.generation, m: &m, km: msg, logger: h.logger, ack: func() error {
+		once.Do(func() { ackErr = ack() })
+		return ackErr
+	}}
+	skip := func() {
+		if err := p.Ack(); err != nil {
+			h.logger.Logf(logger.ErrorLevel, "[segmentio]: unable to commit msg: %v", err)
+		}
+	}
+
+	if err := h.brokerOpts.Codec.Unmarshal(msg.Value, &m); err != nil {
+		p.err = err
+		p.m.Body = msg.Value
+		if eh != nil {
+			eh(p)
+		} else {
+			h.logger.Logf(logger.ErrorLevel, "[segmentio]: failed to unmarshal: %v", err)
+		}
+		// it never will decode, skip it
+		skip()
+		return
+	}
+
+	if len(msg.Headers) > 0 && m.Header == nil {
+		m.Header = make(map[string]string, len(msg.Headers))
+	}
+	for _, header := range msg.Headers {
+		m.Header[header.Key] = string(header.Value)
+	}
+
+	err := h.handler(p)
+	if err == nil && h.subOpts.AutoAck {
+		if err = p.Ack(); err != nil {
+			h.logger.Logf(logger.ErrorLevel, "[segmentio]: unable to commit msg: %v", err)
+		}
+	} else if err != nil {
+		p.err = err
+		if eh != nil {
+			eh(p)
+		} else {
+			h.logger.Logf(logger.ErrorLevel, "[segmentio]: subscriber error: %v", err)
+			skip()
+		}
+	}
+}
+
 func (sub *subscriber) createGroup(ctx context.Context) {
 	sub.RLock()
 	cgcfg := sub.cgcfg
@@ -481,11 +683,14 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 		writerConfig.Brokers = cAddrs
 	}
 	writerConfig.BatchSize = 1
+	// honour partitions set with PublishPartition
+	writerConfig.Balancer = newBalancer(writerConfig.Balancer)
 
 	return &kBroker{
 		readerConfig: readerConfig,
 		writerConfig: writerConfig,
 		writers:      make(map[string]*kafka.Writer),
+		batchWriters: make(map[string]*kafka.Writer),
 		addrs:        cAddrs,
 		opts:         options,
 	}
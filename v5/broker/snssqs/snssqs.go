@@ -27,6 +27,7 @@ const (
 	defaultMaxMessages             = 1
 	defaultVisibilityTimeout       = 3
 	defaultWaitSeconds             = 10
+	maxBatchConcurrency            = 10
 	defaultValidateOnPublish       = false
 	defaultValidateHeaderOnPublish = false
 )
@@ -288,6 +289,42 @@ func (b *awsServices) Publish(topic string, msg *broker.Message, opts ...broker.
 	return nil
 }
 
+// PublishBatch publishes msgs via SNS with up to maxBatchConcurrency
+// requests in flight, as the SNS batch API is not available in the
+// aws-sdk-go version used.
+func (b *awsServices) PublishBatch(topic string, msgs []*broker.Message, opts ...broker.PublishOption) []error {
+	var (
+		wg     sync.WaitGroup
+		mtx    sync.Mutex
+		failed bool
+	)
+
+	errs := make([]error, len(msgs))
+	sem := make(chan struct{}, maxBatchConcurrency)
+	for i, msg := range msgs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, msg *broker.Message) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := b.Publish(topic, msg, opts...); err != nil {
+				mtx.Lock()
+				errs[i] = err
+				failed = true
+				mtx.Unlock()
+			}
+		}(i, msg)
+	}
+	wg.Wait()
+
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
 // Subscribe subscribes to an SQS queue, starting a goroutine to poll for messages.
 func (b *awsServices) Subscribe(queueName string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
 	queueURL, err := b.urlFromQueueName(queueName)
@@ -4,6 +4,7 @@ go 1.19
 
 require (
 	github.com/aws/aws-sdk-go v1.38.69
+	github.com/open-micro/plugins/v5/broker/delay v0.0.0-00010101000000-000000000000
 	go-micro.org/v5 v5.0.1
 )
 
@@ -50,3 +51,5 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/broker/delay => ../delay
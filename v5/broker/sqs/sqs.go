@@ -5,12 +5,15 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/open-micro/plugins/v5/broker/delay"
 	"go-micro.org/v5/broker"
 	log "go-micro.org/v5/logger"
 	"go-micro.org/v5/util/cmd"
@@ -20,6 +23,8 @@ const (
 	defaultMaxMessages       = 1
 	defaultVisibilityTimeout = 3
 	defaultWaitSeconds       = 10
+	maxDelay                 = 15 * time.Minute
+	maxBatchSize             = 10
 )
 
 // Amazon SQS Broker.
@@ -224,6 +229,11 @@ func (b *sqsBroker) Init(opts ...broker.Option) error {
 
 // Publish publishes a message via SQS.
 func (b *sqsBroker) Publish(queueName string, msg *broker.Message, opts ...broker.PublishOption) error {
+	options := broker.PublishOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
 	queueURL, err := b.urlFromQueueName(queueName)
 	if err != nil {
 		return err
@@ -239,6 +249,10 @@ func (b *sqsBroker) Publish(queueName string, msg *broker.Message, opts ...broke
 	input.MessageDeduplicationId = b.generateDedupID(msg)
 	input.MessageGroupId = b.generateGroupID(msg)
 
+	if input.DelaySeconds, err = delaySeconds(queueURL, options); err != nil {
+		return err
+	}
+
 	log.Infof("Publishing SQS message, %d bytes", len(msg.Body))
 	_, err = b.svc.SendMessage(input)
 
@@ -250,6 +264,100 @@ func (b *sqsBroker) Publish(queueName string, msg *broker.Message, opts ...broke
 	return nil
 }
 
+// PublishBatch publishes msgs via SQS SendMessageBatch, in chunks of the
+// maximum of 10 messages per request.
+func (b *sqsBroker) PublishBatch(queueName string, msgs []*broker.Message, opts ...broker.PublishOption) []error {
+	options := broker.PublishOptions{}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	errs := make([]error, len(msgs))
+	fail := func(err error) []error {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	queueURL, err := b.urlFromQueueName(queueName)
+	if err != nil {
+		return fail(err)
+	}
+
+	delaySecs, err := delaySeconds(queueURL, options)
+	if err != nil {
+		return fail(err)
+	}
+
+	var failed bool
+	for start := 0; start < len(msgs); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+
+		input := &sqs.SendMessageBatchInput{QueueUrl: &queueURL}
+		for i := start; i < end; i++ {
+			messageBody := base64.StdEncoding.EncodeToString(msgs[i].Body)
+			input.Entries = append(input.Entries, &sqs.SendMessageBatchRequestEntry{
+				Id:                     aws.String(strconv.Itoa(i)),
+				MessageBody:            &messageBody,
+				MessageAttributes:      copyMessageHeader(msgs[i]),
+				MessageDeduplicationId: b.generateDedupID(msgs[i]),
+				MessageGroupId:         b.generateGroupID(msgs[i]),
+				DelaySeconds:           delaySecs,
+			})
+		}
+
+		log.Infof("Publishing batch of %d SQS messages", len(input.Entries))
+		output, err := b.svc.SendMessageBatch(input)
+		if err != nil {
+			for i := start; i < end; i++ {
+				errs[i] = err
+			}
+			failed = true
+			continue
+		}
+
+		for _, entry := range output.Failed {
+			i, err := strconv.Atoi(aws.StringValue(entry.Id))
+			if err != nil || i < start || i >= end {
+				continue
+			}
+			errs[i] = fmt.Errorf("%s: %s", aws.StringValue(entry.Code), aws.StringValue(entry.Message))
+			failed = true
+		}
+	}
+
+	if !failed {
+		return nil
+	}
+	return errs
+}
+
+// delaySeconds returns the SQS DelaySeconds of a message published with
+// delay.Delay or delay.At. FIFO queues only support a delay on the queue
+// itself, so delayed messages are rejected for them.
+func delaySeconds(queueURL string, options broker.PublishOptions) (*int64, error) {
+	at, ok := delay.FromContext(options.Context)
+	if !ok {
+		return nil, nil
+	}
+	d := time.Until(at)
+	if d > maxDelay {
+		return nil, fmt.Errorf("sqs: delay of %v exceeds the maximum of %v", d, maxDelay)
+	}
+	secs := int64(d.Round(time.Second) / time.Second)
+	if secs <= 0 {
+		return nil, nil
+	}
+	if strings.HasSuffix(queueURL, ".fifo") {
+		return nil, fmt.Errorf("sqs: FIFO queue %s does not support per message delays", queueURL)
+	}
+	return aws.Int64(secs), nil
+}
+
 // Subscribe subscribes to an SQS queue, starting a goroutine to poll for messages.
 func (b *sqsBroker) Subscribe(queueName string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
 	queueURL, err := b.urlFromQueueName(queueName)
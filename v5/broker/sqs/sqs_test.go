@@ -0,0 +1,45 @@
+package sqs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-micro/plugins/v5/broker/delay"
+	"go-micro.org/v5/broker"
+)
+
+func TestDelaySeconds(t *testing.T) {
+	const queueURL = "https://sqs.eu-west-1.amazonaws.com/123456789012/queue"
+
+	publishOptions := func(opts ...broker.PublishOption) broker.PublishOptions {
+		var options broker.PublishOptions
+		for _, o := range opts {
+			o(&options)
+		}
+		return options
+	}
+
+	secs, err := delaySeconds(queueURL, publishOptions())
+	if err != nil || secs != nil {
+		t.Fatalf("Expected no delay without the option, got %v: %v", secs, err)
+	}
+
+	secs, err = delaySeconds(queueURL, publishOptions(delay.Delay(time.Minute)))
+	if err != nil || secs == nil || *secs != 60 {
+		t.Fatalf("Expected a delay of 60 seconds, got %v: %v", secs, err)
+	}
+
+	if _, err := delaySeconds(queueURL, publishOptions(delay.Delay(time.Hour))); err == nil {
+		t.Fatal("Expected an error for a delay over 15 minutes")
+	}
+
+	if _, err := delaySeconds(queueURL+".fifo", publishOptions(delay.Delay(time.Minute))); err == nil {
+		t.Fatal("Expected an error for a delayed message to a FIFO queue")
+	}
+
+	// a due message is sent straight away, also to FIFO queues
+	secs, err = delaySeconds(queueURL+".fifo", publishOptions(delay.At(time.Now().Add(-time.Second))))
+	if err != nil || secs != nil {
+		t.Fatalf("Expected no delay for a due message, got %v: %v", secs, err)
+	}
+}
@@ -0,0 +1,363 @@
+// Package lru provides a size bounded in-process cache with per entry expiry
+package lru
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-micro.org/v5/cache"
+	"go-micro.org/v5/util/cmd"
+)
+
+var (
+	DefaultShards          = 16
+	DefaultCleanupInterval = time.Minute
+
+	// ErrTooLarge is returned by Put for an entry larger than the share of
+	// MaxBytes of a shard.
+	ErrTooLarge = errors.New("entry is larger than the bytes of a cache shard")
+)
+
+// minShardBytes is the smallest share of MaxBytes of a shard, small byte
+// bounds get fewer shards so entries up to it fit.
+const minShardBytes = 64 << 10
+
+func init() {
+	cmd.DefaultCaches["lru"] = NewCache
+}
+
+// NewCache returns a new LRU cache, which implements Cache.
+func NewCache(opts ...cache.Option) cache.Cache {
+	options := cache.NewOptions(opts...)
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	c := &lru{
+		opts:    options,
+		sizer:   defaultSize,
+		stop:    make(chan struct{}),
+		cleanup: DefaultCleanupInterval,
+	}
+
+	maxEntries, _ := options.Context.Value(maxEntriesKey{}).(int)
+	maxBytes, _ := options.Context.Value(maxBytesKey{}).(int64)
+	if fn, ok := options.Context.Value(sizerKey{}).(SizeFunc); ok && fn != nil {
+		c.sizer = fn
+	}
+	if fn, ok := options.Context.Value(onEvictKey{}).(EvictFunc); ok {
+		c.onEvict = fn
+	}
+	if d, ok := options.Context.Value(cleanupIntervalKey{}).(time.Duration); ok {
+		c.cleanup = d
+	}
+	n, ok := options.Context.Value(shardsKey{}).(int)
+	if !ok || n <= 0 {
+		n = DefaultShards
+	}
+
+	// a power of two so keys are mapped with a mask, and no more shards
+	// than entries or than minShardBytes fit in so small bounds hold
+	shards := 1
+	for shards < n {
+		shards <<= 1
+	}
+	for shards > 1 && maxEntries > 0 && shards > maxEntries {
+		shards >>= 1
+	}
+	for shards > 1 && maxBytes > 0 && int64(shards)*minShardBytes > maxBytes {
+		shards >>= 1
+	}
+
+	c.mask = uint64(shards - 1)
+	c.shards = make([]*shard, shards)
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			items:      make(map[string]*list.Element),
+			ll:         list.New(),
+			maxEntries: ceilDiv(int64(maxEntries), int64(shards)),
+			maxBytes:   ceilDiv(maxBytes, int64(shards)),
+		}
+	}
+
+	for k, it := range options.Items {
+		var expires time.Time
+		if it.Expiration > 0 {
+			expires = time.Unix(0, it.Expiration)
+		}
+		c.put(k, it.Value, expires)
+	}
+
+	// the janitor holds the inner cache only, so the returned one can be
+	// collected and stop it
+	w := &lruCache{c}
+	if c.cleanup > 0 {
+		go c.janitor()
+		runtime.SetFinalizer(w, func(w *lruCache) {
+			close(w.stop)
+		})
+	}
+	return w
+}
+
+// Cache is the cache returned by NewCache.
+type Cache interface {
+	cache.Cache
+	// Stats returns the counters of the cache.
+	Stats() Stats
+}
+
+// Stats are the counters of the cache.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Deletions   uint64
+	Entries     int
+	Bytes       int64
+}
+
+type lruCache struct {
+	*lru
+}
+
+type lru struct {
+	opts    cache.Options
+	shards  []*shard
+	mask    uint64
+	sizer   SizeFunc
+	onEvict EvictFunc
+	cleanup time.Duration
+	stop    chan struct{}
+}
+
+type shard struct {
+	sync.Mutex
+	items map[string]*list.Element
+	// most recently used at the front
+	ll    *list.List
+	bytes int64
+
+	maxEntries int64
+	maxBytes   int64
+
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+	deletions   uint64
+}
+
+type entry struct {
+	key     string
+	val     interface{}
+	expires time.Time
+	size    int64
+	reason  Reason
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+func ceilDiv(a, b int64) int64 {
+	if a <= 0 {
+		return 0
+	}
+	return (a + b - 1) / b
+}
+
+// hash is fnv-1a, inlined to avoid allocating a hasher per call.
+func hash(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+func (c *lru) shard(key string) *shard {
+	return c.shards[hash(key)&c.mask]
+}
+
+// evicted calls the eviction callback without locks held.
+func (c *lru) evicted(entries []*entry) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, e := range entries {
+		c.onEvict(e.key, e.val, e.reason)
+	}
+}
+
+// remove deletes the element from the shard, s must be locked.
+func (s *shard) remove(el *list.Element) *entry {
+	e := el.Value.(*entry)
+	s.ll.Remove(el)
+	delete(s.items, e.key)
+	s.bytes -= e.size
+	return e
+}
+
+func (c *lru) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
+	s := c.shard(key)
+
+	s.Lock()
+	el, ok := s.items[key]
+	if !ok {
+		s.Unlock()
+		atomic.AddUint64(&s.misses, 1)
+		return nil, time.Time{}, cache.ErrKeyNotFound
+	}
+
+	e := el.Value.(*entry)
+	if e.expired(time.Now()) {
+		s.remove(el)
+		s.Unlock()
+		atomic.AddUint64(&s.misses, 1)
+		atomic.AddUint64(&s.expirations, 1)
+		e.reason = Expired
+		c.evicted([]*entry{e})
+		return nil, time.Time{}, cache.ErrItemExpired
+	}
+
+	s.ll.MoveToFront(el)
+	val, expires := e.val, e.expires
+	s.Unlock()
+
+	atomic.AddUint64(&s.hits, 1)
+	return val, expires, nil
+}
+
+func (c *lru) Put(ctx context.Context, key string, val interface{}, d time.Duration) error {
+	if d == 0 {
+		d = c.opts.Expiration
+	}
+	var expires time.Time
+	if d > 0 {
+		expires = time.Now().Add(d)
+	}
+	return c.put(key, val, expires)
+}
+
+func (c *lru) put(key string, val interface{}, expires time.Time) error {
+	s := c.shard(key)
+	size := c.sizer(key, val)
+	if s.maxBytes > 0 && size > s.maxBytes {
+		return ErrTooLarge
+	}
+
+	s.Lock()
+	if el, ok := s.items[key]; ok {
+		e := el.Value.(*entry)
+		s.bytes += size - e.size
+		e.val, e.expires, e.size = val, expires, size
+		s.ll.MoveToFront(el)
+	} else {
+		s.items[key] = s.ll.PushFront(&entry{key: key, val: val, expires: expires, size: size})
+		s.bytes += size
+	}
+
+	// evict the least recently used entries until within bounds
+	var evicted []*entry
+	now := time.Now()
+	for (s.maxEntries > 0 && int64(s.ll.Len()) > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes) {
+		e := s.remove(s.ll.Back())
+		if e.expired(now) {
+			e.reason = Expired
+			atomic.AddUint64(&s.expirations, 1)
+		} else {
+			e.reason = Capacity
+			atomic.AddUint64(&s.evictions, 1)
+		}
+		evicted = append(evicted, e)
+	}
+	s.Unlock()
+
+	c.evicted(evicted)
+	return nil
+}
+
+func (c *lru) Delete(ctx context.Context, key string) error {
+	s := c.shard(key)
+
+	s.Lock()
+	el, ok := s.items[key]
+	if !ok {
+		s.Unlock()
+		return nil
+	}
+	e := s.remove(el)
+	s.Unlock()
+
+	atomic.AddUint64(&s.deletions, 1)
+	e.reason = Deleted
+	c.evicted([]*entry{e})
+	return nil
+}
+
+// Stats returns the counters of the cache, summed over the shards.
+func (c *lru) Stats() Stats {
+	var st Stats
+	for _, s := range c.shards {
+		st.Hits += atomic.LoadUint64(&s.hits)
+		st.Misses += atomic.LoadUint64(&s.misses)
+		st.Evictions += atomic.LoadUint64(&s.evictions)
+		st.Expirations += atomic.LoadUint64(&s.expirations)
+		st.Deletions += atomic.LoadUint64(&s.deletions)
+
+		s.Lock()
+		st.Entries += s.ll.Len()
+		st.Bytes += s.bytes
+		s.Unlock()
+	}
+	return st
+}
+
+// janitor removes expired entries in the background.
+func (c *lru) janitor() {
+	t := time.NewTicker(c.cleanup)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-t.C:
+			c.deleteExpired()
+		}
+	}
+}
+
+func (c *lru) deleteExpired() {
+	now := time.Now()
+	for _, s := range c.shards {
+		var expired []*entry
+
+		s.Lock()
+		for el := s.ll.Back(); el != nil; {
+			prev := el.Prev()
+			if el.Value.(*entry).expired(now) {
+				e := s.remove(el)
+				e.reason = Expired
+				expired = append(expired, e)
+			}
+			el = prev
+		}
+		s.Unlock()
+
+		atomic.AddUint64(&s.expirations, uint64(len(expired)))
+		c.evicted(expired)
+	}
+}
+
+func (c *lru) String() string {
+	return "lru"
+}
@@ -0,0 +1,190 @@
+package lru
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/cache"
+)
+
+var ctx = context.TODO()
+
+func TestCache(t *testing.T) {
+	c := NewCache()
+
+	if _, _, err := c.Get(ctx, "foo"); err != cache.ErrKeyNotFound {
+		t.Fatalf("expected %v, got %v", cache.ErrKeyNotFound, err)
+	}
+	if err := c.Put(ctx, "foo", "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	val, expires, err := c.Get(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "bar" || !expires.IsZero() {
+		t.Fatalf("unexpected value %v expiring %v", val, expires)
+	}
+	if err := c.Delete(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Get(ctx, "foo"); err != cache.ErrKeyNotFound {
+		t.Fatalf("expected %v, got %v", cache.ErrKeyNotFound, err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	var mu sync.Mutex
+	var expired []string
+
+	c := NewCache(
+		CleanupInterval(10*time.Millisecond),
+		OnEvict(func(key string, val interface{}, reason Reason) {
+			mu.Lock()
+			defer mu.Unlock()
+			if reason == Expired {
+				expired = append(expired, key)
+			}
+		}),
+	)
+
+	c.Put(ctx, "lazy", 1, 20*time.Millisecond)
+	c.Put(ctx, "forever", 2, 0)
+	time.Sleep(30 * time.Millisecond)
+
+	// removed on access, or by the janitor first
+	if _, _, err := c.Get(ctx, "lazy"); err == nil {
+		t.Fatal("expected expired entry to be gone")
+	}
+	if _, _, err := c.Get(ctx, "forever"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put(ctx, "background", 3, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(expired) != 2 {
+		t.Fatalf("expected 2 expired entries, got %v", expired)
+	}
+	if st := c.(Cache).Stats(); st.Entries != 1 || st.Expirations != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	var deleted []string
+	c := NewCache(OnEvict(func(key string, val interface{}, reason Reason) {
+		if reason != Deleted {
+			t.Errorf("unexpected reason %v", reason)
+		}
+		deleted = append(deleted, key)
+	}))
+
+	c.Put(ctx, "a", 1, 0)
+	c.Delete(ctx, "a")
+	// deleting a missing key is not counted
+	c.Delete(ctx, "b")
+
+	if len(deleted) != 1 || deleted[0] != "a" {
+		t.Fatalf("expected a to be deleted, got %v", deleted)
+	}
+	if st := c.(Cache).Stats(); st.Deletions != 1 || st.Entries != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	var evicted []string
+	c := NewCache(MaxEntries(2), Shards(1), OnEvict(func(key string, val interface{}, reason Reason) {
+		if reason != Capacity {
+			t.Errorf("unexpected reason %v", reason)
+		}
+		evicted = append(evicted, key)
+	}))
+
+	c.Put(ctx, "a", 1, 0)
+	c.Put(ctx, "b", 2, 0)
+	// a is now the most recently used
+	c.Get(ctx, "a")
+	c.Put(ctx, "c", 3, 0)
+
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("expected b to be evicted, got %v", evicted)
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, _, err := c.Get(ctx, k); err != nil {
+			t.Fatalf("expected %s to be cached: %v", k, err)
+		}
+	}
+
+	st := c.(Cache).Stats()
+	if st.Hits != 3 || st.Misses != 0 || st.Evictions != 1 || st.Entries != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	c := NewCache(MaxBytes(10), Shards(1))
+
+	if err := c.Put(ctx, "a", "12345678901", 0); err != ErrTooLarge {
+		t.Fatalf("expected %v, got %v", ErrTooLarge, err)
+	}
+	c.Put(ctx, "a", "1234", 0)
+	c.Put(ctx, "b", "1234", 0)
+	if st := c.(Cache).Stats(); st.Bytes != 10 || st.Entries != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	c.Put(ctx, "c", "1", 0)
+	if _, _, err := c.Get(ctx, "a"); err != cache.ErrKeyNotFound {
+		t.Fatal("expected a to be evicted")
+	}
+	if st := c.(Cache).Stats(); st.Bytes != 7 || st.Entries != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestMaxBytesShards(t *testing.T) {
+	// a small byte bound is not split between the default shards
+	c := NewCache(MaxBytes(100))
+	if n := len(c.(*lruCache).shards); n != 1 {
+		t.Fatalf("expected 1 shard, got %d", n)
+	}
+	if err := c.Put(ctx, "a", strings.Repeat("x", 90), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	c = NewCache(MaxBytes(4 * minShardBytes))
+	if n := len(c.(*lruCache).shards); n != 4 {
+		t.Fatalf("expected 4 shards, got %d", n)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	c := NewCache(MaxEntries(100))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := fmt.Sprintf("%d-%d", i, j%200)
+				c.Put(ctx, key, j, 0)
+				c.Get(ctx, key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// bounds are split between the shards so may round up
+	if st := c.(Cache).Stats(); st.Entries > 100+len(c.(*lruCache).shards) {
+		t.Fatalf("cache grew to %d entries", st.Entries)
+	}
+}
@@ -0,0 +1,102 @@
+package lru
+
+import (
+	"context"
+	"time"
+
+	"go-micro.org/v5/cache"
+)
+
+type maxEntriesKey struct{}
+type maxBytesKey struct{}
+type sizerKey struct{}
+type onEvictKey struct{}
+type shardsKey struct{}
+type cleanupIntervalKey struct{}
+
+// Reason an entry was evicted.
+type Reason int
+
+const (
+	// Capacity evictions make room for new entries.
+	Capacity Reason = iota
+	// Expired entries outlived their TTL.
+	Expired
+	// Deleted entries were removed with Delete.
+	Deleted
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Capacity:
+		return "capacity"
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// EvictFunc is called with entries evicted from the cache.
+type EvictFunc func(key string, val interface{}, reason Reason)
+
+// SizeFunc returns the size of an entry counted against MaxBytes.
+type SizeFunc func(key string, val interface{}) int64
+
+// MaxEntries bounds the number of entries, 0 is unbounded.
+func MaxEntries(n int) cache.Option {
+	return setOption(maxEntriesKey{}, n)
+}
+
+// MaxBytes bounds the total size of the entries as returned by the Sizer,
+// 0 is unbounded. It is split between the shards, Put returns ErrTooLarge
+// for an entry larger than the share of a shard.
+func MaxBytes(n int64) cache.Option {
+	return setOption(maxBytesKey{}, n)
+}
+
+// Sizer sets the function sizing entries. By default the size of an entry is
+// the length of its key, plus the length of its value for []byte and string
+// values.
+func Sizer(fn SizeFunc) cache.Option {
+	return setOption(sizerKey{}, fn)
+}
+
+// OnEvict sets a function called with evicted, expired and deleted
+// entries. It is called without locks held.
+func OnEvict(fn EvictFunc) cache.Option {
+	return setOption(onEvictKey{}, fn)
+}
+
+// Shards sets the number of independently locked shards, rounded up to a
+// power of two. Bounds are split evenly between the shards.
+func Shards(n int) cache.Option {
+	return setOption(shardsKey{}, n)
+}
+
+// CleanupInterval sets how often expired entries are removed in the
+// background, 0 only removes them lazily on access.
+func CleanupInterval(d time.Duration) cache.Option {
+	return setOption(cleanupIntervalKey{}, d)
+}
+
+func setOption(k, v interface{}) cache.Option {
+	return func(o *cache.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+func defaultSize(key string, val interface{}) int64 {
+	n := int64(len(key))
+	switch v := val.(type) {
+	case []byte:
+		n += int64(len(v))
+	case string:
+		n += int64(len(v))
+	}
+	return n
+}
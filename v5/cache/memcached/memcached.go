@@ -0,0 +1,184 @@
+// Package memcached provides a memcached cache, spreading keys over the
+// servers by consistent hashing
+package memcached
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	mc "github.com/bradfitz/gomemcache/memcache"
+	"go-micro.org/v5/cache"
+	"go-micro.org/v5/codec"
+	log "go-micro.org/v5/logger"
+	"go-micro.org/v5/util/cmd"
+)
+
+// memcached treats expirations over 30 days as unix timestamps.
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+func init() {
+	cmd.DefaultCaches["memcached"] = NewCache
+}
+
+// Cache is implemented by the caches returned by NewCache.
+//
+// Values other than []byte and string are encoded with the codec set by
+// WithCodec, json by default. Get returns the encoded value, Read decodes it.
+type Cache interface {
+	cache.Cache
+	// Read decodes the value of key into v, a *[]byte or *string receives
+	// the value as stored.
+	Read(ctx context.Context, key string, v interface{}) (time.Time, error)
+	// GetMulti returns the encoded values of the keys found, in one round
+	// trip per server.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+}
+
+// NewCache returns a new memcached cache.
+func NewCache(opts ...cache.Option) cache.Cache {
+	options := cache.NewOptions(opts...)
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	servers, _ := options.Context.Value(serversKey{}).([]string)
+	if len(servers) == 0 && len(options.Address) > 0 {
+		servers = strings.Split(options.Address, ",")
+	}
+	if len(servers) == 0 {
+		servers = []string{"127.0.0.1:11211"}
+	}
+
+	r, err := newRing(servers...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := &memcachedCache{
+		opts:   options,
+		ring:   r,
+		client: mc.NewFromSelector(r),
+		codec:  DefaultCodec,
+	}
+	if m, ok := options.Context.Value(codecKey{}).(codec.Marshaler); ok {
+		c.codec = m
+	}
+	if p, ok := options.Context.Value(prefixKey{}).(string); ok {
+		c.prefix = p
+	}
+	return c
+}
+
+type memcachedCache struct {
+	opts   cache.Options
+	ring   *ring
+	client *mc.Client
+	codec  codec.Marshaler
+	prefix string
+}
+
+func (c *memcachedCache) key(key string) string {
+	return c.prefix + key
+}
+
+func (c *memcachedCache) encode(val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return c.codec.Marshal(val)
+}
+
+func (c *memcachedCache) decode(b []byte, v interface{}) error {
+	switch p := v.(type) {
+	case *[]byte:
+		*p = b
+		return nil
+	case *string:
+		*p = string(b)
+		return nil
+	}
+	return c.codec.Unmarshal(b, v)
+}
+
+// expires returns the expiry kept in the flags of an item, memcached doesn't
+// return it. Items written by other clients may use the flags otherwise, the
+// prefix has to keep their keys apart.
+func expires(it *mc.Item) time.Time {
+	if it.Flags == 0 {
+		return time.Unix(1<<63-1, 0)
+	}
+	return time.Unix(int64(it.Flags), 0)
+}
+
+func (c *memcachedCache) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
+	it, err := c.client.Get(c.key(key))
+	if err == mc.ErrCacheMiss {
+		return nil, time.Time{}, cache.ErrKeyNotFound
+	} else if err != nil {
+		return nil, time.Time{}, err
+	}
+	return it.Value, expires(it), nil
+}
+
+func (c *memcachedCache) Read(ctx context.Context, key string, v interface{}) (time.Time, error) {
+	val, exp, err := c.Get(ctx, key)
+	if err != nil {
+		return exp, err
+	}
+	return exp, c.decode(val.([]byte), v)
+}
+
+func (c *memcachedCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = c.key(k)
+	}
+
+	items, err := c.client.GetMulti(prefixed)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := make(map[string][]byte, len(items))
+	for k, it := range items {
+		vals[strings.TrimPrefix(k, c.prefix)] = it.Value
+	}
+	return vals, nil
+}
+
+func (c *memcachedCache) Put(ctx context.Context, key string, val interface{}, d time.Duration) error {
+	b, err := c.encode(val)
+	if err != nil {
+		return err
+	}
+
+	it := &mc.Item{Key: c.key(key), Value: b}
+	if d > 0 {
+		// memcached expires in whole seconds, round up so short durations
+		// don't become no expiry
+		exp := time.Now().Add(d).Add(time.Second - 1).Truncate(time.Second)
+		it.Flags = uint32(exp.Unix())
+		if d > maxRelativeExpiration {
+			it.Expiration = int32(exp.Unix())
+		} else {
+			it.Expiration = int32((d + time.Second - 1) / time.Second)
+		}
+	}
+	return c.client.Set(it)
+}
+
+func (c *memcachedCache) Delete(ctx context.Context, key string) error {
+	err := c.client.Delete(c.key(key))
+	if err == mc.ErrCacheMiss {
+		return nil
+	}
+	return err
+}
+
+func (c *memcachedCache) String() string {
+	return "memcached"
+}
@@ -0,0 +1,56 @@
+package memcached
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/cache"
+)
+
+func TestCache(t *testing.T) {
+	if len(os.Getenv("LOCAL")) == 0 {
+		t.Skip()
+	}
+
+	ctx := context.TODO()
+	c := NewCache(WithPrefix("test:")).(Cache)
+
+	if _, _, err := c.Get(ctx, "missing"); err != cache.ErrKeyNotFound {
+		t.Fatalf("expected %v, got %v", cache.ErrKeyNotFound, err)
+	}
+
+	type value struct {
+		Name string
+	}
+	if err := c.Put(ctx, "foo", value{"bar"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var v value
+	exp, err := c.Read(ctx, "foo", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "bar" {
+		t.Fatalf("unexpected value %+v", v)
+	}
+	if d := time.Until(exp); d < 58*time.Second || d > 61*time.Second {
+		t.Fatalf("unexpected expiry %v", exp)
+	}
+
+	vals, err := c.GetMulti(ctx, "foo", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || string(vals["foo"]) != `{"Name":"bar"}` {
+		t.Fatalf("unexpected values %v", vals)
+	}
+
+	if err := c.Delete(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Get(ctx, "foo"); err != cache.ErrKeyNotFound {
+		t.Fatalf("expected %v, got %v", cache.ErrKeyNotFound, err)
+	}
+}
@@ -0,0 +1,44 @@
+package memcached
+
+import (
+	"context"
+
+	"go-micro.org/v5/cache"
+	"go-micro.org/v5/codec"
+	"go-micro.org/v5/codec/json"
+)
+
+// DefaultCodec encodes values other than []byte and string.
+var DefaultCodec codec.Marshaler = json.Marshaler{}
+
+type serversKey struct{}
+type codecKey struct{}
+type prefixKey struct{}
+
+// WithServers sets the memcached servers keys are spread over. By default
+// the comma separated cache.WithAddress is used.
+func WithServers(addrs ...string) cache.Option {
+	return setOption(serversKey{}, addrs)
+}
+
+// WithCodec sets the codec values other than []byte and string are encoded
+// with.
+func WithCodec(c codec.Marshaler) cache.Option {
+	return setOption(codecKey{}, c)
+}
+
+// WithPrefix prefixes every key, e.g. with the service name so services
+// sharing memcached don't collide. It must also keep the keys apart from
+// those of other clients, whose item flags are not expiries.
+func WithPrefix(p string) cache.Option {
+	return setOption(prefixKey{}, p)
+}
+
+func setOption(k, v interface{}) cache.Option {
+	return func(o *cache.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,99 @@
+package memcached
+
+import (
+	"hash/crc32"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	mc "github.com/bradfitz/gomemcache/memcache"
+)
+
+// DefaultReplicas is the number of points each server has on the ring.
+var DefaultReplicas = 160
+
+// ring is a mc.ServerSelector picking servers by consistent hashing, so
+// adding or removing a server only moves the keys of its neighbours on the
+// ring rather than most keys, as the modulo of the default selector does.
+type ring struct {
+	mu     sync.RWMutex
+	addrs  []net.Addr
+	points []uint32
+	owners map[uint32]net.Addr
+}
+
+func newRing(servers ...string) (*ring, error) {
+	r := new(ring)
+	if err := r.SetServers(servers...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetServers replaces the servers of the ring.
+func (r *ring) SetServers(servers ...string) error {
+	addrs := make([]net.Addr, len(servers))
+	points := make([]uint32, 0, len(servers)*DefaultReplicas)
+	owners := make(map[uint32]net.Addr, len(servers)*DefaultReplicas)
+
+	for i, server := range servers {
+		var addr net.Addr
+		var err error
+		if strings.Contains(server, "/") {
+			addr, err = net.ResolveUnixAddr("unix", server)
+		} else {
+			addr, err = net.ResolveTCPAddr("tcp", server)
+		}
+		if err != nil {
+			return err
+		}
+		addrs[i] = addr
+
+		// points are hashed from the server as configured, so they don't
+		// move when its address resolves differently
+		for j := 0; j < DefaultReplicas; j++ {
+			p := crc32.ChecksumIEEE([]byte(server + "-" + strconv.Itoa(j)))
+			if _, ok := owners[p]; ok {
+				continue
+			}
+			owners[p] = addr
+			points = append(points, p)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+
+	r.mu.Lock()
+	r.addrs, r.points, r.owners = addrs, points, owners
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *ring) PickServer(key string) (net.Addr, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.points) == 0 {
+		return nil, mc.ErrNoServers
+	}
+
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]], nil
+}
+
+func (r *ring) Each(fn func(net.Addr) error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, a := range r.addrs {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
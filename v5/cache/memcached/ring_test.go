@@ -0,0 +1,56 @@
+package memcached
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	r, err := newRing("127.0.0.1:11211", "127.0.0.1:11212", "127.0.0.1:11213")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 10000
+	before := make(map[string]string, n)
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		k := "key-" + strconv.Itoa(i)
+		addr, err := r.PickServer(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[k] = addr.String()
+		counts[addr.String()]++
+	}
+	for addr, c := range counts {
+		if c < n/6 {
+			t.Errorf("%s only owns %d of %d keys", addr, c, n)
+		}
+	}
+
+	// adding a server only moves keys to it
+	if err := r.SetServers("127.0.0.1:11211", "127.0.0.1:11212", "127.0.0.1:11213", "127.0.0.1:11214"); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for k, prev := range before {
+		addr, _ := r.PickServer(k)
+		if addr.String() == prev {
+			continue
+		}
+		if addr.String() != "127.0.0.1:11214" {
+			t.Fatalf("%s moved from %s to %s", k, prev, addr)
+		}
+		moved++
+	}
+	if moved > n/2 {
+		t.Errorf("%d of %d keys moved", moved, n)
+	}
+}
+
+func TestRingEmpty(t *testing.T) {
+	if _, err := new(ring).PickServer("foo"); err == nil {
+		t.Fatal("expected an error without servers")
+	}
+}
@@ -0,0 +1,110 @@
+package redis
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "go-micro.org/v5/logger"
+)
+
+// deltaSuffix is appended to a key to store how long its value took to load.
+const deltaSuffix = ":delta"
+
+func (c *redisCache) GetOrLoad(ctx context.Context, key string, v interface{}, d time.Duration, load LoadFunc) error {
+	var get, delta *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, c.key(key))
+		ttl = p.PTTL(ctx, c.key(key))
+		delta = p.Get(ctx, c.key(key)+deltaSuffix)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	b, err := get.Bytes()
+	if err == nil {
+		if c.refreshEarly(ttl.Val(), delta) {
+			c.refresh(key, d, load)
+		}
+		return c.decode(b, v)
+	} else if err != redis.Nil {
+		return err
+	}
+
+	if b, err = c.load(ctx, key, d, load); err != nil {
+		return err
+	}
+	return c.decode(b, v)
+}
+
+// refreshEarly decides whether to refresh a value before it expires, with a
+// probability growing as the expiry nears and with the time the value took
+// to load (probabilistic early expiration, or XFetch).
+func (c *redisCache) refreshEarly(ttl time.Duration, delta *redis.StringCmd) bool {
+	if c.beta <= 0 || ttl <= 0 {
+		return false
+	}
+	ms, err := delta.Int64()
+	if err != nil || ms <= 0 {
+		return false
+	}
+	c.mtx.Lock()
+	r := c.rand.Float64()
+	c.mtx.Unlock()
+	early := -float64(ms) * c.beta * math.Log(r)
+	return time.Duration(early*float64(time.Millisecond)) >= ttl
+}
+
+// refresh loads the value of key in the background, unless it is being
+// refreshed already.
+func (c *redisCache) refresh(key string, d time.Duration, load LoadFunc) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.refreshing[key] {
+		return
+	}
+	c.refreshing[key] = true
+
+	go func() {
+		if _, err := c.load(context.Background(), key, d, load); err != nil {
+			log.Errorf("cache: error refreshing %s: %v", key, err)
+		}
+		c.mtx.Lock()
+		delete(c.refreshing, key)
+		c.mtx.Unlock()
+	}()
+}
+
+// load calls the loader once per key in the process and stores its result.
+func (c *redisCache) load(ctx context.Context, key string, d time.Duration, load LoadFunc) ([]byte, error) {
+	res, err, _ := c.group.Do(key, func() (interface{}, error) {
+		start := time.Now()
+		val, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		delta := time.Since(start)
+
+		b, err := c.encode(val)
+		if err != nil {
+			return nil, err
+		}
+		_, err = c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+			p.Set(ctx, c.key(key), b, d)
+			p.Set(ctx, c.key(key)+deltaSuffix, delta.Milliseconds(), d)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return b, c.written(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.([]byte), nil
+}
@@ -0,0 +1,196 @@
+package redis
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/open-micro/plugins/v5/cache/lru"
+	"github.com/redis/go-redis/v9"
+	"go-micro.org/v5/cache"
+	"go-micro.org/v5/logger"
+)
+
+var (
+	// DefaultInvalidationChannel is the channel invalidated keys are
+	// published to.
+	DefaultInvalidationChannel = "micro-cache-invalidate"
+	// DefaultLocalEntries bounds the local tier unless set with
+	// WithLocalOptions.
+	DefaultLocalEntries = 10000
+)
+
+// NewNearCache returns a redis cache with a bounded local tier in front of it.
+// Every Put and Delete publishes the key on the invalidation channel, and the
+// replicas drop their local copy on receipt, so local reads are stale for no
+// longer than the fan-out takes. The local tier is flushed whenever the
+// subscription breaks, as invalidations may have been missed.
+func NewNearCache(opts ...cache.Option) cache.Cache {
+	options := cache.NewOptions(opts...)
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	channel := DefaultInvalidationChannel
+	if ch, ok := options.Context.Value(invalidationChannelKey{}).(string); ok && len(ch) > 0 {
+		channel = ch
+	}
+	localTTL, _ := options.Context.Value(localTTLKey{}).(time.Duration)
+	localOpts, _ := options.Context.Value(localOptionsKey{}).([]cache.Option)
+
+	rc := newRedisCache(options)
+	n := &nearCache{
+		redisCache: rc,
+		localOpts:  append([]cache.Option{lru.MaxEntries(DefaultLocalEntries)}, localOpts...),
+		localTTL:   localTTL,
+		channel:    channel,
+		pubsub:     rc.client.Subscribe(context.Background(), channel),
+	}
+	rc.onWrite = n.invalidate
+	n.flush()
+	go n.run()
+
+	// the subscriber holds the inner cache only, so the returned one can be
+	// collected and close the subscription
+	w := &nearCacheWrapper{n}
+	runtime.SetFinalizer(w, func(w *nearCacheWrapper) {
+		w.pubsub.Close()
+	})
+	return w
+}
+
+type nearCacheWrapper struct {
+	*nearCache
+}
+
+type nearCache struct {
+	// incremented on every invalidation, local copies of values read from
+	// redis are only kept if no invalidation arrived while reading
+	gen uint64
+
+	*redisCache
+
+	// the local tier, replaced to flush it
+	local     atomic.Value
+	localOpts []cache.Option
+	localTTL  time.Duration
+	channel   string
+	pubsub    *redis.PubSub
+}
+
+func (n *nearCache) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
+	if val, expires, err := n.localCache().Get(ctx, key); err == nil {
+		if expires.IsZero() {
+			expires = time.Unix(1<<63-1, 0)
+		}
+		return val, expires, nil
+	}
+
+	gen := atomic.LoadUint64(&n.gen)
+	val, expires, err := n.redisCache.Get(ctx, key)
+	if err != nil {
+		return val, expires, err
+	}
+
+	d := n.localTTL
+	if expires.Unix() != 1<<63-1 {
+		if until := time.Until(expires); d <= 0 || until < d {
+			d = until
+		}
+		if d <= 0 {
+			return val, expires, nil
+		}
+	}
+	if atomic.LoadUint64(&n.gen) != gen {
+		return val, expires, nil
+	}
+	n.localCache().Put(ctx, key, val, d)
+	// an invalidation between the check and the put may have deleted the
+	// key before it was put, invalidations bump gen before deleting so
+	// checking again after the put catches it
+	if atomic.LoadUint64(&n.gen) != gen {
+		n.localCache().Delete(ctx, key)
+	}
+	return val, expires, nil
+}
+
+func (n *nearCache) Read(ctx context.Context, key string, v interface{}) (time.Time, error) {
+	val, expires, err := n.Get(ctx, key)
+	if err != nil {
+		return expires, err
+	}
+	return expires, n.decode(val.([]byte), v)
+}
+
+func (n *nearCache) GetOrLoad(ctx context.Context, key string, v interface{}, d time.Duration, load LoadFunc) error {
+	if val, _, err := n.localCache().Get(ctx, key); err == nil {
+		return n.decode(val.([]byte), v)
+	}
+	return n.redisCache.GetOrLoad(ctx, key, v, d, load)
+}
+
+// invalidate drops the keys locally and publishes them to the replicas.
+func (n *nearCache) invalidate(ctx context.Context, keys ...string) error {
+	atomic.AddUint64(&n.gen, 1)
+	for _, k := range keys {
+		n.localCache().Delete(ctx, k)
+	}
+
+	_, err := n.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, k := range keys {
+			p.Publish(ctx, n.channel, n.key(k))
+		}
+		return nil
+	})
+	return err
+}
+
+func (n *nearCache) localCache() cache.Cache {
+	return n.local.Load().(cache.Cache)
+}
+
+// flush drops the local tier.
+func (n *nearCache) flush() {
+	atomic.AddUint64(&n.gen, 1)
+	n.local.Store(lru.NewCache(n.localOpts...))
+}
+
+func (n *nearCache) run() {
+	ctx := context.Background()
+	subscribed := false
+
+	for {
+		msg, err := n.pubsub.Receive(ctx)
+		if err == redis.ErrClosed {
+			return
+		} else if err != nil {
+			// the connection is reestablished on the next receive
+			logger.Errorf("near cache subscription to %s failed: %v", n.channel, err)
+			n.flush()
+			time.Sleep(time.Second)
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// resubscribed after a reconnect
+			if subscribed {
+				n.flush()
+			}
+			subscribed = true
+		case *redis.Message:
+			// keys of caches with other prefixes are not ours
+			if !strings.HasPrefix(m.Payload, n.prefix) {
+				continue
+			}
+			atomic.AddUint64(&n.gen, 1)
+			n.localCache().Delete(ctx, strings.TrimPrefix(m.Payload, n.prefix))
+		}
+	}
+}
+
+func (n *nearCache) String() string {
+	return "redis-near"
+}
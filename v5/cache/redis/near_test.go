@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNearCache(t *testing.T) {
+	if len(os.Getenv("LOCAL")) == 0 {
+		t.Skip()
+	}
+
+	ch := WithInvalidationChannel("near-cache-test")
+	a := NewNearCache(addr, ch)
+	b := NewNearCache(addr, ch)
+	// let the subscriptions settle
+	time.Sleep(100 * time.Millisecond)
+
+	if err := a.Put(ctx, key, "one", 0); err != nil {
+		t.Fatal(err)
+	}
+	// cache the value locally in b
+	if v, _, err := b.Get(ctx, key); err != nil {
+		t.Fatal(err)
+	} else if string(v.([]byte)) != "one" {
+		t.Fatalf("expected one, got %s", v)
+	}
+
+	if err := a.Put(ctx, key, "two", 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if v, _, err := b.Get(ctx, key); err != nil {
+		t.Fatal(err)
+	} else if string(v.([]byte)) != "two" {
+		t.Fatalf("expected b to see two, got %s", v)
+	}
+
+	if err := a.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, _, err := b.Get(ctx, key); err == nil {
+		t.Fatal("expected b to see the delete")
+	}
+}
+
+func TestNearCacheLocalTTL(t *testing.T) {
+	if len(os.Getenv("LOCAL")) == 0 {
+		t.Skip()
+	}
+
+	c := NewNearCache(addr, WithLocalTTL(20*time.Millisecond)).(*nearCacheWrapper)
+	if err := c.Put(ctx, key, val, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	c.Get(ctx, key)
+
+	// changed behind the near cache's back
+	c.client.Set(ctx, key, "changed", time.Minute)
+	time.Sleep(30 * time.Millisecond)
+	if v, _, err := c.Get(ctx, key); err != nil {
+		t.Fatal(err)
+	} else if string(v.([]byte)) != "changed" {
+		t.Fatalf("expected the local copy to expire, got %s", v)
+	}
+}
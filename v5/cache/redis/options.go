@@ -2,12 +2,28 @@ package redis
 
 import (
 	"context"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go-micro.org/v5/cache"
+	"go-micro.org/v5/codec"
+)
+
+var (
+	// DefaultCodec encodes values other than []byte and string. It is nil,
+	// so values are encoded as go-redis encodes them unless WithCodec is set.
+	DefaultCodec codec.Marshaler
+	// DefaultEarlyRefresh is the beta of the early refresh of GetOrLoad.
+	DefaultEarlyRefresh = 1.0
 )
 
 type redisOptionsContextKey struct{}
+type localOptionsKey struct{}
+type localTTLKey struct{}
+type invalidationChannelKey struct{}
+type codecKey struct{}
+type prefixKey struct{}
+type earlyRefreshKey struct{}
 
 // WithRedisOptions sets advanced options for redis.
 func WithRedisOptions(options redis.UniversalOptions) cache.Option {
@@ -20,6 +36,51 @@ func WithRedisOptions(options redis.UniversalOptions) cache.Option {
 	}
 }
 
+// WithCodec sets the codec values other than []byte and string are encoded
+// with, e.g. json.Marshaler{} to store structs.
+func WithCodec(c codec.Marshaler) cache.Option {
+	return setOption(codecKey{}, c)
+}
+
+// WithPrefix prefixes every key, e.g. with the service name so services
+// sharing a redis don't collide.
+func WithPrefix(p string) cache.Option {
+	return setOption(prefixKey{}, p)
+}
+
+// WithEarlyRefresh sets how eagerly GetOrLoad refreshes values before they
+// expire, values above 1 favour earlier refreshes and 0 disables them.
+func WithEarlyRefresh(beta float64) cache.Option {
+	return setOption(earlyRefreshKey{}, beta)
+}
+
+// WithLocalOptions sets the options of the local lru tier of a near cache.
+func WithLocalOptions(opts ...cache.Option) cache.Option {
+	return setOption(localOptionsKey{}, opts)
+}
+
+// WithLocalTTL bounds how long a near cache keeps values locally, limiting
+// staleness should an invalidation be lost. By default values are kept until
+// they expire in redis.
+func WithLocalTTL(d time.Duration) cache.Option {
+	return setOption(localTTLKey{}, d)
+}
+
+// WithInvalidationChannel sets the channel a near cache publishes and
+// receives invalidations on. Caches sharing keys must share the channel.
+func WithInvalidationChannel(ch string) cache.Option {
+	return setOption(invalidationChannelKey{}, ch)
+}
+
+func setOption(k, v interface{}) cache.Option {
+	return func(o *cache.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
 func newUniversalClient(options cache.Options) redis.UniversalClient {
 	if options.Context == nil {
 		options.Context = context.Background()
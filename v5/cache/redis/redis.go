@@ -2,43 +2,154 @@ package redis
 
 import (
 	"context"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go-micro.org/v5/cache"
+	"go-micro.org/v5/codec"
 	"go-micro.org/v5/util/cmd"
+	"golang.org/x/sync/singleflight"
 )
 
 func init() {
 	cmd.DefaultCaches["redis"] = NewCache
 }
 
+// Cache is implemented by the caches returned by NewCache and NewNearCache.
+//
+// Values other than []byte and string are encoded with the codec set by
+// WithCodec. Without one they are encoded as go-redis encodes arguments:
+// numbers, bools, times and encoding.BinaryMarshaler values. Get returns the
+// encoded value, Read decodes it.
+type Cache interface {
+	cache.Cache
+	// Read decodes the value of key into v, a *[]byte or *string receives
+	// the value as stored.
+	Read(ctx context.Context, key string, v interface{}) (time.Time, error)
+	// GetMulti returns the encoded values of the keys found, in one round
+	// trip.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+	// PutMulti stores the values in one round trip.
+	PutMulti(ctx context.Context, vals map[string]interface{}, d time.Duration) error
+	// GetOrLoad reads the value of key into v, calling load and storing its
+	// result for d when it is missing. Concurrent loads of a key in the
+	// process are deduplicated, and values are refreshed in the background
+	// shortly before they expire so loads don't coincide across replicas.
+	GetOrLoad(ctx context.Context, key string, v interface{}, d time.Duration, load LoadFunc) error
+}
+
+// LoadFunc loads a value missing from the cache.
+type LoadFunc func(ctx context.Context) (interface{}, error)
+
 // NewCache returns a new redis cache.
 func NewCache(opts ...cache.Option) cache.Cache {
-	options := cache.NewOptions(opts...)
-	return &redisCache{
-		opts:   options,
-		client: newUniversalClient(options),
+	return newRedisCache(cache.NewOptions(opts...))
+}
+
+func newRedisCache(options cache.Options) *redisCache {
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	c := &redisCache{
+		opts:       options,
+		client:     newUniversalClient(options),
+		codec:      DefaultCodec,
+		beta:       DefaultEarlyRefresh,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		refreshing: make(map[string]bool),
 	}
+	if m, ok := options.Context.Value(codecKey{}).(codec.Marshaler); ok {
+		c.codec = m
+	}
+	if p, ok := options.Context.Value(prefixKey{}).(string); ok {
+		c.prefix = p
+	}
+	if b, ok := options.Context.Value(earlyRefreshKey{}).(float64); ok {
+		c.beta = b
+	}
+	return c
 }
 
 type redisCache struct {
 	opts   cache.Options
 	client redis.UniversalClient
+	codec  codec.Marshaler
+	prefix string
+	beta   float64
+	group  singleflight.Group
+
+	mtx sync.Mutex
+	// source of the early refresh decisions
+	rand *rand.Rand
+	// keys refreshed in the background
+	refreshing map[string]bool
+
+	// called with the keys written, used by the near cache to invalidate
+	onWrite func(ctx context.Context, keys ...string) error
+}
+
+func (c *redisCache) key(key string) string {
+	return c.prefix + key
+}
+
+func (c *redisCache) encode(val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	if c.codec == nil {
+		return appendValue(val)
+	}
+	return c.codec.Marshal(val)
+}
+
+func (c *redisCache) decode(b []byte, v interface{}) error {
+	switch p := v.(type) {
+	case *[]byte:
+		*p = b
+		return nil
+	case *string:
+		*p = string(b)
+		return nil
+	}
+	if c.codec == nil {
+		return scanValue(b, v)
+	}
+	return c.codec.Unmarshal(b, v)
+}
+
+func (c *redisCache) written(ctx context.Context, keys ...string) error {
+	if c.onWrite == nil {
+		return nil
+	}
+	return c.onWrite(ctx, keys...)
 }
 
 func (c *redisCache) Get(ctx context.Context, key string) (interface{}, time.Time, error) {
-	val, err := c.client.Get(ctx, key).Bytes()
-	if err != nil && err == redis.Nil {
-		return nil, time.Time{}, cache.ErrKeyNotFound
-	} else if err != nil {
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, c.key(key))
+		ttl = p.PTTL(ctx, c.key(key))
+		return nil
+	})
+	if err != nil && err != redis.Nil {
 		return nil, time.Time{}, err
 	}
 
-	dur, err := c.client.TTL(ctx, key).Result()
-	if err != nil {
+	val, err := get.Bytes()
+	if err == redis.Nil {
+		return nil, time.Time{}, cache.ErrKeyNotFound
+	} else if err != nil {
 		return nil, time.Time{}, err
 	}
+
+	dur := ttl.Val()
 	if dur == -1 {
 		return val, time.Unix(1<<63-1, 0), nil
 	}
@@ -49,12 +160,79 @@ func (c *redisCache) Get(ctx context.Context, key string) (interface{}, time.Tim
 	return val, time.Now().Add(dur), nil
 }
 
+func (c *redisCache) Read(ctx context.Context, key string, v interface{}) (time.Time, error) {
+	val, expires, err := c.Get(ctx, key)
+	if err != nil {
+		return expires, err
+	}
+	return expires, c.decode(val.([]byte), v)
+}
+
 func (c *redisCache) Put(ctx context.Context, key string, val interface{}, dur time.Duration) error {
-	return c.client.Set(ctx, key, val, dur).Err()
+	v, err := c.encode(val)
+	if err != nil {
+		return err
+	}
+	if err := c.client.Set(ctx, c.key(key), v, dur).Err(); err != nil {
+		return err
+	}
+	return c.written(ctx, key)
+}
+
+func (c *redisCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, k := range keys {
+			cmds[i] = p.Get(ctx, c.key(k))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	vals := make(map[string][]byte, len(keys))
+	for i, cmd := range cmds {
+		b, err := cmd.Bytes()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		vals[keys[i]] = b
+	}
+	return vals, nil
+}
+
+func (c *redisCache) PutMulti(ctx context.Context, vals map[string]interface{}, dur time.Duration) error {
+	keys := make([]string, 0, len(vals))
+	encoded := make([][]byte, 0, len(vals))
+	for k, val := range vals {
+		v, err := c.encode(val)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, k)
+		encoded = append(encoded, v)
+	}
+
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, k := range keys {
+			p.Set(ctx, c.key(k), encoded[i], dur)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.written(ctx, keys...)
 }
 
 func (c *redisCache) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+	if err := c.client.Del(ctx, c.key(key)).Err(); err != nil {
+		return err
+	}
+	return c.written(ctx, key)
 }
 
 func (m *redisCache) String() string {
@@ -2,11 +2,16 @@ package redis
 
 import (
 	"context"
+	"math/rand"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"go-micro.org/v5/cache"
+	"go-micro.org/v5/codec/json"
 )
 
 var (
@@ -86,3 +91,147 @@ func TestCache(t *testing.T) {
 		}
 	})
 }
+
+type testValue struct {
+	Name  string
+	Count int
+}
+
+func TestTypedCache(t *testing.T) {
+	if len(os.Getenv("LOCAL")) == 0 {
+		t.Skip()
+	}
+
+	c := NewCache(addr, WithPrefix("test:"), WithCodec(json.Marshaler{})).(Cache)
+
+	t.Run("Read", func(t *testing.T) {
+		if err := c.Put(ctx, key, testValue{"foo", 1}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		var v testValue
+		if _, err := c.Read(ctx, key, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Name != "foo" || v.Count != 1 {
+			t.Fatalf("unexpected value %+v", v)
+		}
+	})
+
+	t.Run("Multi", func(t *testing.T) {
+		err := c.PutMulti(ctx, map[string]interface{}{"a": "1", "b": []byte("2")}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals, err := c.GetMulti(ctx, "a", "b", "missing")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vals) != 2 || string(vals["a"]) != "1" || string(vals["b"]) != "2" {
+			t.Fatalf("unexpected values %v", vals)
+		}
+	})
+
+	t.Run("GetOrLoad", func(t *testing.T) {
+		c.Delete(ctx, "load")
+
+		var loads int32
+		load := func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			time.Sleep(50 * time.Millisecond)
+			return testValue{"loaded", 2}, nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var v testValue
+				if err := c.GetOrLoad(ctx, "load", &v, time.Minute, load); err != nil {
+					t.Error(err)
+				} else if v.Name != "loaded" {
+					t.Errorf("unexpected value %+v", v)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if n := atomic.LoadInt32(&loads); n != 1 {
+			t.Fatalf("expected 1 load, got %d", n)
+		}
+	})
+}
+
+func TestRefreshEarly(t *testing.T) {
+	c := &redisCache{beta: 1, rand: rand.New(rand.NewSource(1))}
+
+	if c.refreshEarly(time.Hour, redis.NewStringResult("1", nil)) {
+		t.Error("expected no refresh an hour before expiry")
+	}
+	if !c.refreshEarly(time.Nanosecond, redis.NewStringResult("60000", nil)) {
+		t.Error("expected a refresh right before expiry")
+	}
+	if c.refreshEarly(time.Millisecond, redis.NewStringResult("", redis.Nil)) {
+		t.Error("expected no refresh without a delta")
+	}
+
+	c.beta = 0
+	if c.refreshEarly(time.Millisecond, redis.NewStringResult("60000", nil)) {
+		t.Error("expected no refresh when disabled")
+	}
+}
+
+type binaryValue struct {
+	b []byte
+}
+
+func (v binaryValue) MarshalBinary() ([]byte, error) {
+	return v.b, nil
+}
+
+func (v *binaryValue) UnmarshalBinary(b []byte) error {
+	v.b = b
+	return nil
+}
+
+func TestValueWithoutCodec(t *testing.T) {
+	c := &redisCache{}
+
+	now := time.Now().Round(0)
+	var (
+		i  int
+		f  float64
+		ok bool
+		tm time.Time
+		bv binaryValue
+	)
+	for _, tc := range []struct {
+		in, out interface{}
+		encoded string
+	}{
+		{42, &i, "42"},
+		{1.5, &f, "1.5"},
+		{true, &ok, "1"},
+		{now, &tm, now.Format(time.RFC3339Nano)},
+		{binaryValue{[]byte("raw")}, &bv, "raw"},
+	} {
+		b, err := c.encode(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.encoded {
+			t.Fatalf("expected %v encoded as %q, got %q", tc.in, tc.encoded, b)
+		}
+		if err := c.decode(b, tc.out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if i != 42 || f != 1.5 || !ok || !tm.Equal(now) || string(bv.b) != "raw" {
+		t.Fatalf("unexpected values %v %v %v %v %q", i, f, ok, tm, bv.b)
+	}
+
+	// structs need a codec, as they did before codecs could be set
+	if _, err := c.encode(testValue{"foo", 1}); err == nil {
+		t.Fatal("expected an error encoding a struct without a codec")
+	}
+}
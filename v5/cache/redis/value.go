@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"encoding"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// appendValue encodes val the way go-redis encodes command arguments, which
+// is how values were stored before codecs could be set.
+func appendValue(val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case uint:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, v, 10), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 64), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case time.Time:
+		return v.AppendFormat(nil, time.RFC3339Nano), nil
+	case time.Duration:
+		return strconv.AppendInt(nil, v.Nanoseconds(), 10), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	}
+	return nil, fmt.Errorf("redis: can't marshal %T (implement encoding.BinaryMarshaler or set a codec)", val)
+}
+
+// scanValue decodes b into v, the reverse of appendValue.
+func scanValue(b []byte, v interface{}) error {
+	var err error
+	switch p := v.(type) {
+	case *string:
+		*p = string(b)
+	case *[]byte:
+		*p = b
+	case *int:
+		var n int64
+		n, err = strconv.ParseInt(string(b), 10, 0)
+		*p = int(n)
+	case *int8:
+		var n int64
+		n, err = strconv.ParseInt(string(b), 10, 8)
+		*p = int8(n)
+	case *int16:
+		var n int64
+		n, err = strconv.ParseInt(string(b), 10, 16)
+		*p = int16(n)
+	case *int32:
+		var n int64
+		n, err = strconv.ParseInt(string(b), 10, 32)
+		*p = int32(n)
+	case *int64:
+		*p, err = strconv.ParseInt(string(b), 10, 64)
+	case *uint:
+		var n uint64
+		n, err = strconv.ParseUint(string(b), 10, 0)
+		*p = uint(n)
+	case *uint8:
+		var n uint64
+		n, err = strconv.ParseUint(string(b), 10, 8)
+		*p = uint8(n)
+	case *uint16:
+		var n uint64
+		n, err = strconv.ParseUint(string(b), 10, 16)
+		*p = uint16(n)
+	case *uint32:
+		var n uint64
+		n, err = strconv.ParseUint(string(b), 10, 32)
+		*p = uint32(n)
+	case *uint64:
+		*p, err = strconv.ParseUint(string(b), 10, 64)
+	case *float32:
+		var f float64
+		f, err = strconv.ParseFloat(string(b), 32)
+		*p = float32(f)
+	case *float64:
+		*p, err = strconv.ParseFloat(string(b), 64)
+	case *bool:
+		*p = len(b) == 1 && b[0] == '1'
+	case *time.Time:
+		*p, err = time.Parse(time.RFC3339Nano, string(b))
+	case *time.Duration:
+		var n int64
+		n, err = strconv.ParseInt(string(b), 10, 64)
+		*p = time.Duration(n)
+	case encoding.BinaryUnmarshaler:
+		err = p.UnmarshalBinary(b)
+	default:
+		err = fmt.Errorf("redis: can't unmarshal %T (implement encoding.BinaryUnmarshaler or set a codec)", v)
+	}
+	return err
+}
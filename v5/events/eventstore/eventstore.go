@@ -0,0 +1,219 @@
+// Package eventstore provides event sourcing on top of the events backends:
+// aggregate streams with expected version checks, snapshots and projections.
+package eventstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.org/v5/store"
+)
+
+const (
+	// Any appends to a stream regardless of its version.
+	Any int64 = -1
+	// NoStream appends to a stream only if it doesn't exist yet.
+	NoStream int64 = 0
+)
+
+var (
+	ErrWrongVersion  = errors.New("wrong expected version")
+	ErrMissingEvents = errors.New("missing events")
+	ErrNoSnapshot    = errors.New("no snapshot")
+)
+
+// Event is an event of an aggregate stream.
+type Event struct {
+	// ID of the event, generated on append when empty.
+	ID string `json:"id"`
+	// Stream the event belongs to.
+	Stream string `json:"stream"`
+	// Type of the event.
+	Type string `json:"type"`
+	// Version of the stream after the event, starting at 1.
+	Version int64 `json:"version"`
+	// Position of the event across all streams, set when read with ReadAll.
+	Position string `json:"-"`
+	// Timestamp of the event, set on append when zero.
+	Timestamp time.Time `json:"timestamp"`
+	// Metadata of the event.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Data of the event.
+	Data []byte `json:"data"`
+}
+
+// NewEvent returns an event of type typ with data encoded as json, unless
+// it is already encoded.
+func NewEvent(typ string, data interface{}) (*Event, error) {
+	b, ok := data.([]byte)
+	if !ok {
+		var err error
+		if b, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+	}
+	return &Event{Type: typ, Data: b}, nil
+}
+
+// Unmarshal the data of the event.
+func (e *Event) Unmarshal(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Backend persists aggregate streams. The events/redis and events/natsjs
+// plugins implement it over Redis Streams and JetStream.
+type Backend interface {
+	// Append writes the events to the end of the stream if its version is
+	// the expected one, or expected is Any, and returns the new version.
+	Append(stream string, expected int64, events []*Event) (int64, error)
+	// Read returns up to limit events of the stream with a version greater
+	// than from, limit 0 returns all of them.
+	Read(stream string, from int64, limit int) ([]*Event, error)
+	// ReadAll returns up to limit events of all streams appended after the
+	// position, an empty position reads from the start and limit 0 returns
+	// all of them.
+	ReadAll(after string, limit int) ([]*Event, error)
+}
+
+// Snapshot is the state of an aggregate at a version.
+type Snapshot struct {
+	Stream    string    `json:"stream"`
+	Version   int64     `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      []byte    `json:"data"`
+}
+
+// Store appends and reads aggregate streams, keeps their snapshots and runs
+// projections over all streams.
+type Store interface {
+	// Append the events to the stream, see Backend.
+	Append(stream string, expected int64, events ...*Event) (int64, error)
+	// Read the events of the stream.
+	Read(stream string, opts ...ReadOption) ([]*Event, error)
+	// Load restores the state of the aggregate from its latest snapshot and
+	// applies the events appended since, returning the version of the
+	// stream. A snapshot is taken when SnapshotEvery events or more were
+	// applied.
+	Load(stream string, state interface{}, apply func(*Event) error) (int64, error)
+	// Snapshot saves the state of the aggregate at the version.
+	Snapshot(stream string, version int64, state interface{}) error
+	// LoadSnapshot returns the latest snapshot of the stream.
+	LoadSnapshot(stream string) (*Snapshot, error)
+	// Project runs the handler for the events of all streams in order,
+	// tracking its position in a checkpoint.
+	Project(name string, h Handler, opts ...ProjectOption) (*Projection, error)
+}
+
+type eventStore struct {
+	backend Backend
+	opts    Options
+}
+
+// NewStore returns a Store over the backend.
+func NewStore(b Backend, opts ...Option) Store {
+	options := Options{
+		Store:  store.DefaultStore,
+		Prefix: DefaultPrefix,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &eventStore{backend: b, opts: options}
+}
+
+func (s *eventStore) Append(stream string, expected int64, events ...*Event) (int64, error) {
+	if len(events) == 0 {
+		return 0, ErrMissingEvents
+	}
+	now := time.Now()
+	for _, e := range events {
+		if len(e.ID) == 0 {
+			e.ID = uuid.New().String()
+		}
+		if e.Timestamp.IsZero() {
+			e.Timestamp = now
+		}
+		e.Stream = stream
+	}
+	return s.backend.Append(stream, expected, events)
+}
+
+func (s *eventStore) Read(stream string, opts ...ReadOption) ([]*Event, error) {
+	var options ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	return s.backend.Read(stream, options.From, options.Limit)
+}
+
+func (s *eventStore) snapshotKey(stream string) string {
+	return s.opts.Prefix + "snapshots/" + stream
+}
+
+func (s *eventStore) Snapshot(stream string, version int64, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(&Snapshot{
+		Stream:    stream,
+		Version:   version,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+	return s.opts.Store.Write(&store.Record{Key: s.snapshotKey(stream), Value: b})
+}
+
+func (s *eventStore) LoadSnapshot(stream string) (*Snapshot, error) {
+	recs, err := s.opts.Store.Read(s.snapshotKey(stream))
+	if err == store.ErrNotFound || (err == nil && len(recs) == 0) {
+		return nil, ErrNoSnapshot
+	} else if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{}
+	if err := json.Unmarshal(recs[0].Value, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+func (s *eventStore) Load(stream string, state interface{}, apply func(*Event) error) (int64, error) {
+	var version int64
+
+	snap, err := s.LoadSnapshot(stream)
+	switch err {
+	case nil:
+		if err := json.Unmarshal(snap.Data, state); err != nil {
+			return 0, fmt.Errorf("decoding snapshot of %s: %w", stream, err)
+		}
+		version = snap.Version
+	case ErrNoSnapshot:
+	default:
+		return 0, err
+	}
+
+	events, err := s.backend.Read(stream, version, 0)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if err := apply(e); err != nil {
+			return 0, err
+		}
+		version = e.Version
+	}
+
+	if s.opts.SnapshotEvery > 0 && len(events) >= s.opts.SnapshotEvery {
+		if err := s.Snapshot(stream, version, state); err != nil {
+			return 0, err
+		}
+	}
+	return version, nil
+}
@@ -0,0 +1,161 @@
+package eventstore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-micro.org/v5/store"
+)
+
+type deposited struct {
+	Amount int `json:"amount"`
+}
+
+type account struct {
+	Balance int `json:"balance"`
+}
+
+func (a *account) apply(e *Event) error {
+	var d deposited
+	if err := e.Unmarshal(&d); err != nil {
+		return err
+	}
+	a.Balance += d.Amount
+	return nil
+}
+
+func deposit(t *testing.T, amount int) *Event {
+	e, err := NewEvent("deposited", deposited{Amount: amount})
+	require.NoError(t, err)
+	return e
+}
+
+func TestAppendRead(t *testing.T) {
+	s := NewStore(NewMemoryBackend(), Store(store.NewMemoryStore()))
+
+	v, err := s.Append("account-1", NoStream, deposit(t, 10), deposit(t, 20))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), v)
+
+	_, err = s.Append("account-1", NoStream, deposit(t, 5))
+	assert.Equal(t, ErrWrongVersion, err)
+
+	v, err = s.Append("account-1", 2, deposit(t, 5))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), v)
+
+	v, err = s.Append("account-1", Any, deposit(t, 1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), v)
+
+	events, err := s.Read("account-1", From(1), Limit(2))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(2), events[0].Version)
+	assert.Equal(t, int64(3), events[1].Version)
+	assert.Equal(t, "account-1", events[0].Stream)
+	assert.NotEmpty(t, events[0].ID)
+
+	events, err = s.Read("account-2")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestSnapshots(t *testing.T) {
+	s := NewStore(NewMemoryBackend(), Store(store.NewMemoryStore()), SnapshotEvery(3))
+
+	for i := 0; i < 3; i++ {
+		_, err := s.Append("account-1", Any, deposit(t, 10))
+		require.NoError(t, err)
+	}
+
+	var a account
+	v, err := s.Load("account-1", &a, a.apply)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), v)
+	assert.Equal(t, 30, a.Balance)
+
+	snap, err := s.LoadSnapshot("account-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), snap.Version)
+
+	_, err = s.Append("account-1", 3, deposit(t, 5))
+	require.NoError(t, err)
+
+	// only the event after the snapshot is applied
+	var applied int
+	var b account
+	v, err = s.Load("account-1", &b, func(e *Event) error {
+		applied++
+		return b.apply(e)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), v)
+	assert.Equal(t, 35, b.Balance)
+	assert.Equal(t, 1, applied)
+
+	_, err = s.LoadSnapshot("account-2")
+	assert.Equal(t, ErrNoSnapshot, err)
+}
+
+func TestProjection(t *testing.T) {
+	st := store.NewMemoryStore()
+	s := NewStore(NewMemoryBackend(), Store(st))
+
+	var mtx sync.Mutex
+	totals := map[string]int{}
+	handler := func(e *Event) error {
+		var d deposited
+		if err := e.Unmarshal(&d); err != nil {
+			return err
+		}
+		mtx.Lock()
+		totals[e.Stream] += d.Amount
+		mtx.Unlock()
+		return nil
+	}
+	total := func(stream string) int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return totals[stream]
+	}
+
+	p, err := s.Project("totals", handler, Interval(10*time.Millisecond), BatchSize(2))
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err := s.Append("account-1", Any, deposit(t, 10))
+		require.NoError(t, err)
+	}
+	_, err = s.Append("account-2", Any, deposit(t, 7))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return total("account-1") == 50 && total("account-2") == 7
+	}, time.Second, 10*time.Millisecond)
+	p.Stop()
+	assert.Equal(t, "6", p.Position())
+
+	// resumes from the checkpoint
+	_, err = s.Append("account-2", Any, deposit(t, 3))
+	require.NoError(t, err)
+	p, err = s.Project("totals", handler, Interval(10*time.Millisecond))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return total("account-2") == 10
+	}, time.Second, 10*time.Millisecond)
+	p.Stop()
+	assert.Equal(t, 50, total("account-1"))
+
+	// rebuilds from the first event
+	totals = map[string]int{}
+	p, err = s.Project("totals", handler, Interval(10*time.Millisecond), Reset())
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return total("account-1") == 50 && total("account-2") == 10
+	}, time.Second, 10*time.Millisecond)
+	p.Stop()
+}
@@ -0,0 +1,81 @@
+package eventstore
+
+import (
+	"strconv"
+	"sync"
+)
+
+type memoryBackend struct {
+	sync.RWMutex
+	streams map[string][]*Event
+	all     []*Event
+}
+
+// NewMemoryBackend returns a Backend keeping streams in memory, for tests.
+func NewMemoryBackend() Backend {
+	return &memoryBackend{streams: map[string][]*Event{}}
+}
+
+func (m *memoryBackend) Append(stream string, expected int64, events []*Event) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	version := int64(len(m.streams[stream]))
+	if expected != Any && expected != version {
+		return version, ErrWrongVersion
+	}
+
+	for _, e := range events {
+		version++
+		ev := *e
+		ev.Version = version
+		ev.Position = strconv.Itoa(len(m.all) + 1)
+		m.streams[stream] = append(m.streams[stream], &ev)
+		m.all = append(m.all, &ev)
+	}
+	return version, nil
+}
+
+func (m *memoryBackend) Read(stream string, from int64, limit int) ([]*Event, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	events := m.streams[stream]
+	if from >= int64(len(events)) {
+		return nil, nil
+	}
+	if from < 0 {
+		from = 0
+	}
+	return copyEvents(events[from:], limit), nil
+}
+
+func (m *memoryBackend) ReadAll(after string, limit int) ([]*Event, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var from int
+	if len(after) > 0 {
+		n, err := strconv.Atoi(after)
+		if err != nil {
+			return nil, err
+		}
+		from = n
+	}
+	if from >= len(m.all) {
+		return nil, nil
+	}
+	return copyEvents(m.all[from:], limit), nil
+}
+
+func copyEvents(events []*Event, limit int) []*Event {
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	out := make([]*Event, len(events))
+	for i, e := range events {
+		ev := *e
+		out[i] = &ev
+	}
+	return out
+}
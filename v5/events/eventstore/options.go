@@ -0,0 +1,109 @@
+package eventstore
+
+import (
+	"time"
+
+	"go-micro.org/v5/store"
+)
+
+var (
+	DefaultPrefix = "micro/eventstore/"
+	// DefaultInterval is how often projections poll for new events.
+	DefaultInterval = time.Second
+	// DefaultBatchSize is how many events projections read at once.
+	DefaultBatchSize = 100
+)
+
+// Options of the event store.
+type Options struct {
+	// Store keeps snapshots and projection checkpoints.
+	Store store.Store
+	// Prefix of the keys written to the store.
+	Prefix string
+	// SnapshotEvery takes a snapshot on Load once as many events were
+	// applied on top of the latest one, 0 disables automatic snapshots.
+	SnapshotEvery int
+}
+
+type Option func(o *Options)
+
+// Store sets the store of snapshots and checkpoints, defaults to
+// store.DefaultStore.
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Prefix sets the prefix of the keys written to the store.
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// SnapshotEvery takes a snapshot on Load once n events were applied on top
+// of the latest snapshot.
+func SnapshotEvery(n int) Option {
+	return func(o *Options) {
+		o.SnapshotEvery = n
+	}
+}
+
+// ReadOptions of Read.
+type ReadOptions struct {
+	// From reads the events with a greater version.
+	From int64
+	// Limit of events to read, 0 reads all of them.
+	Limit int
+}
+
+type ReadOption func(o *ReadOptions)
+
+// From reads the events after version v.
+func From(v int64) ReadOption {
+	return func(o *ReadOptions) {
+		o.From = v
+	}
+}
+
+// Limit the number of events read.
+func Limit(n int) ReadOption {
+	return func(o *ReadOptions) {
+		o.Limit = n
+	}
+}
+
+// ProjectOptions of a projection.
+type ProjectOptions struct {
+	// Interval the backend is polled for new events.
+	Interval time.Duration
+	// BatchSize of events read at once.
+	BatchSize int
+	// Reset starts the projection from the first event, rebuilding it.
+	Reset bool
+}
+
+type ProjectOption func(o *ProjectOptions)
+
+// Interval sets how often the projection polls for new events.
+func Interval(d time.Duration) ProjectOption {
+	return func(o *ProjectOptions) {
+		o.Interval = d
+	}
+}
+
+// BatchSize sets how many events the projection reads at once.
+func BatchSize(n int) ProjectOption {
+	return func(o *ProjectOptions) {
+		o.BatchSize = n
+	}
+}
+
+// Reset discards the checkpoint so the projection is rebuilt from the first
+// event.
+func Reset() ProjectOption {
+	return func(o *ProjectOptions) {
+		o.Reset = true
+	}
+}
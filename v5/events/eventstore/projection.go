@@ -0,0 +1,151 @@
+package eventstore
+
+import (
+	"sync"
+	"time"
+
+	log "go-micro.org/v5/logger"
+	"go-micro.org/v5/store"
+)
+
+// Handler handles an event of a projection. Returning an error stops the
+// projection at the event until the next poll.
+type Handler func(*Event) error
+
+// Projection runs a handler for the events of all streams in order and keeps
+// its position in a checkpoint, so it resumes where it stopped.
+type Projection struct {
+	name    string
+	handler Handler
+	store   *eventStore
+	opts    ProjectOptions
+
+	sync.Mutex
+	position string
+
+	once sync.Once
+	exit chan struct{}
+	done chan struct{}
+}
+
+func (s *eventStore) Project(name string, h Handler, opts ...ProjectOption) (*Projection, error) {
+	options := ProjectOptions{
+		Interval:  DefaultInterval,
+		BatchSize: DefaultBatchSize,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	p := &Projection{
+		name:    name,
+		handler: h,
+		store:   s,
+		opts:    options,
+		exit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if options.Reset {
+		if err := p.writeCheckpoint(""); err != nil {
+			return nil, err
+		}
+	} else {
+		recs, err := s.opts.Store.Read(p.checkpointKey())
+		if err != nil && err != store.ErrNotFound {
+			return nil, err
+		}
+		if len(recs) > 0 {
+			p.position = string(recs[0].Value)
+		}
+	}
+
+	go p.run()
+	return p, nil
+}
+
+func (p *Projection) checkpointKey() string {
+	return p.store.opts.Prefix + "checkpoints/" + p.name
+}
+
+func (p *Projection) writeCheckpoint(position string) error {
+	return p.store.opts.Store.Write(&store.Record{Key: p.checkpointKey(), Value: []byte(position)})
+}
+
+func (p *Projection) run() {
+	defer close(p.done)
+
+	t := time.NewTicker(p.opts.Interval)
+	defer t.Stop()
+
+	for {
+		n, err := p.poll()
+		if err != nil {
+			log.Errorf("eventstore: projection %s: %v", p.name, err)
+		}
+
+		// keep reading while there are full batches to catch up on
+		if err == nil && n == p.opts.BatchSize {
+			select {
+			case <-p.exit:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-p.exit:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// poll handles the next batch of events and advances the checkpoint past the
+// handled ones.
+func (p *Projection) poll() (int, error) {
+	p.Lock()
+	position := p.position
+	p.Unlock()
+
+	events, err := p.store.backend.ReadAll(position, p.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var handled int
+	var herr error
+	for _, e := range events {
+		if herr = p.handler(e); herr != nil {
+			break
+		}
+		position = e.Position
+		handled++
+	}
+
+	if handled > 0 {
+		if err := p.writeCheckpoint(position); err != nil {
+			return 0, err
+		}
+		p.Lock()
+		p.position = position
+		p.Unlock()
+	}
+	return handled, herr
+}
+
+// Position returns the position of the last handled event.
+func (p *Projection) Position() string {
+	p.Lock()
+	defer p.Unlock()
+	return p.position
+}
+
+// Stop the projection after the running batch.
+func (p *Projection) Stop() {
+	p.once.Do(func() {
+		close(p.exit)
+	})
+	<-p.done
+}
@@ -0,0 +1,471 @@
+// Package memory provides an in-memory implementation of the events.Stream interface.
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go-micro.org/v5/events"
+	"go-micro.org/v5/logger"
+	"go-micro.org/v5/store"
+)
+
+var (
+	DefaultPrefix = "micro/events/"
+	// DefaultAckWait is how long a delivered event may go unacknowledged
+	// before it is redelivered, matching the JetStream default.
+	DefaultAckWait = 30 * time.Second
+)
+
+// NewStream returns an in-memory stream. Events and consumer group positions
+// are kept in a store when the Store option is set. The stream implements
+// io.Closer, closing it stops its consumers and closes their channels.
+func NewStream(opts ...Option) (events.Stream, error) {
+	options := Options{
+		Prefix: DefaultPrefix,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &stream{
+		opts:   options,
+		topics: map[string]*topic{},
+		exit:   make(chan struct{}),
+	}, nil
+}
+
+type stream struct {
+	opts Options
+
+	sync.Mutex
+	topics map[string]*topic
+	// closed to stop the consumers
+	exit   chan struct{}
+	closed bool
+}
+
+type topic struct {
+	name string
+	opts Options
+	exit chan struct{}
+
+	sync.Mutex
+	log    []*events.Event
+	groups map[string]*group
+	// closed and replaced whenever there may be something new to deliver
+	notify chan struct{}
+}
+
+// group is a consumer group, each event of the topic is delivered to one
+// consumer of the group.
+type group struct {
+	name string
+	// index of the next event in the log to deliver
+	next int
+	// delivered but unacknowledged events by index in the log
+	pending map[int]*delivery
+}
+
+type delivery struct {
+	attempts int
+	// the event is redelivered once the deadline passed, a zero deadline
+	// redelivers it immediately
+	deadline time.Time
+}
+
+// groupState is a group as written to the store.
+type groupState struct {
+	Next    int         `json:"next"`
+	Pending map[int]int `json:"pending"`
+}
+
+func (s *stream) topic(name string) (*topic, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if t, ok := s.topics[name]; ok {
+		return t, nil
+	}
+
+	t := &topic{
+		name:   name,
+		opts:   s.opts,
+		exit:   s.exit,
+		groups: map[string]*group{},
+		notify: make(chan struct{}),
+	}
+	if s.opts.Store != nil {
+		if err := t.load(); err != nil {
+			return nil, errors.Wrap(err, "Error loading topic from store")
+		}
+	}
+	s.topics[name] = t
+	return t, nil
+}
+
+// Publish a message to a topic.
+func (s *stream) Publish(topic string, msg interface{}, opts ...events.PublishOption) error {
+	// validate the topic
+	if len(topic) == 0 {
+		return events.ErrMissingTopic
+	}
+
+	// parse the options
+	options := events.PublishOptions{
+		Timestamp: time.Now(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	// encode the message if it's not already encoded
+	var payload []byte
+	if p, ok := msg.([]byte); ok {
+		payload = p
+	} else {
+		p, err := json.Marshal(msg)
+		if err != nil {
+			return events.ErrEncodingMessage
+		}
+		payload = p
+	}
+
+	// construct the event
+	event := &events.Event{
+		ID:        uuid.New().String(),
+		Topic:     topic,
+		Timestamp: options.Timestamp,
+		Metadata:  options.Metadata,
+		Payload:   payload,
+	}
+
+	t, err := s.topic(topic)
+	if err != nil {
+		return err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if err := t.writeEvent(len(t.log), event); err != nil {
+		return errors.Wrap(err, "Error writing event to store")
+	}
+	t.log = append(t.log, event)
+	t.signal()
+	return nil
+}
+
+// Consume from a topic.
+func (s *stream) Consume(topic string, opts ...events.ConsumeOption) (<-chan events.Event, error) {
+	// validate the topic
+	if len(topic) == 0 {
+		return nil, events.ErrMissingTopic
+	}
+
+	// parse the options
+	options := events.ConsumeOptions{
+		Group: uuid.New().String(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s.Lock()
+	closed := s.closed
+	s.Unlock()
+	if closed {
+		return nil, errors.New("stream closed")
+	}
+
+	t, err := s.topic(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Lock()
+	g, ok := t.groups[options.Group]
+	if !ok {
+		// a new group starts at the offset, or with new events only
+		g = &group{
+			name:    options.Group,
+			next:    len(t.log),
+			pending: map[int]*delivery{},
+		}
+		if !options.Offset.IsZero() {
+			g.next = sort.Search(len(t.log), func(i int) bool {
+				return !t.log[i].Timestamp.Before(options.Offset)
+			})
+		}
+		t.groups[g.name] = g
+		t.writeGroup(g)
+	}
+	t.Unlock()
+
+	ch := make(chan events.Event)
+	go t.consume(g, options, ch)
+	return ch, nil
+}
+
+// Close stops the consumers of the stream and closes their channels.
+func (s *stream) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.exit)
+	}
+	return nil
+}
+
+// consume delivers the events claimed for the group to the channel until
+// the stream is closed.
+func (t *topic) consume(g *group, options events.ConsumeOptions, ch chan events.Event) {
+	defer close(ch)
+
+	ackWait := options.AckWait
+	if ackWait <= 0 {
+		ackWait = DefaultAckWait
+	}
+
+	for {
+		t.Lock()
+		idx, attempt, ok := t.claim(g, options.AutoAck, ackWait, options.GetRetryLimit())
+		var evt events.Event
+		if ok {
+			// copied under the lock, publishing may grow the log
+			evt = *t.log[idx]
+		}
+		notify := t.notify
+		deadline := g.deadline()
+		t.Unlock()
+
+		if !ok {
+			// wait for a publish, a nack or the next ack deadline
+			var timer *time.Timer
+			var timeout <-chan time.Time
+			if !deadline.IsZero() {
+				timer = time.NewTimer(time.Until(deadline))
+				timeout = timer.C
+			}
+			select {
+			case <-notify:
+			case <-timeout:
+			case <-t.exit:
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			select {
+			case <-t.exit:
+				return
+			default:
+			}
+			continue
+		}
+
+		if !options.AutoAck {
+			evt.SetAckFunc(func() error {
+				return t.ack(g, idx, attempt)
+			})
+			evt.SetNackFunc(func() error {
+				return t.nack(g, idx, attempt)
+			})
+		}
+
+		select {
+		case ch <- evt:
+		case <-t.exit:
+			// the claimed event is redelivered once its ack deadline passed
+			return
+		}
+	}
+}
+
+// claim returns the index of the next event to deliver to the group.
+// Unacknowledged events which are due are redelivered before new events.
+func (t *topic) claim(g *group, autoAck bool, ackWait time.Duration, retryLimit int) (int, int, bool) {
+	now := time.Now()
+
+	indexes := make([]int, 0, len(g.pending))
+	for idx := range g.pending {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	for _, idx := range indexes {
+		d := g.pending[idx]
+		if d.deadline.After(now) {
+			continue
+		}
+		if retryLimit > 0 && d.attempts >= retryLimit {
+			// delivered as often as allowed, give up on it
+			delete(g.pending, idx)
+			t.writeGroup(g)
+			continue
+		}
+		d.attempts++
+		d.deadline = now.Add(ackWait)
+		t.writeGroup(g)
+		return idx, d.attempts, true
+	}
+
+	if g.next >= len(t.log) {
+		return 0, 0, false
+	}
+
+	idx := g.next
+	g.next++
+	if !autoAck {
+		g.pending[idx] = &delivery{attempts: 1, deadline: now.Add(ackWait)}
+	}
+	t.writeGroup(g)
+	return idx, 1, true
+}
+
+func (t *topic) ack(g *group, idx, attempt int) error {
+	t.Lock()
+	defer t.Unlock()
+
+	// the event was redelivered since, or acknowledged already
+	if d, ok := g.pending[idx]; !ok || d.attempts != attempt {
+		return nil
+	}
+	delete(g.pending, idx)
+	t.writeGroup(g)
+	return nil
+}
+
+func (t *topic) nack(g *group, idx, attempt int) error {
+	t.Lock()
+	defer t.Unlock()
+
+	d, ok := g.pending[idx]
+	if !ok || d.attempts != attempt {
+		return nil
+	}
+	d.deadline = time.Time{}
+	t.signal()
+	return nil
+}
+
+// deadline returns the earliest ack deadline of the group.
+func (g *group) deadline() time.Time {
+	var deadline time.Time
+	for _, d := range g.pending {
+		if deadline.IsZero() || d.deadline.Before(deadline) {
+			deadline = d.deadline
+		}
+	}
+	return deadline
+}
+
+func (t *topic) signal() {
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+func (t *topic) eventPrefix() string {
+	return fmt.Sprintf("%sevents/%s/", t.opts.Prefix, t.name)
+}
+
+func (t *topic) groupPrefix() string {
+	return fmt.Sprintf("%sgroups/%s/", t.opts.Prefix, t.name)
+}
+
+func (t *topic) writeEvent(idx int, event *events.Event) error {
+	if t.opts.Store == nil {
+		return nil
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return t.opts.Store.Write(&store.Record{
+		Key:   fmt.Sprintf("%s%020d", t.eventPrefix(), idx),
+		Value: b,
+	})
+}
+
+func (t *topic) writeGroup(g *group) {
+	if t.opts.Store == nil {
+		return
+	}
+	state := groupState{Next: g.next, Pending: make(map[int]int, len(g.pending))}
+	for idx, d := range g.pending {
+		state.Pending[idx] = d.attempts
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		logger.Errorf("Error encoding group %s of topic %s: %v", g.name, t.name, err)
+		return
+	}
+	if err := t.opts.Store.Write(&store.Record{Key: t.groupPrefix() + g.name, Value: b}); err != nil {
+		logger.Errorf("Error writing group %s of topic %s: %v", g.name, t.name, err)
+	}
+}
+
+// load reads the events and groups of the topic from the store. Events
+// pending when the groups were written are redelivered immediately.
+func (t *topic) load() error {
+	prefix := t.eventPrefix()
+	keys, err := t.opts.Store.List(store.ListPrefix(prefix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// keys of topics sharing the prefix don't parse
+		if _, err := strconv.Atoi(strings.TrimPrefix(k, prefix)); err != nil {
+			continue
+		}
+		recs, err := t.opts.Store.Read(k)
+		if err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			continue
+		}
+		var event events.Event
+		if err := json.Unmarshal(recs[0].Value, &event); err != nil {
+			return err
+		}
+		t.log = append(t.log, &event)
+	}
+
+	prefix = t.groupPrefix()
+	keys, err = t.opts.Store.List(store.ListPrefix(prefix))
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		name := strings.TrimPrefix(k, prefix)
+		if strings.Contains(name, "/") {
+			continue
+		}
+		recs, err := t.opts.Store.Read(k)
+		if err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			continue
+		}
+		var state groupState
+		if err := json.Unmarshal(recs[0].Value, &state); err != nil {
+			return err
+		}
+		g := &group{name: name, next: state.Next, pending: map[int]*delivery{}}
+		for idx, attempts := range state.Pending {
+			g.pending[idx] = &delivery{attempts: attempts}
+		}
+		t.groups[name] = g
+	}
+	return nil
+}
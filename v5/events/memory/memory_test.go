@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-micro.org/v5/events"
+	"go-micro.org/v5/store"
+)
+
+type testObj struct {
+	One string
+	Two int64
+}
+
+func receive(t *testing.T, ch <-chan events.Event) events.Event {
+	t.Helper()
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Failed to receive message within the time limit")
+	}
+	return events.Event{}
+}
+
+func nothing(t *testing.T, ch <-chan events.Event) {
+	t.Helper()
+	select {
+	case ev := <-ch:
+		t.Fatalf("Unexpected event %s", ev.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestStream(t *testing.T) {
+	s, err := NewStream()
+	require.NoError(t, err)
+
+	start := time.Now()
+	ch, err := s.Consume("foo", events.WithAutoAck(true, 0))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Publish("foo", testObj{One: "foo", Two: 12345}, events.WithMetadata(map[string]string{"meta": "bar"})))
+	ev := receive(t, ch)
+	assert.NotEmpty(t, ev.ID, "Missing ID")
+	assert.Equal(t, "foo", ev.Topic)
+	assert.Equal(t, "bar", ev.Metadata["meta"])
+
+	var tes testObj
+	assert.NoError(t, json.Unmarshal(ev.Payload, &tes))
+	assert.Equal(t, int64(12345), tes.Two)
+
+	require.NoError(t, s.Publish("foo", testObj{One: "bar", Two: 6789}))
+	receive(t, ch)
+
+	// a new consumer replays from the offset
+	replay, err := s.Consume("foo", events.WithOffset(start), events.WithAutoAck(true, 0))
+	require.NoError(t, err)
+	receive(t, replay)
+	receive(t, replay)
+	nothing(t, replay)
+
+	assert.Equal(t, events.ErrMissingTopic, s.Publish("", nil))
+}
+
+func TestGroups(t *testing.T) {
+	s, err := NewStream()
+	require.NoError(t, err)
+
+	ch1, err := s.Consume("bar", events.WithGroup("mygroup"), events.WithAutoAck(true, 0))
+	require.NoError(t, err)
+	ch2, err := s.Consume("bar", events.WithGroup("mygroup"), events.WithAutoAck(true, 0))
+	require.NoError(t, err)
+	other, err := s.Consume("bar", events.WithGroup("other"), events.WithAutoAck(true, 0))
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Publish("bar", testObj{Two: int64(i)}))
+	}
+
+	var n int
+	timeout := time.After(time.Second)
+	for n < 10 {
+		select {
+		case <-ch1:
+		case <-ch2:
+		case <-timeout:
+			t.Fatalf("Received %d of 10 events", n)
+		}
+		n++
+	}
+	nothing(t, ch1)
+	nothing(t, ch2)
+
+	for i := 0; i < 10; i++ {
+		receive(t, other)
+	}
+}
+
+func TestAckNack(t *testing.T) {
+	s, err := NewStream()
+	require.NoError(t, err)
+
+	ch, err := s.Consume("baz", events.WithGroup("mygroup"), events.WithRetryLimit(2), events.WithAutoAck(false, 100*time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, s.Publish("baz", testObj{One: "nack"}))
+
+	ev := receive(t, ch)
+	require.NoError(t, ev.Nack())
+	redelivered := receive(t, ch)
+	assert.Equal(t, ev.ID, redelivered.ID)
+
+	// delivered as often as the retry limit allows
+	require.NoError(t, redelivered.Nack())
+	nothing(t, ch)
+
+	require.NoError(t, s.Publish("baz", testObj{One: "timeout"}))
+	ev = receive(t, ch)
+	// not acknowledged within the ack wait
+	redelivered = receive(t, ch)
+	assert.Equal(t, ev.ID, redelivered.ID)
+	require.NoError(t, redelivered.Ack())
+	nothing(t, ch)
+}
+
+func TestClose(t *testing.T) {
+	s, err := NewStream()
+	require.NoError(t, err)
+
+	// nobody reads the channel, the consumer is blocked sending the event
+	ch, err := s.Consume("qux", events.WithAutoAck(true, 0))
+	require.NoError(t, err)
+	require.NoError(t, s.Publish("qux", testObj{One: "first"}))
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, s.(io.Closer).Close())
+	select {
+	case _, ok := <-ch:
+		if ok {
+			// the event was sent before the stream was closed
+			_, ok = <-ch
+		}
+		assert.False(t, ok, "Expected the channel to be closed")
+	case <-time.After(time.Second):
+		t.Fatal("Consumer was not stopped")
+	}
+
+	_, err = s.Consume("qux")
+	assert.Error(t, err, "Expected an error consuming a closed stream")
+}
+
+func TestDurable(t *testing.T) {
+	st := store.NewMemoryStore()
+
+	s, err := NewStream(Store(st))
+	require.NoError(t, err)
+	ch, err := s.Consume("durable", events.WithGroup("mygroup"))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Publish("durable", testObj{One: "acked"}))
+	require.NoError(t, s.Publish("durable", testObj{One: "pending"}))
+	require.NoError(t, receive(t, ch).Ack())
+	pending := receive(t, ch)
+
+	// a stream over the same store resumes the group
+	s, err = NewStream(Store(st))
+	require.NoError(t, err)
+	ch, err = s.Consume("durable", events.WithGroup("mygroup"))
+	require.NoError(t, err)
+	ev := receive(t, ch)
+	assert.Equal(t, pending.ID, ev.ID)
+	require.NoError(t, ev.Ack())
+	nothing(t, ch)
+
+	replay, err := s.Consume("durable", events.WithOffset(time.Now().Add(-time.Minute)), events.WithAutoAck(true, 0))
+	require.NoError(t, err)
+	receive(t, replay)
+	receive(t, replay)
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"go-micro.org/v5/store"
+)
+
+// Options which are used to configure the in-memory stream.
+type Options struct {
+	// Store persists events and consumer group positions when set, so a
+	// stream created over the same store resumes where the last one
+	// stopped.
+	Store store.Store
+	// Prefix of the keys written to the store.
+	Prefix string
+}
+
+// Option is a function which configures options.
+type Option func(o *Options)
+
+// Store persists events and consumer groups to the store.
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Prefix sets the prefix of the keys written to the store.
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
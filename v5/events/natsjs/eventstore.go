@@ -0,0 +1,269 @@
+package natsjs
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/open-micro/plugins/v5/events/eventstore"
+	"github.com/pkg/errors"
+)
+
+var (
+	// EventStoreStream is the JetStream stream aggregate streams are kept in.
+	EventStoreStream = "EVENTSTORE"
+	// EventStoreSubject prefixes the subjects of aggregate streams, which
+	// have to be valid subject tokens.
+	EventStoreSubject = "eventstore"
+	// eventStoreTimeout bounds waiting for messages when reading.
+	eventStoreTimeout = 5 * time.Second
+	// eventStoreRetries bounds retrying appends to Any on concurrent
+	// appends.
+	eventStoreRetries = 10
+)
+
+// versionHeader carries the version of the aggregate stream after an event.
+const versionHeader = "Micro-Version"
+
+type natsEventStore struct {
+	js nats.JetStreamContext
+}
+
+// NewEventStore returns an eventstore.Backend over JetStream. Events of an
+// aggregate are published to <EventStoreSubject>.<stream>, expected version
+// checks use the last sequence per subject. Reads look up the start
+// sequence with direct gets, which the stream is created or updated to
+// allow.
+func NewEventStore(opts ...Option) (eventstore.Backend, error) {
+	options := Options{
+		ClusterID: defaultClusterID,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	js, err := connectToNatsJetStream(options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error connecting to nats cluster %v", options.ClusterID)
+	}
+
+	// ensure that the stream of aggregates exists
+	info, err := js.StreamInfo(EventStoreStream)
+	if err != nil {
+		if _, err := js.AddStream(&nats.StreamConfig{
+			Name:        EventStoreStream,
+			Subjects:    []string{EventStoreSubject + ".>"},
+			AllowDirect: true,
+		}); err != nil {
+			return nil, errors.Wrap(err, "Stream did not exist and adding a stream failed")
+		}
+	} else if !info.Config.AllowDirect {
+		cfg := info.Config
+		cfg.AllowDirect = true
+		if _, err := js.UpdateStream(&cfg); err != nil {
+			return nil, errors.Wrap(err, "Error allowing direct gets on the stream")
+		}
+	}
+
+	return &natsEventStore{js: js}, nil
+}
+
+func (n *natsEventStore) subject(stream string) string {
+	return EventStoreSubject + "." + stream
+}
+
+// last returns the version of the stream and the sequence of its last event.
+func (n *natsEventStore) last(stream string) (int64, uint64, error) {
+	msg, err := n.js.GetLastMsg(EventStoreStream, n.subject(stream))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	version, err := msgVersion(msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	return version, msg.Sequence, nil
+}
+
+func msgVersion(msg *nats.RawStreamMsg) (int64, error) {
+	version, err := strconv.ParseInt(msg.Header.Get(versionHeader), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid version of message %d", msg.Sequence)
+	}
+	return version, nil
+}
+
+// start returns the sequence to read the stream from to get the events after
+// version from. The sequences of a stream ascend with its versions, so the
+// first event after from is searched for with direct gets of the next event
+// of the stream at a sequence, up to the sequence of its last event.
+func (n *natsEventStore) start(stream string, from int64, last uint64) (uint64, error) {
+	lo, hi := uint64(1), last
+	if from <= 0 {
+		return lo, nil
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		msg, err := n.js.GetMsg(EventStoreStream, mid, nats.DirectGetNext(n.subject(stream)))
+		if err != nil {
+			return 0, errors.Wrapf(err, "Error getting event at %d", mid)
+		}
+		version, err := msgVersion(msg)
+		if err != nil {
+			return 0, err
+		}
+		if version > from {
+			hi = mid
+		} else {
+			// no later event up to its sequence
+			lo = msg.Sequence + 1
+		}
+	}
+	return lo, nil
+}
+
+// Append publishes the events one by one, each expecting the sequence of the
+// previous one. JetStream has no atomic batch publish, so a batch failing at
+// an event, e.g. on a concurrent append, leaves the events published before
+// it in place and returns the version after the last of them with the error.
+//
+// Versions are assigned here, so the first event still expects the last
+// sequence read when expected is Any; a concurrent append is retried with
+// the new version instead of failing.
+func (n *natsEventStore) Append(stream string, expected int64, events []*eventstore.Event) (int64, error) {
+	for retries := 0; ; retries++ {
+		version, seq, err := n.last(stream)
+		if err != nil {
+			return 0, err
+		}
+		if expected != eventstore.Any && expected != version {
+			return version, eventstore.ErrWrongVersion
+		}
+
+		v, err := n.append(stream, version, seq, events)
+		if err == eventstore.ErrWrongVersion && expected == eventstore.Any && v == version && retries < eventStoreRetries {
+			// nothing was published before the conflict
+			continue
+		}
+		return v, err
+	}
+}
+
+// append publishes the events after version, the first one expecting seq to
+// be the last sequence of the stream.
+func (n *natsEventStore) append(stream string, version int64, seq uint64, events []*eventstore.Event) (int64, error) {
+	for _, e := range events {
+		ev := *e
+		ev.Version = version + 1
+
+		b, err := json.Marshal(&ev)
+		if err != nil {
+			return version, errors.Wrap(err, "Error encoding event")
+		}
+
+		msg := nats.NewMsg(n.subject(stream))
+		msg.Header.Set(versionHeader, strconv.FormatInt(ev.Version, 10))
+		msg.Header.Set(nats.MsgIdHdr, ev.ID)
+		msg.Data = b
+
+		ack, err := n.js.PublishMsg(msg, nats.ExpectLastSequencePerSubject(seq))
+		if err != nil {
+			var apiErr *nats.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence {
+				return version, eventstore.ErrWrongVersion
+			}
+			return version, errors.Wrap(err, "Error publishing event")
+		}
+		if ack.Duplicate {
+			// dropped by the deduplication window
+			return version, errors.Errorf("Duplicate event %s", ev.ID)
+		}
+		version = ev.Version
+		seq = ack.Sequence
+	}
+	return version, nil
+}
+
+// read consumes the subject from the start sequence until the last message
+// or the limit.
+func (n *natsEventStore) read(subject string, start uint64, limit int, keep func(*eventstore.Event) bool) ([]*eventstore.Event, error) {
+	sub, err := n.js.SubscribeSync(subject,
+		nats.OrderedConsumer(),
+		nats.StartSequence(start),
+		nats.BindStream(EventStoreStream),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error subscribing to events")
+	}
+	defer sub.Unsubscribe()
+
+	var events []*eventstore.Event
+	for limit <= 0 || len(events) < limit {
+		msg, err := sub.NextMsg(eventStoreTimeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error reading events")
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		e := &eventstore.Event{}
+		if err := json.Unmarshal(msg.Data, e); err != nil {
+			return nil, errors.Wrap(err, "Error decoding event")
+		}
+		e.Position = strconv.FormatUint(meta.Sequence.Stream, 10)
+		if keep(e) {
+			events = append(events, e)
+		}
+
+		if meta.NumPending == 0 {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (n *natsEventStore) Read(stream string, from int64, limit int) ([]*eventstore.Event, error) {
+	version, last, err := n.last(stream)
+	if err != nil {
+		return nil, err
+	}
+	if version <= from {
+		return nil, nil
+	}
+
+	start, err := n.start(stream, from, last)
+	if err != nil {
+		return nil, err
+	}
+	return n.read(n.subject(stream), start, limit, func(e *eventstore.Event) bool {
+		return e.Version > from
+	})
+}
+
+func (n *natsEventStore) ReadAll(after string, limit int) ([]*eventstore.Event, error) {
+	var seq uint64
+	if len(after) > 0 {
+		s, err := strconv.ParseUint(after, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid position %s", after)
+		}
+		seq = s
+	}
+
+	info, err := n.js.StreamInfo(EventStoreStream)
+	if err != nil {
+		return nil, err
+	}
+	if info.State.LastSeq <= seq {
+		return nil, nil
+	}
+
+	return n.read(EventStoreSubject+".>", seq+1, limit, func(*eventstore.Event) bool {
+		return true
+	})
+}
@@ -0,0 +1,99 @@
+package natsjs_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	nserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/open-micro/plugins/v5/events/eventstore"
+	"github.com/open-micro/plugins/v5/events/natsjs"
+	"github.com/stretchr/testify/assert"
+	"github.com/test-go/testify/require"
+)
+
+func TestEventStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	clusterName := "test-cluster"
+	natsAddr := getFreeLocalhostAddress()
+	natsPort, _ := strconv.Atoi(strings.Split(natsAddr, ":")[1])
+
+	go natsServer(ctx,
+		t,
+		&nserver.Options{
+			Host: strings.Split(natsAddr, ":")[0],
+			Port: natsPort,
+			Cluster: nserver.ClusterOpts{
+				Name: clusterName,
+			},
+		},
+	)
+
+	time.Sleep(1 * time.Second)
+
+	es, err := natsjs.NewEventStore(
+		natsjs.Address(natsAddr),
+		natsjs.ClusterID(clusterName),
+	)
+	require.NoError(t, err)
+	if err != nil {
+		return
+	}
+
+	newEvents := func(ids ...string) []*eventstore.Event {
+		events := make([]*eventstore.Event, len(ids))
+		for i, id := range ids {
+			events[i] = &eventstore.Event{ID: id, Stream: "order1", Type: "created", Timestamp: time.Now()}
+		}
+		return events
+	}
+
+	version, err := es.Append("order1", eventstore.NoStream, newEvents("a", "b", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+
+	// events of another stream interleave the sequences
+	_, err = es.Append("order2", eventstore.NoStream, []*eventstore.Event{{ID: "x", Stream: "order2"}})
+	require.NoError(t, err)
+
+	_, err = es.Append("order1", eventstore.NoStream, newEvents("d"))
+	assert.Equal(t, eventstore.ErrWrongVersion, err)
+
+	version, err = es.Append("order1", eventstore.Any, newEvents("d"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), version)
+
+	events, err := es.Read("order1", 2, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "c", events[0].ID)
+	assert.Equal(t, int64(3), events[0].Version)
+	assert.Equal(t, "d", events[1].ID)
+
+	events, err = es.Read("order1", 0, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "a", events[0].ID)
+
+	events, err = es.Read("order1", 4, 0)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// a batch failing at an event keeps the events published before it
+	version, err = es.Append("order1", 4, newEvents("e", "a", "f"))
+	assert.Error(t, err)
+	assert.Equal(t, int64(5), version)
+
+	events, err = es.Read("order1", 3, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "e", events[1].ID)
+
+	events, err = es.ReadAll("", 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 6)
+}
@@ -0,0 +1,327 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"go-micro.org/v5/events"
+	"go-micro.org/v5/logger"
+)
+
+const (
+	// ErrorMetadata is the metadata key of the reason an event was dead
+	// lettered.
+	ErrorMetadata = "Micro-Dead-Letter-Error"
+	// GroupMetadata is the metadata key of the group that dead lettered an
+	// event.
+	GroupMetadata = "Micro-Dead-Letter-Group"
+)
+
+var errConsumerClosed = errors.New("consumer closed")
+
+// consumer reads a stream as a member of a group.
+type consumer struct {
+	stream     *redisStream
+	topic      string
+	group      string
+	name       string
+	deadLetter string
+	options    events.ConsumeOptions
+	counters   *groupCounters
+
+	// guards sending on ch against closing it, nacked events are redelivered
+	// from their own goroutines
+	mu     sync.RWMutex
+	closed bool
+	ch     chan events.Event
+}
+
+func (c *consumer) run() {
+	defer func() {
+		logger.Infof("Deleting consumer %s %s %s", c.topic, c.group, c.name)
+		// try to clean up the consumer
+		if err := callWithRetry(func() error {
+			return c.stream.redisClient.XGroupDelConsumer(context.Background(), c.topic, c.group, c.name).Err()
+		}, 2); err != nil {
+			logger.Errorf("Error deleting consumer %s", err)
+		}
+
+		c.stream.release(c.counters)
+
+		c.mu.Lock()
+		c.closed = true
+		close(c.ch)
+		c.mu.Unlock()
+	}()
+
+	// sweep up any old pending messages
+	if err := c.claimPending(); err != nil {
+		logger.Errorf("Error claiming pending messages %s", err)
+		return
+	}
+	lastClaim := time.Now()
+
+	for {
+		// messages of consumers that died or stopped acking are picked up
+		// while running too, not only by new consumers
+		if time.Since(lastClaim) >= c.ackWait() {
+			if err := c.claimPending(); err != nil {
+				logger.Errorf("Error claiming pending messages %s", err)
+			}
+			lastClaim = time.Now()
+		}
+
+		res := c.stream.redisClient.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.name,
+			Streams:  []string{c.topic, ">"},
+			Block:    readGroupTimeout,
+		})
+		sl, err := res.Result()
+		if err != nil && err != redis.Nil {
+			logger.Errorf("Error reading from stream %s", err)
+			if !isTimeoutError(err) {
+				return
+			}
+			sleepWithJitter(2 * time.Second)
+			continue
+		}
+		if len(sl) == 0 || len(sl[0].Messages) == 0 {
+			// test the channel is still being read from
+			if err := c.send(events.Event{}); err != nil {
+				logger.Errorf("Timed out waiting for consumer")
+				return
+			}
+			continue
+		}
+
+		if err := c.process(sl[0].Messages, nil); err != nil {
+			logger.Errorf("Error processing message %s", err)
+			return
+		}
+	}
+}
+
+// ackWait is how long messages stay pending before they are claimed.
+func (c *consumer) ackWait() time.Duration {
+	if c.options.AckWait > 0 {
+		return c.options.AckWait
+	}
+	return pendingIdleTime
+}
+
+// claimPending claims the messages pending in the group for longer than the
+// ack wait and processes them.
+func (c *consumer) claimPending() error {
+	start := "-"
+	for {
+		var pendingCmd *redis.XPendingExtCmd
+		err := callWithRetry(func() error {
+			pendingCmd = c.stream.redisClient.XPendingExt(context.Background(), &redis.XPendingExtArgs{
+				Stream: c.topic,
+				Group:  c.group,
+				Idle:   c.ackWait(),
+				Start:  start,
+				End:    "+",
+				Count:  50,
+			})
+			return pendingCmd.Err()
+		}, 2)
+		if err != nil && err != redis.Nil {
+			return errors.Wrap(err, "Error finding pending messages")
+		}
+		pend := pendingCmd.Val()
+		if len(pend) == 0 {
+			return nil
+		}
+
+		pendingIDs := make([]string, len(pend))
+		// claiming is another delivery
+		deliveries := make(map[string]int64, len(pend))
+		for i, p := range pend {
+			pendingIDs[i] = p.ID
+			deliveries[p.ID] = p.RetryCount + 1
+		}
+		var claimCmd *redis.XMessageSliceCmd
+		err = callWithRetry(func() error {
+			claimCmd = c.stream.redisClient.XClaim(context.Background(), &redis.XClaimArgs{
+				Stream:   c.topic,
+				Group:    c.group,
+				Consumer: c.name,
+				MinIdle:  c.ackWait(),
+				Messages: pendingIDs,
+			})
+			return claimCmd.Err()
+		}, 2)
+		if err != nil {
+			return errors.Wrap(err, "Error claiming message")
+		}
+		msgs := claimCmd.Val()
+		atomic.AddUint64(&c.counters.claimed, uint64(len(msgs)))
+		if err := c.process(msgs, deliveries); err != nil {
+			return errors.Wrap(err, "Error reprocessing message")
+		}
+
+		if len(pendingIDs) < 50 {
+			return nil
+		}
+		start = incrementID(pendingIDs[49])
+	}
+}
+
+// redeliver claims a nacked message back and processes it again, its
+// delivery count in the pending entries list counting the attempts.
+func (c *consumer) redeliver(id string) {
+	ctx := context.Background()
+
+	msgs, err := c.stream.redisClient.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   c.topic,
+		Group:    c.group,
+		Consumer: c.name,
+		Messages: []string{id},
+	}).Result()
+	if err != nil {
+		logger.Errorf("Error claiming nacked message %s %s", id, err)
+		return
+	}
+	if len(msgs) == 0 {
+		// acked or trimmed meanwhile
+		return
+	}
+
+	pend, err := c.stream.redisClient.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: c.topic,
+		Group:  c.group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		logger.Errorf("Error finding delivery count of %s %s", id, err)
+		return
+	}
+	deliveries := map[string]int64{}
+	for _, p := range pend {
+		deliveries[p.ID] = p.RetryCount
+	}
+
+	if err := c.process(msgs, deliveries); err != nil && err != errConsumerClosed {
+		logger.Errorf("Error redelivering message %s", err)
+	}
+}
+
+// process sends the messages to the consumer, deliveries holds the delivery
+// counts of messages delivered before.
+func (c *consumer) process(msgs []redis.XMessage, deliveries map[string]int64) error {
+	for _, v := range msgs {
+		vid := v.ID
+		evBytes := v.Values["event"]
+		var ev events.Event
+		bStr, ok := evBytes.(string)
+		if !ok {
+			logger.Warnf("Failed to convert to bytes, dead lettering %s", vid)
+			c.deadLetterMessage(v, nil, "event is not a string")
+			continue
+		}
+		if err := json.Unmarshal([]byte(bStr), &ev); err != nil {
+			logger.Warnf("Failed to unmarshal event, dead lettering %s %s", err, vid)
+			c.deadLetterMessage(v, nil, fmt.Sprintf("failed to unmarshal event: %s", err))
+			continue
+		}
+
+		attempt, ok := deliveries[vid]
+		if !ok {
+			attempt = 1
+		}
+		// the first delivery is not a retry
+		if limit := c.options.RetryLimit; limit > 0 && attempt > int64(limit)+1 {
+			c.deadLetterMessage(v, &ev, fmt.Sprintf("retry limit of %d exceeded", limit))
+			continue
+		}
+
+		if !c.options.AutoAck {
+			ev.SetAckFunc(func() error {
+				return c.stream.redisClient.XAck(context.Background(), c.topic, c.group, vid).Err()
+			})
+			ev.SetNackFunc(func() error {
+				// the message stays pending and is claimed back, which
+				// counts the delivery
+				go c.redeliver(vid)
+				return nil
+			})
+		}
+		if err := c.send(ev); err != nil {
+			// If event is not consumed from channel after 10 secs we assume that something is
+			// wrong with the consumer so we bomb out
+			return err
+		}
+
+		if !c.options.AutoAck {
+			continue
+		}
+		// TODO check for error
+		c.stream.redisClient.XAck(context.Background(), c.topic, c.group, vid)
+	}
+	return nil
+}
+
+// send sends an event to the consumer channel unless it is closed.
+func (c *consumer) send(ev events.Event) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return errConsumerClosed
+	}
+	select {
+	case c.ch <- ev:
+		return nil
+	case <-time.After(consumerTimeout):
+		return errors.Errorf("timed out waiting for consumer")
+	}
+}
+
+// deadLetterMessage moves a message to the dead letter stream with the reason
+// it failed. The event keeps its topic, the reason and the group are added
+// to its metadata and to the message.
+func (c *consumer) deadLetterMessage(msg redis.XMessage, ev *events.Event, reason string) {
+	values := make(map[string]interface{}, len(msg.Values)+4)
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+	if ev != nil {
+		if ev.Metadata == nil {
+			ev.Metadata = map[string]string{}
+		}
+		ev.Metadata[ErrorMetadata] = reason
+		ev.Metadata[GroupMetadata] = c.group
+		if b, err := json.Marshal(ev); err == nil {
+			values["event"] = string(b)
+		}
+	}
+	values["error"] = reason
+	values["topic"] = c.topic
+	values["group"] = c.group
+	values["id"] = msg.ID
+
+	ctx := context.Background()
+	if err := c.stream.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: c.deadLetter,
+		Values: values,
+	}).Err(); err != nil {
+		// left pending, the next claim tries again
+		logger.Errorf("Error dead lettering %s %s", msg.ID, err)
+		return
+	}
+	atomic.AddUint64(&c.counters.deadLettered, 1)
+
+	if err := c.stream.redisClient.XAck(ctx, c.topic, c.group, msg.ID).Err(); err != nil {
+		logger.Errorf("Error acking dead lettered %s %s", msg.ID, err)
+	}
+}
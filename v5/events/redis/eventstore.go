@@ -0,0 +1,156 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/open-micro/plugins/v5/events/eventstore"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// EventStorePrefix of the aggregate stream keys, the hash tag keeps all
+	// of them in one slot so appends are atomic on a cluster.
+	EventStorePrefix = "{eventstore}:stream:"
+	// EventStoreAllKey is the stream of the events of all aggregates.
+	EventStoreAllKey = "{eventstore}:all"
+)
+
+// appendScript checks the version of the aggregate stream and appends the
+// events to it and to the stream of all events. Entry ids of aggregate
+// streams are 0-<version>.
+var appendScript = redis.NewScript(`
+local version = redis.call('XLEN', KEYS[1])
+local expected = tonumber(ARGV[1])
+if expected >= 0 and version ~= expected then
+	return {0, version}
+end
+for i = 3, #ARGV do
+	version = version + 1
+	redis.call('XADD', KEYS[1], '0-' .. version, 'event', ARGV[i])
+	redis.call('XADD', KEYS[2], '*', 'stream', ARGV[2], 'version', version, 'event', ARGV[i])
+end
+return {1, version}
+`)
+
+type redisEventStore struct {
+	redisClient redis.UniversalClient
+}
+
+// NewEventStore returns an eventstore.Backend over Redis Streams.
+func NewEventStore(opts ...Option) (eventstore.Backend, error) {
+	options := Options{}
+	for _, o := range opts {
+		o(&options)
+	}
+	return &redisEventStore{redisClient: options.newUniversalClient()}, nil
+}
+
+func (r *redisEventStore) Append(stream string, expected int64, events []*eventstore.Event) (int64, error) {
+	args := make([]interface{}, 0, len(events)+2)
+	args = append(args, expected, stream)
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return 0, errors.Wrap(err, "Error encoding event")
+		}
+		args = append(args, string(b))
+	}
+
+	res, err := appendScript.Run(context.Background(), r.redisClient, []string{EventStorePrefix + stream, EventStoreAllKey}, args...).Slice()
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 2 {
+		return 0, fmt.Errorf("unexpected append result %v", res)
+	}
+	ok, _ := res[0].(int64)
+	version, _ := res[1].(int64)
+	if ok == 0 {
+		return version, eventstore.ErrWrongVersion
+	}
+	return version, nil
+}
+
+func (r *redisEventStore) Read(stream string, from int64, limit int) ([]*eventstore.Event, error) {
+	if from < 0 {
+		from = 0
+	}
+	ctx := context.Background()
+	key := EventStorePrefix + stream
+	start := fmt.Sprintf("0-%d", from+1)
+
+	var msgs []redis.XMessage
+	var err error
+	if limit > 0 {
+		msgs, err = r.redisClient.XRangeN(ctx, key, start, "+", int64(limit)).Result()
+	} else {
+		msgs, err = r.redisClient.XRange(ctx, key, start, "+").Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*eventstore.Event, 0, len(msgs))
+	for _, m := range msgs {
+		e, err := decodeStoredEvent(m)
+		if err != nil {
+			return nil, err
+		}
+		if e.Version, err = strconv.ParseInt(strings.TrimPrefix(m.ID, "0-"), 10, 64); err != nil {
+			return nil, errors.Wrapf(err, "Invalid event id %s", m.ID)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (r *redisEventStore) ReadAll(after string, limit int) ([]*eventstore.Event, error) {
+	start := "-"
+	if len(after) > 0 {
+		start = incrementID(after)
+	}
+	ctx := context.Background()
+
+	var msgs []redis.XMessage
+	var err error
+	if limit > 0 {
+		msgs, err = r.redisClient.XRangeN(ctx, EventStoreAllKey, start, "+", int64(limit)).Result()
+	} else {
+		msgs, err = r.redisClient.XRange(ctx, EventStoreAllKey, start, "+").Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*eventstore.Event, 0, len(msgs))
+	for _, m := range msgs {
+		e, err := decodeStoredEvent(m)
+		if err != nil {
+			return nil, err
+		}
+		v, _ := m.Values["version"].(string)
+		if e.Version, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, errors.Wrapf(err, "Invalid event version %s", v)
+		}
+		e.Position = m.ID
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func decodeStoredEvent(m redis.XMessage) (*eventstore.Event, error) {
+	s, ok := m.Values["event"].(string)
+	if !ok {
+		return nil, errors.Errorf("Missing event in %s", m.ID)
+	}
+	e := &eventstore.Event{}
+	if err := json.Unmarshal([]byte(s), e); err != nil {
+		return nil, errors.Wrap(err, "Error decoding event")
+	}
+	return e, nil
+}
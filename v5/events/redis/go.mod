@@ -4,6 +4,7 @@ go 1.19
 
 require (
 	github.com/google/uuid v1.6.0
+	github.com/open-micro/plugins/v5/events/eventstore v0.0.0-00010101000000-000000000000
 	github.com/pkg/errors v0.9.1
 	github.com/redis/go-redis/v9 v9.3.0
 	github.com/stretchr/testify v1.9.0
@@ -31,3 +32,5 @@ require (
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/events/eventstore => ../eventstore
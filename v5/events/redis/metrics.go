@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+type groupKey struct {
+	topic string
+	group string
+}
+
+type groupCounters struct {
+	claimed      uint64
+	deadLettered uint64
+
+	key groupKey
+	// consumers of the group in this process, guarded by the stream
+	refs int
+}
+
+// GroupMetrics are the metrics of a consumer group of a topic. Pending and
+// lag are read from redis and cover every consumer of the group, claimed and
+// dead lettered are counted by this process while it consumes the group.
+type GroupMetrics struct {
+	Topic     string
+	Group     string
+	Consumers int64
+	// Pending is the number of messages delivered but not acked.
+	Pending int64
+	// Lag is the number of messages not yet delivered to the group, it
+	// needs redis 7.
+	Lag int64
+	// Claimed is the number of messages claimed after they were left
+	// pending for longer than the ack wait, e.g. by a consumer that died.
+	Claimed uint64
+	// DeadLettered is the number of messages moved to the dead letter
+	// stream.
+	DeadLettered uint64
+}
+
+func (r *redisStream) counters(topic, group string) *groupCounters {
+	r.Lock()
+	defer r.Unlock()
+
+	k := groupKey{topic, group}
+	c, ok := r.groups[k]
+	if !ok {
+		c = &groupCounters{key: k}
+		r.groups[k] = c
+	}
+	c.refs++
+	return c
+}
+
+// release drops the counters once the last consumer of the group exited, so
+// groups no longer consumed, such as those of consumers without a group, are
+// not reported.
+func (r *redisStream) release(c *groupCounters) {
+	r.Lock()
+	defer r.Unlock()
+
+	c.refs--
+	if c.refs <= 0 && r.groups[c.key] == c {
+		delete(r.groups, c.key)
+	}
+}
+
+// Metrics returns the metrics of the groups consumed by the stream. Groups
+// whose stream or group no longer exists in redis are skipped.
+func (r *redisStream) Metrics(ctx context.Context) ([]GroupMetrics, error) {
+	r.RLock()
+	groups := make(map[groupKey]*groupCounters, len(r.groups))
+	for k, c := range r.groups {
+		groups[k] = c
+	}
+	r.RUnlock()
+
+	// one call per topic covers its groups
+	infos := map[string]map[string]GroupMetrics{}
+	var metrics []GroupMetrics
+	for k, c := range groups {
+		info, ok := infos[k.topic]
+		if !ok {
+			res, err := r.redisClient.XInfoGroups(ctx, fmt.Sprintf("stream-%s", k.topic)).Result()
+			if err != nil && strings.Contains(err.Error(), "no such key") {
+				// deleted, its groups are skipped
+				res = nil
+			} else if err != nil {
+				return nil, err
+			}
+			info = make(map[string]GroupMetrics, len(res))
+			for _, g := range res {
+				info[g.Name] = GroupMetrics{
+					Consumers: g.Consumers,
+					Pending:   g.Pending,
+					Lag:       g.Lag,
+				}
+			}
+			infos[k.topic] = info
+		}
+
+		m, ok := info[k.group]
+		if !ok {
+			continue
+		}
+		m.Topic = k.topic
+		m.Group = k.group
+		m.Claimed = atomic.LoadUint64(&c.claimed)
+		m.DeadLettered = atomic.LoadUint64(&c.deadLettered)
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
@@ -6,6 +6,10 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// DefaultDeadLetterSuffix is appended to a topic to name its dead letter
+// topic unless DeadLetterTopic is set.
+var DefaultDeadLetterSuffix = ".dead-letter"
+
 // Options which are used to configure the redis stream.
 type Options struct {
 	Address   string
@@ -13,6 +17,10 @@ type Options struct {
 	Password  string
 	TLSConfig *tls.Config
 
+	// DeadLetterTopic receives the events of every topic that exceed their
+	// retry limit or can't be decoded.
+	DeadLetterTopic string
+
 	RedisOptions *redis.UniversalOptions
 }
 
@@ -48,6 +56,14 @@ func TLSConfig(tlsConfig *tls.Config) Option {
 	}
 }
 
+// DeadLetterTopic sets the topic events exceeding their retry limit are
+// moved to, instead of one per topic.
+func DeadLetterTopic(topic string) Option {
+	return func(o *Options) {
+		o.DeadLetterTopic = topic
+	}
+}
+
 // WithRedisOptions sets advanced options for redis.
 func WithRedisOptions(options *redis.UniversalOptions) Option {
 	return func(o *Options) {
@@ -123,6 +123,61 @@ func TestStream(t *testing.T) {
 		}
 	})
 
+	t.Run("DeadLetter", func(t *testing.T) {
+		topic := "foodead"
+		ctx := context.Background()
+		rc := s.(*redisStream).redisClient
+		assert.NoError(t, rc.Del(ctx, "stream-"+topic, "stream-"+topic+DefaultDeadLetterSuffix).Err())
+
+		dead, err := s.Consume(topic + DefaultDeadLetterSuffix)
+		assert.NoError(t, err)
+		ch, err := s.Consume(topic, events.WithGroup("deadgroup"), events.WithRetryLimit(1))
+		assert.NoError(t, err)
+
+		next := func(ch <-chan events.Event) events.Event {
+			for {
+				select {
+				case <-time.After(5 * time.Second):
+					t.Fatalf("Failed to receive message within the time limit")
+				case ev := <-ch:
+					// skip the liveness checks
+					if len(ev.ID) > 0 {
+						return ev
+					}
+				}
+			}
+		}
+
+		assert.NoError(t, s.Publish(topic, testObj{One: "dead"}))
+		// delivered and retried once
+		for i := 0; i < 2; i++ {
+			ev := next(ch)
+			assert.NoError(t, ev.Nack())
+		}
+
+		ev := next(dead)
+		assert.Equal(t, topic, ev.Topic)
+		assert.Equal(t, "retry limit of 1 exceeded", ev.Metadata[ErrorMetadata])
+		assert.Equal(t, "deadgroup", ev.Metadata[GroupMetadata])
+
+		// acked after it is dead lettered
+		time.Sleep(100 * time.Millisecond)
+		metrics, err := s.(*redisStream).Metrics(ctx)
+		assert.NoError(t, err)
+		found := false
+		for _, m := range metrics {
+			if m.Topic != topic {
+				continue
+			}
+			found = true
+			assert.Equal(t, "deadgroup", m.Group)
+			assert.Equal(t, int64(0), m.Pending)
+			assert.Equal(t, uint64(0), m.Claimed)
+			assert.Equal(t, uint64(1), m.DeadLettered)
+		}
+		assert.True(t, found, "Missing metrics of %s", topic)
+	})
+
 	t.Run("WithGroup", func(t *testing.T) {
 		topic := "foogroup"
 		assert.NoError(t, s.(*redisStream).redisClient.XTrimMaxLen(context.Background(), "stream-"+topic, 0).Err())
@@ -253,3 +308,16 @@ func TestJanitor(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, cons, 1)
 }
+
+func TestCounters(t *testing.T) {
+	r := &redisStream{groups: map[groupKey]*groupCounters{}}
+
+	a := r.counters("foo", "group")
+	b := r.counters("foo", "group")
+	assert.Equal(t, a, b, "Consumers of a group should share counters")
+
+	r.release(a)
+	assert.Len(t, r.groups, 1, "Counters should be kept while the group is consumed")
+	r.release(b)
+	assert.Empty(t, r.groups, "Counters should be dropped once the group is no longer consumed")
+}
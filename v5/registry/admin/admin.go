@@ -0,0 +1,274 @@
+// Package admin serves an http api and page over a registry
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+var (
+	// DefaultHeartbeat is the interval of the comments keeping watch
+	// streams open.
+	DefaultHeartbeat = 15 * time.Second
+)
+
+type handler struct {
+	registry registry.Registry
+	options  Options
+}
+
+// NewHandler returns a handler serving the services of a registry:
+//
+//	GET    /                                 the catalog page
+//	GET    /services                         the services and their versions
+//	GET    /services/{name}                  the versions of a service
+//	DELETE /services/{name}/nodes/{id}       deregisters a node, if allowed
+//	GET    /watch?service={name}             the watch results, as server sent events
+//
+// Mount it under a prefix with http.StripPrefix.
+func NewHandler(r registry.Registry, opts ...Option) http.Handler {
+	options := Options{
+		Heartbeat: DefaultHeartbeat,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Heartbeat <= 0 {
+		options.Heartbeat = DefaultHeartbeat
+	}
+
+	return &handler{registry: r, options: options}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		h.page(w, r)
+	case parts[0] == "services" && len(parts) == 1:
+		h.listServices(w, r)
+	case parts[0] == "services" && len(parts) == 2:
+		h.getService(w, r, parts[1])
+	case parts[0] == "services" && len(parts) == 4 && parts[2] == "nodes":
+		h.deregister(w, r, parts[1], parts[3])
+	case parts[0] == "watch" && len(parts) == 1:
+		h.watch(w, r)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method || (method == http.MethodGet && r.Method == http.MethodHead) {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	//nolint:errcheck
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func registryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, registry.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusBadGateway, err)
+}
+
+func (h *handler) page(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	//nolint:errcheck
+	w.Write([]byte(page))
+}
+
+// service is a service and its versions.
+type service struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+}
+
+func (h *handler) listServices(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	list, err := h.registry.ListServices()
+	if err != nil {
+		registryError(w, err)
+		return
+	}
+
+	byName := make(map[string]*service)
+	for _, s := range list {
+		svc, ok := byName[s.Name]
+		if !ok {
+			svc = &service{Name: s.Name, Versions: []string{}}
+			byName[s.Name] = svc
+		}
+		if len(s.Version) > 0 {
+			svc.Versions = append(svc.Versions, s.Version)
+		}
+	}
+
+	services := make([]*service, 0, len(byName))
+	for _, s := range byName {
+		sort.Strings(s.Versions)
+		services = append(services, s)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, services)
+}
+
+func (h *handler) getService(w http.ResponseWriter, r *http.Request, name string) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	services, err := h.registry.GetService(name)
+	if err != nil {
+		registryError(w, err)
+		return
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Version < services[j].Version
+	})
+
+	writeJSON(w, http.StatusOK, services)
+}
+
+// deregister deregisters a node of every version registering it.
+func (h *handler) deregister(w http.ResponseWriter, r *http.Request, name, id string) {
+	if !allow(w, r, http.MethodDelete) {
+		return
+	}
+	if !h.options.Deregister {
+		writeError(w, http.StatusForbidden, errors.New("deregistering is not allowed"))
+		return
+	}
+
+	services, err := h.registry.GetService(name)
+	if err != nil {
+		registryError(w, err)
+		return
+	}
+
+	var found bool
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			if n.Id != id {
+				continue
+			}
+
+			found = true
+			svc := *s
+			svc.Nodes = []*registry.Node{n}
+			if err := h.registry.Deregister(&svc); err != nil {
+				registryError(w, err)
+				return
+			}
+		}
+	}
+
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("node %s of %s not found", id, name))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// watch streams the results of a watcher as server sent events named by
+// their action.
+func (h *handler) watch(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	var opts []registry.WatchOption
+	if name := r.URL.Query().Get("service"); len(name) > 0 {
+		opts = append(opts, registry.WatchService(name))
+	}
+
+	watcher, err := h.registry.Watch(opts...)
+	if err != nil {
+		registryError(w, err)
+		return
+	}
+	defer watcher.Stop()
+
+	results := make(chan *registry.Result)
+	done := make(chan error, 1)
+	go func() {
+		for {
+			res, err := watcher.Next()
+			if err != nil {
+				done <- err
+				return
+			}
+			select {
+			case results <- res:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.options.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case err := <-done:
+			fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+			flusher.Flush()
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case res := <-results:
+			b, err := json.Marshal(res.Service)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", res.Action, b)
+		}
+		flusher.Flush()
+	}
+}
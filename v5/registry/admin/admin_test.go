@@ -0,0 +1,160 @@
+package admin
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+var testService = &registry.Service{
+	Name:    "foo",
+	Version: "1.0.0",
+	Nodes: []*registry.Node{
+		{Id: "foo-1", Address: "10.0.0.1:8080", Metadata: map[string]string{"protocol": "grpc"}},
+		{Id: "foo-2", Address: "10.0.0.2:8080", Metadata: map[string]string{"protocol": "grpc"}},
+	},
+	Endpoints: []*registry.Endpoint{
+		{
+			Name:     "Foo.Call",
+			Request:  &registry.Value{Name: "Request", Type: "Request", Values: []*registry.Value{{Name: "name", Type: "string"}}},
+			Response: &registry.Value{Name: "Response", Type: "Response"},
+		},
+	},
+}
+
+func setup(t *testing.T, opts ...Option) (registry.Registry, *httptest.Server) {
+	t.Helper()
+
+	r := registry.NewMemoryRegistry()
+	if err := r.Register(testService); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(NewHandler(r, opts...))
+	t.Cleanup(srv.Close)
+
+	return r, srv
+}
+
+func do(t *testing.T, method, url string, v interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(rsp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rsp.StatusCode
+}
+
+func TestServices(t *testing.T) {
+	_, srv := setup(t)
+
+	var services []*service
+	if code := do(t, http.MethodGet, srv.URL+"/services", &services); code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", code)
+	}
+	if len(services) != 1 || services[0].Name != "foo" || services[0].Versions[0] != "1.0.0" {
+		t.Fatalf("unexpected services %+v", services)
+	}
+
+	var versions []*registry.Service
+	if code := do(t, http.MethodGet, srv.URL+"/services/foo", &versions); code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", code)
+	}
+	if len(versions) != 1 || len(versions[0].Nodes) != 2 {
+		t.Fatalf("unexpected versions %+v", versions)
+	}
+	ep := versions[0].Endpoints[0]
+	if ep.Request.Values[0].Name != "name" || ep.Response.Type != "Response" {
+		t.Fatalf("unexpected endpoint %+v", ep)
+	}
+
+	if code := do(t, http.MethodGet, srv.URL+"/services/bar", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", code)
+	}
+	if code := do(t, http.MethodPost, srv.URL+"/services", nil); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", code)
+	}
+}
+
+func TestDeregister(t *testing.T) {
+	_, srv := setup(t)
+	if code := do(t, http.MethodDelete, srv.URL+"/services/foo/nodes/foo-1", nil); code != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", code)
+	}
+
+	r, srv := setup(t, AllowDeregister())
+	if code := do(t, http.MethodDelete, srv.URL+"/services/foo/nodes/foo-3", nil); code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", code)
+	}
+	if code := do(t, http.MethodDelete, srv.URL+"/services/foo/nodes/foo-1", nil); code != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", code)
+	}
+
+	services, err := r.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services[0].Nodes) != 1 || services[0].Nodes[0].Id != "foo-2" {
+		t.Fatalf("expected foo-2 left got %+v", services[0].Nodes)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	r, srv := setup(t)
+
+	rsp, err := http.Get(srv.URL + "/watch?service=bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	if ct := rsp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream got %s", ct)
+	}
+
+	go func() {
+		// the watcher is created before the headers are sent
+		time.Sleep(10 * time.Millisecond)
+		//nolint:errcheck
+		r.Register(&registry.Service{Name: "bar", Version: "1", Nodes: []*registry.Node{{Id: "bar-1"}}})
+	}()
+
+	scanner := bufio.NewScanner(rsp.Body)
+	var event, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			event = strings.TrimPrefix(line, "event: ")
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+			break
+		}
+	}
+
+	var svc registry.Service
+	if err := json.Unmarshal([]byte(data), &svc); err != nil {
+		t.Fatal(err)
+	}
+	if event != "update" || svc.Name != "bar" {
+		t.Fatalf("expected an update of bar got %s %+v", event, svc)
+	}
+}
@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"time"
+)
+
+// Options of the handler.
+type Options struct {
+	// Deregister allows deregistering nodes.
+	Deregister bool
+	// Heartbeat is the interval of the comments keeping watch streams open.
+	Heartbeat time.Duration
+}
+
+// Option sets an option of the handler.
+type Option func(o *Options)
+
+// AllowDeregister allows deregistering nodes, eg: dead ones a registry
+// without health checks keeps.
+func AllowDeregister() Option {
+	return func(o *Options) {
+		o.Deregister = true
+	}
+}
+
+// Heartbeat sets the interval of the comments keeping watch streams open
+// through proxies.
+func Heartbeat(d time.Duration) Option {
+	return func(o *Options) {
+		o.Heartbeat = d
+	}
+}
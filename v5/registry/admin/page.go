@@ -0,0 +1,121 @@
+package admin
+
+// page lists the services, their nodes and endpoints, reloading them as
+// watch events arrive.
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Registry</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+details { margin: .5em 0; }
+summary { cursor: pointer; font-weight: bold; }
+table { border-collapse: collapse; margin: .5em 0 1em; }
+td, th { border: 1px solid #ddd; padding: .3em .6em; text-align: left; vertical-align: top; font-size: .9em; }
+pre { margin: 0; }
+button { font-size: .8em; }
+.muted { color: #888; }
+</style>
+</head>
+<body>
+<h1>Registry <span id="status" class="muted"></span></h1>
+<div id="services"></div>
+<script>
+const root = document.getElementById('services');
+const status = document.getElementById('status');
+const open = new Set();
+
+function el(tag, text) {
+  const e = document.createElement(tag);
+  if (text !== undefined) e.textContent = text;
+  return e;
+}
+
+function row(table, cells) {
+  const tr = el('tr');
+  for (const c of cells) {
+    const td = el('td');
+    if (c instanceof Node) td.appendChild(c); else td.textContent = c;
+    tr.appendChild(td);
+  }
+  table.appendChild(tr);
+}
+
+function meta(m) {
+  return Object.entries(m || {}).map(([k, v]) => k + '=' + v).join('\n');
+}
+
+function schema(v) {
+  if (!v) return '';
+  const f = (v, d) => '  '.repeat(d) + v.name + ' ' + v.type + '\n' +
+    (v.values || []).map(c => f(c, d + 1)).join('');
+  return f(v, 0);
+}
+
+async function load(name, body) {
+  const rsp = await fetch('services/' + encodeURIComponent(name));
+  const versions = await rsp.json();
+  body.textContent = '';
+  if (!rsp.ok) { body.textContent = versions.error; return; }
+  for (const s of versions) {
+    body.appendChild(el('h3', 'version ' + (s.version || '-')));
+    const nodes = el('table');
+    row(nodes, ['id', 'address', 'metadata', '']);
+    for (const n of s.nodes || []) {
+      const del = el('button', 'deregister');
+      del.onclick = async () => {
+        if (!confirm('Deregister ' + n.id + '?')) return;
+        const r = await fetch('services/' + encodeURIComponent(name) + '/nodes/' + encodeURIComponent(n.id), {method: 'DELETE'});
+        if (!r.ok) alert((await r.json()).error);
+      };
+      const pre = el('pre', meta(n.metadata));
+      row(nodes, [n.id, n.address, pre, del]);
+    }
+    body.appendChild(nodes);
+    if ((s.endpoints || []).length) {
+      const eps = el('table');
+      row(eps, ['endpoint', 'request', 'response', 'metadata']);
+      for (const e of s.endpoints) {
+        row(eps, [e.name, el('pre', schema(e.request)), el('pre', schema(e.response)), el('pre', meta(e.metadata))]);
+      }
+      body.appendChild(eps);
+    }
+  }
+}
+
+async function refresh() {
+  const rsp = await fetch('services');
+  const services = await rsp.json();
+  if (!rsp.ok) { status.textContent = services.error; return; }
+  root.textContent = '';
+  for (const s of services) {
+    const d = el('details');
+    const body = el('div', 'loading');
+    d.appendChild(el('summary', s.name + ' ' + s.versions.join(', ')));
+    d.appendChild(body);
+    d.ontoggle = () => {
+      if (d.open) { open.add(s.name); load(s.name, body); } else { open.delete(s.name); }
+    };
+    d.open = open.has(s.name);
+    root.appendChild(d);
+  }
+}
+
+let timer;
+const events = new EventSource('watch');
+for (const action of ['create', 'update', 'delete']) {
+  events.addEventListener(action, () => {
+    clearTimeout(timer);
+    timer = setTimeout(refresh, 500);
+  });
+}
+events.onopen = () => { status.textContent = ''; };
+events.onerror = () => { status.textContent = '(disconnected)'; };
+
+refresh();
+</script>
+</body>
+</html>
+`
@@ -4,9 +4,73 @@ import (
 	"time"
 
 	"go-micro.org/v5/registry/cache"
+	"go-micro.org/v5/store"
 )
 
 // WithTTL sets the cache TTL.
 func WithTTL(t time.Duration) cache.Option {
 	return cache.WithTTL(t)
 }
+
+// Options of the snapshot cache.
+type Options struct {
+	// TTL is how long services are served without asking the registry.
+	TTL time.Duration
+	// MaxStaleness bounds the age of the services served while the
+	// registry fails, DefaultStaleTTLs TTLs by default. A negative max
+	// staleness serves them however old.
+	MaxStaleness time.Duration
+	// Store snapshots are written to, under Key.
+	Store store.Store
+	Key   string
+	// Path of the file snapshots are written to, unless Store is set.
+	Path string
+	// Interval between snapshots.
+	Interval time.Duration
+}
+
+// Option sets an option of the snapshot cache.
+type Option func(o *Options)
+
+// TTL sets how long services are served without asking the registry.
+func TTL(t time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = t
+	}
+}
+
+// MaxStaleness bounds the age of the services served while the registry
+// fails, negative durations serve them however old.
+func MaxStaleness(d time.Duration) Option {
+	return func(o *Options) {
+		o.MaxStaleness = d
+	}
+}
+
+// Store writes snapshots to a store.
+func Store(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Key sets the key of the snapshots in the store.
+func Key(k string) Option {
+	return func(o *Options) {
+		o.Key = k
+	}
+}
+
+// Path writes snapshots to a file.
+func Path(p string) Option {
+	return func(o *Options) {
+		o.Path = p
+	}
+}
+
+// Interval sets how often changed services are written.
+func Interval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
@@ -0,0 +1,365 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "go-micro.org/v5/logger"
+	"go-micro.org/v5/registry"
+	"go-micro.org/v5/registry/cache"
+	"go-micro.org/v5/store"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// DefaultSnapshotKey is the key snapshots are written to in a store.
+	DefaultSnapshotKey = "micro/registry/cache"
+	// DefaultSnapshotInterval is how often changed services are written.
+	DefaultSnapshotInterval = time.Minute
+	// DefaultStaleTTLs is the max staleness in TTLs when none is set.
+	DefaultStaleTTLs = 10
+)
+
+// Stats are the counters of a snapshot cache.
+type Stats struct {
+	// Hits are reads served from the cache.
+	Hits uint64
+	// Misses are reads served by the registry.
+	Misses uint64
+	// Stale are reads served from expired entries as the registry failed.
+	Stale uint64
+	// Errors are reads failing, with the registry down and no entry fresh
+	// enough to serve.
+	Errors uint64
+}
+
+// NewSnapshotCache returns a cache that periodically writes the services it
+// holds to disk or a store, and loads them on start. Should the registry fail,
+// expired services are served until they are older than the max staleness, so
+// a service started while the registry is down can still resolve the
+// services it resolved before.
+//
+// Services are cached by name only, lookups with GetOptions, which may select
+// other services of the name, go to the registry.
+func NewSnapshotCache(r registry.Registry, opts ...Option) cache.Cache {
+	options := Options{
+		TTL:      time.Minute,
+		Key:      DefaultSnapshotKey,
+		Interval: DefaultSnapshotInterval,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.MaxStaleness == 0 {
+		options.MaxStaleness = time.Duration(DefaultStaleTTLs) * options.TTL
+	}
+
+	c := &snapshotCache{
+		Registry: r,
+		opts:     options,
+		entries:  map[string]*entry{},
+		exit:     make(chan struct{}),
+	}
+	if err := c.load(); err != nil {
+		log.Warnf("[cache] Error loading snapshot: %v", err)
+	}
+	if options.Interval > 0 && (options.Store != nil || len(options.Path) > 0) {
+		go c.run()
+	}
+	return c
+}
+
+type entry struct {
+	Services []*registry.Service `json:"services"`
+	Updated  time.Time           `json:"updated"`
+	// a watch reported a change, fetch again but serve if that fails
+	expired bool
+}
+
+type snapshotCache struct {
+	registry.Registry
+	opts Options
+
+	hits   uint64
+	misses uint64
+	stale  uint64
+	errors uint64
+
+	sync.RWMutex
+	entries  map[string]*entry
+	dirty    bool
+	watching bool
+	sg       singleflight.Group
+	exit     chan struct{}
+	once     sync.Once
+}
+
+func copyServices(svcs []*registry.Service) []*registry.Service {
+	cp := make([]*registry.Service, len(svcs))
+	for i, s := range svcs {
+		c := *s
+		c.Nodes = make([]*registry.Node, len(s.Nodes))
+		copy(c.Nodes, s.Nodes)
+		cp[i] = &c
+	}
+	return cp
+}
+
+func (c *snapshotCache) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	if len(opts) > 0 {
+		return c.Registry.GetService(name, opts...)
+	}
+
+	c.RLock()
+	e, ok := c.entries[name]
+	if ok && !e.expired && time.Since(e.Updated) < c.opts.TTL {
+		svcs := copyServices(e.Services)
+		c.RUnlock()
+		atomic.AddUint64(&c.hits, 1)
+		return svcs, nil
+	}
+	if !c.watching {
+		c.RUnlock()
+		c.startWatch()
+	} else {
+		c.RUnlock()
+	}
+
+	val, err, _ := c.sg.Do(name, func() (interface{}, error) {
+		return c.Registry.GetService(name)
+	})
+	if err == nil {
+		svcs := val.([]*registry.Service)
+		c.Lock()
+		c.entries[name] = &entry{Services: copyServices(svcs), Updated: time.Now()}
+		c.dirty = true
+		c.Unlock()
+		atomic.AddUint64(&c.misses, 1)
+		return copyServices(svcs), nil
+	}
+
+	if err == registry.ErrNotFound {
+		c.Lock()
+		if _, ok := c.entries[name]; ok {
+			delete(c.entries, name)
+			c.dirty = true
+		}
+		c.Unlock()
+		return nil, err
+	}
+
+	// serve what we had, as long as it's not too old
+	if ok && (c.opts.MaxStaleness <= 0 || time.Since(e.Updated) < c.opts.MaxStaleness) {
+		atomic.AddUint64(&c.stale, 1)
+		c.RLock()
+		svcs := copyServices(e.Services)
+		c.RUnlock()
+		return svcs, nil
+	}
+	atomic.AddUint64(&c.errors, 1)
+	return nil, err
+}
+
+func (c *snapshotCache) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	svcs, err := c.Registry.ListServices(opts...)
+	if err == nil {
+		return svcs, nil
+	}
+
+	// list the services we have fresh enough entries of
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, e := range c.entries {
+		if c.opts.MaxStaleness > 0 && time.Since(e.Updated) >= c.opts.MaxStaleness {
+			continue
+		}
+		for _, s := range e.Services {
+			svcs = append(svcs, &registry.Service{Name: s.Name, Version: s.Version})
+		}
+	}
+	if len(svcs) == 0 {
+		return nil, err
+	}
+	return svcs, nil
+}
+
+// Stats returns the counters of the cache.
+func (c *snapshotCache) Stats() Stats {
+	return Stats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+		Stale:  atomic.LoadUint64(&c.stale),
+		Errors: atomic.LoadUint64(&c.errors),
+	}
+}
+
+// startWatch expires entries as the registry reports changes to them.
+func (c *snapshotCache) startWatch() {
+	c.Lock()
+	defer c.Unlock()
+	if c.watching {
+		return
+	}
+	c.watching = true
+	go c.watch()
+}
+
+func (c *snapshotCache) watch() {
+	backoff := time.Second
+	for {
+		w, err := c.Registry.Watch()
+		if err == nil {
+			backoff = time.Second
+			stop := make(chan struct{})
+			go func() {
+				select {
+				case <-c.exit:
+					w.Stop()
+				case <-stop:
+				}
+			}()
+
+			for {
+				res, err := w.Next()
+				if err != nil {
+					break
+				}
+				if res.Service == nil {
+					continue
+				}
+				c.Lock()
+				if e, ok := c.entries[res.Service.Name]; ok {
+					e.expired = true
+				}
+				c.Unlock()
+			}
+			close(stop)
+			w.Stop()
+		}
+
+		// changes may have been missed
+		c.Lock()
+		for _, e := range c.entries {
+			e.expired = true
+		}
+		c.Unlock()
+
+		select {
+		case <-c.exit:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > time.Minute {
+			backoff = time.Minute
+		}
+	}
+}
+
+func (c *snapshotCache) run() {
+	t := time.NewTicker(c.opts.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.exit:
+			return
+		case <-t.C:
+			if err := c.save(); err != nil {
+				log.Warnf("[cache] Error writing snapshot: %v", err)
+			}
+		}
+	}
+}
+
+// save writes the entries if they changed since the last snapshot.
+func (c *snapshotCache) save() error {
+	c.Lock()
+	if !c.dirty {
+		c.Unlock()
+		return nil
+	}
+	b, err := json.Marshal(c.entries)
+	c.dirty = false
+	c.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if c.opts.Store != nil {
+		return c.opts.Store.Write(&store.Record{Key: c.opts.Key, Value: b})
+	}
+
+	// write and rename so a crash can't leave half a snapshot
+	tmp := c.opts.Path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(c.opts.Path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.opts.Path)
+}
+
+// load reads the last snapshot, its entries expired so they are fetched again
+// but served should the registry fail.
+func (c *snapshotCache) load() error {
+	var b []byte
+	switch {
+	case c.opts.Store != nil:
+		recs, err := c.opts.Store.Read(c.opts.Key)
+		if err == store.ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			return nil
+		}
+		b = recs[0].Value
+	case len(c.opts.Path) > 0:
+		var err error
+		b, err = os.ReadFile(c.opts.Path)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	entries := map[string]*entry{}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		e.expired = true
+	}
+
+	c.Lock()
+	c.entries = entries
+	c.Unlock()
+	return nil
+}
+
+// Stop stops watching the registry and writes a last snapshot.
+func (c *snapshotCache) Stop() {
+	c.once.Do(func() {
+		close(c.exit)
+		if c.opts.Store == nil && len(c.opts.Path) == 0 {
+			return
+		}
+		if err := c.save(); err != nil {
+			log.Warnf("[cache] Error writing snapshot: %v", err)
+		}
+	})
+}
+
+func (c *snapshotCache) String() string {
+	return "cache"
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+// flakyRegistry fails reads while down.
+type flakyRegistry struct {
+	registry.Registry
+	down bool
+}
+
+func (f *flakyRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	if f.down {
+		return nil, errors.New("down")
+	}
+	return f.Registry.GetService(name, opts...)
+}
+
+func TestSnapshotCache(t *testing.T) {
+	r := &flakyRegistry{Registry: registry.NewMemoryRegistry()}
+	err := r.Register(&registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "foo-1", Address: "localhost:8080"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "registry.json")
+	opts := []Option{Path(path), TTL(time.Hour), Interval(time.Hour)}
+
+	c := NewSnapshotCache(r, opts...)
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetService("foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := c.(*snapshotCache).Stats(); st.Misses != 1 || st.Hits != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	c.Stop()
+
+	// started while the registry is down
+	r.down = true
+	c = NewSnapshotCache(r, append(opts, MaxStaleness(time.Hour))...)
+	defer c.Stop()
+	svcs, err := c.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcs) != 1 || len(svcs[0].Nodes) != 1 || svcs[0].Nodes[0].Id != "foo-1" {
+		t.Fatalf("expected the snapshot service, got %+v", svcs)
+	}
+	if st := c.(*snapshotCache).Stats(); st.Stale != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	// too stale to serve
+	old := NewSnapshotCache(r, append(opts, MaxStaleness(time.Nanosecond))...)
+	defer old.Stop()
+	if _, err := old.GetService("foo"); err == nil {
+		t.Fatal("expected an error past the max staleness")
+	}
+	if st := old.(*snapshotCache).Stats(); st.Errors != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestSnapshotCacheDefaults(t *testing.T) {
+	r := &flakyRegistry{Registry: registry.NewMemoryRegistry()}
+	c := NewSnapshotCache(r, TTL(time.Second))
+	defer c.Stop()
+
+	if d := c.(*snapshotCache).opts.MaxStaleness; d != time.Duration(DefaultStaleTTLs)*time.Second {
+		t.Fatalf("expected a max staleness of %d TTLs, got %v", DefaultStaleTTLs, d)
+	}
+
+	if err := r.Register(&registry.Service{Name: "foo", Version: "1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetService("foo", func(*registry.GetOptions) {}); err != nil {
+		t.Fatal(err)
+	}
+	if st := c.(*snapshotCache).Stats(); st.Misses != 0 {
+		t.Fatalf("expected lookups with options not to be cached, got %+v", st)
+	}
+}
@@ -0,0 +1,271 @@
+package consul
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	consul "github.com/hashicorp/consul/api"
+	"go-micro.org/v5/logger"
+)
+
+var (
+	// ErrNoCertificate is returned before the leaf certificate is fetched.
+	ErrNoCertificate = errors.New("connect certificate not fetched yet")
+	// ErrNotAuthorized is returned for clients denied by intentions.
+	ErrNotAuthorized = errors.New("connect client not authorized")
+)
+
+// ConnectCerts keeps the leaf certificate of a connect native service and the
+// connect CA roots up to date, with blocking queries to the local agent, for
+// mutual tls between connect services.
+//
+//	certs := consul.NewConnectCerts(client, "greeter")
+//	defer certs.Stop()
+//	if err := certs.Ready(ctx); err != nil { ... }
+//	transport.TLSConfig(certs.ServerTLSConfig())
+type ConnectCerts struct {
+	client  *consul.Client
+	service string
+	cancel  context.CancelFunc
+	// authorize asks whether a client may connect, by the spiffe uri and
+	// serial of its certificate
+	authorize func(uri, serial string) error
+
+	sync.RWMutex
+	cert  *tls.Certificate
+	roots *x509.CertPool
+	ready chan struct{}
+}
+
+// NewConnectCerts starts fetching the certificates of a service.
+func NewConnectCerts(client *consul.Client, service string) *ConnectCerts {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &ConnectCerts{
+		client:  client,
+		service: service,
+		cancel:  cancel,
+		ready:   make(chan struct{}),
+	}
+	c.authorize = c.agentAuthorize
+
+	go c.watchLeaf(ctx)
+	go c.watchRoots(ctx)
+
+	return c
+}
+
+// ConnectCerts starts fetching the certificates of a service from the agent
+// of the registry.
+func (c *consulRegistry) ConnectCerts(service string) *ConnectCerts {
+	return NewConnectCerts(c.Client(), service)
+}
+
+func (c *ConnectCerts) watchLeaf(ctx context.Context) {
+	var leaf *consul.LeafCert
+
+	blocking(ctx, func(index uint64) (*consul.QueryMeta, error) {
+		var meta *consul.QueryMeta
+		var err error
+		q := &consul.QueryOptions{WaitIndex: index}
+		leaf, meta, err = c.client.Agent().ConnectCALeaf(c.service, q.WithContext(ctx))
+		return meta, err
+	}, func() {
+		cert, err := tls.X509KeyPair([]byte(leaf.CertPEM), []byte(leaf.PrivateKeyPEM))
+		if err != nil {
+			logger.Errorf("Invalid connect certificate of %s: %v", c.service, err)
+			return
+		}
+
+		c.Lock()
+		c.cert = &cert
+		c.setReady()
+		c.Unlock()
+	})
+}
+
+func (c *ConnectCerts) watchRoots(ctx context.Context) {
+	var list *consul.CARootList
+
+	blocking(ctx, func(index uint64) (*consul.QueryMeta, error) {
+		var meta *consul.QueryMeta
+		var err error
+		q := &consul.QueryOptions{WaitIndex: index}
+		list, meta, err = c.client.Agent().ConnectCARoots(q.WithContext(ctx))
+		return meta, err
+	}, func() {
+		// keep every root, not only the active one, so certificates
+		// signed by the previous one verify while the CA rotates
+		pool := x509.NewCertPool()
+		for _, root := range list.Roots {
+			pool.AppendCertsFromPEM([]byte(root.RootCertPEM))
+		}
+
+		c.Lock()
+		c.roots = pool
+		c.setReady()
+		c.Unlock()
+	})
+}
+
+// setReady marks the certificates ready once both are fetched.
+func (c *ConnectCerts) setReady() {
+	if c.cert == nil || c.roots == nil {
+		return
+	}
+	select {
+	case <-c.ready:
+	default:
+		close(c.ready)
+	}
+}
+
+// Ready waits for the certificates to be fetched.
+func (c *ConnectCerts) Ready(ctx context.Context) error {
+	select {
+	case <-c.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Certificate returns the leaf certificate of the service.
+func (c *ConnectCerts) Certificate() (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.cert == nil {
+		return nil, ErrNoCertificate
+	}
+	return c.cert, nil
+}
+
+// Roots returns the connect CA roots.
+func (c *ConnectCerts) Roots() *x509.CertPool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.roots
+}
+
+// verify verifies the certificate chain of a peer against the current roots.
+// Connect certificates name services by spiffe uri rather than host name,
+// so only the chain is verified.
+func (c *ConnectCerts) verify(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	_, err := c.verifyChain(rawCerts)
+	return err
+}
+
+// verifyClient verifies the certificate chain of a client, and asks the agent
+// whether intentions allow it to connect to the service.
+func (c *ConnectCerts) verifyClient(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	cert, err := c.verifyChain(rawCerts)
+	if err != nil {
+		return err
+	}
+	if len(cert.URIs) == 0 {
+		return errors.New("no spiffe uri in the client certificate")
+	}
+	return c.authorize(cert.URIs[0].String(), encodeSerial(cert.SerialNumber.Bytes()))
+}
+
+// agentAuthorize asks the local agent to authorize a client against the
+// intentions of the service.
+func (c *ConnectCerts) agentAuthorize(uri, serial string) error {
+	auth, err := c.client.Agent().ConnectAuthorize(&consul.AgentAuthorizeParams{
+		Target:           c.service,
+		ClientCertURI:    uri,
+		ClientCertSerial: serial,
+	})
+	if err != nil {
+		return err
+	}
+	if !auth.Authorized {
+		return fmt.Errorf("%w: %s", ErrNotAuthorized, auth.Reason)
+	}
+	return nil
+}
+
+// encodeSerial formats a certificate serial the way consul does, as colon
+// separated hex bytes.
+func encodeSerial(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, ":")
+}
+
+// verifyChain verifies the certificate chain of a peer against the current
+// roots, returning the peer certificate.
+func (c *ConnectCerts) verifyChain(rawCerts [][]byte) (*x509.Certificate, error) {
+	roots := c.Roots()
+	if roots == nil {
+		return nil, ErrNoCertificate
+	}
+	if len(rawCerts) == 0 {
+		return nil, errors.New("no peer certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// ServerTLSConfig returns a tls config serving the leaf certificate and
+// requiring clients to present one signed by the connect CA, which the
+// intentions of the service allow to connect.
+func (c *ConnectCerts) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.Certificate()
+		},
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: c.verifyClient,
+	}
+}
+
+// ClientTLSConfig returns a tls config presenting the leaf certificate and
+// verifying servers against the connect CA.
+func (c *ConnectCerts) ClientTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.Certificate()
+		},
+		// verified by verify, as host names don't apply
+		//nolint:gosec
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: c.verify,
+	}
+}
+
+// Stop stops fetching the certificates.
+func (c *ConnectCerts) Stop() {
+	c.cancel()
+}
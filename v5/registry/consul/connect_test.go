@@ -0,0 +1,118 @@
+package consul
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newCert(t *testing.T, name string, ca bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if !ca {
+		tmpl.URIs = []*url.URL{{Scheme: "spiffe", Host: "cluster.consul", Path: "/ns/default/dc/dc1/svc/" + name}}
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestConnectVerify(t *testing.T) {
+	ca, caKey := newCert(t, "Consul CA", true, nil, nil)
+	leaf, _ := newCert(t, "greeter", false, ca, caKey)
+	other, otherKey := newCert(t, "Other CA", true, nil, nil)
+	foreign, _ := newCert(t, "greeter", false, other, otherKey)
+
+	c := &ConnectCerts{ready: make(chan struct{})}
+	if err := c.verify([][]byte{leaf.Raw}, nil); err != ErrNoCertificate {
+		t.Fatalf("Expected no certificate before the roots are fetched, got %v", err)
+	}
+	if _, err := c.Certificate(); err != ErrNoCertificate {
+		t.Fatalf("Expected no certificate, got %v", err)
+	}
+
+	c.roots = x509.NewCertPool()
+	c.roots.AddCert(ca)
+	c.cert = &tls.Certificate{Certificate: [][]byte{leaf.Raw}}
+	c.setReady()
+
+	if err := c.verify([][]byte{leaf.Raw}, nil); err != nil {
+		t.Fatalf("Expected the leaf to verify, got %v", err)
+	}
+	if err := c.verify([][]byte{foreign.Raw}, nil); err == nil {
+		t.Fatal("Expected a certificate of another CA to fail")
+	}
+
+	select {
+	case <-c.ready:
+	default:
+		t.Fatal("Expected the certificates to be ready")
+	}
+}
+
+func TestConnectAuthorize(t *testing.T) {
+	ca, caKey := newCert(t, "Consul CA", true, nil, nil)
+	leaf, _ := newCert(t, "web", false, ca, caKey)
+
+	var uri, serial string
+	c := &ConnectCerts{ready: make(chan struct{}), roots: x509.NewCertPool()}
+	c.roots.AddCert(ca)
+	c.authorize = func(u, s string) error {
+		uri, serial = u, s
+		return nil
+	}
+
+	if err := c.verifyClient([][]byte{leaf.Raw}, nil); err != nil {
+		t.Fatalf("Expected the client to be authorized, got %v", err)
+	}
+	if uri != "spiffe://cluster.consul/ns/default/dc/dc1/svc/web" {
+		t.Fatalf("Expected the spiffe uri of the client, got %s", uri)
+	}
+	if serial != encodeSerial(leaf.SerialNumber.Bytes()) {
+		t.Fatalf("Expected the serial of the client, got %s", serial)
+	}
+
+	c.authorize = func(string, string) error {
+		return ErrNotAuthorized
+	}
+	if err := c.verifyClient([][]byte{leaf.Raw}, nil); !errors.Is(err, ErrNotAuthorized) {
+		t.Fatalf("Expected the client to be denied, got %v", err)
+	}
+
+	if got := encodeSerial([]byte{0x0a, 0xff, 0x01}); got != "0a:ff:01" {
+		t.Fatalf("Expected colon separated hex, got %s", got)
+	}
+}
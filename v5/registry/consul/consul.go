@@ -1,6 +1,7 @@
 package consul
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 
 	consul "github.com/hashicorp/consul/api"
 	hash "github.com/mitchellh/hashstructure"
+	"github.com/open-micro/plugins/v5/registry/health"
 	"go-micro.org/v5/registry"
 	"go-micro.org/v5/util/cmd"
 	mnet "go-micro.org/v5/util/net"
@@ -28,6 +30,8 @@ type consulRegistry struct {
 
 	// connect enabled
 	connect bool
+	// filter expression of the nodes of services
+	filter string
 
 	queryOptions *consul.QueryOptions
 
@@ -37,10 +41,46 @@ type consulRegistry struct {
 	lastChecked map[string]time.Time
 }
 
+// StatusCheckTTL is the ttl of the status checks of nodes with a health
+// status, renewed every time they register.
+var StatusCheckTTL = time.Hour
+
 func init() {
 	cmd.DefaultRegistries["consul"] = NewRegistry
 }
 
+// statusCheck returns the check of the health status of a node, nil if it
+// has none. Starting nodes are critical, draining ones warn.
+func statusCheck(node *registry.Node) *consul.AgentServiceCheck {
+	if _, ok := node.Metadata[health.MetadataKey]; !ok {
+		return nil
+	}
+
+	status := consul.HealthPassing
+	switch health.StatusOf(node) {
+	case health.Starting:
+		status = consul.HealthCritical
+	case health.Draining:
+		status = consul.HealthWarning
+	}
+
+	return &consul.AgentServiceCheck{
+		CheckID: "micro-status:" + node.Id,
+		Name:    "Micro status",
+		Notes:   "The micro health status of the node",
+		TTL:     fmt.Sprintf("%v", StatusCheckTTL),
+		Status:  status,
+	}
+}
+
+// renewStatus renews the status check of a node.
+func (c *consulRegistry) renewStatus(node *registry.Node) {
+	if check := statusCheck(node); check != nil {
+		//nolint:errcheck
+		c.Client().Agent().UpdateTTL(check.CheckID, string(health.StatusOf(node)), check.Status)
+	}
+}
+
 func getDeregisterTTL(t time.Duration) time.Duration {
 	// splay slightly for the watcher?
 	splay := time.Second * 5
@@ -93,6 +133,9 @@ func configure(c *consulRegistry, opts ...registry.Option) {
 		if cn, ok := c.opts.Context.Value("consul_connect").(bool); ok {
 			c.connect = cn
 		}
+		if f, ok := c.opts.Context.Value("consul_filter").(string); ok {
+			c.filter = f
+		}
 
 		// Use the consul query options passed in the options, if available
 		if qo, ok := c.opts.Context.Value("consul_query_options").(*consul.QueryOptions); ok && qo != nil {
@@ -217,12 +260,14 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 		if options.TTL == time.Duration(0) {
 			// ensure that our service hasn't been deregistered by Consul
 			if time.Since(lastChecked) <= getDeregisterTTL(regInterval) {
+				c.renewStatus(node)
 				return nil
 			}
 			services, _, err := c.Client().Health().Checks(s.Name, c.queryOptions)
 			if err == nil {
 				for _, v := range services {
 					if v.ServiceID == node.Id {
+						c.renewStatus(node)
 						return nil
 					}
 				}
@@ -231,6 +276,7 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 			// if the err is nil we're all good, bail out
 			// if not, we don't know what the state is, so full re-register
 			if err := c.Client().Agent().PassTTL("service:"+node.Id, ""); err == nil {
+				c.renewStatus(node)
 				return nil
 			}
 		}
@@ -293,6 +339,11 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 		Check:   check,
 	}
 
+	// the status check hides starting nodes and warns of draining ones
+	if sc := statusCheck(node); sc != nil {
+		asr.Checks = consul.AgentServiceChecks{sc}
+	}
+
 	// Specify consul connect
 	if c.connect {
 		asr.Connect = &consul.AgentServiceConnect{
@@ -320,15 +371,7 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 }
 
 func (c *consulRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
-	var rsp []*consul.ServiceEntry
-	var err error
-
-	// if we're connect enabled only get connect services
-	if c.connect {
-		rsp, _, err = c.Client().Health().Connect(name, "", false, c.queryOptions)
-	} else {
-		rsp, _, err = c.Client().Health().Service(name, "", false, c.queryOptions)
-	}
+	rsp, _, err := c.health(context.Background(), name, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -394,6 +437,23 @@ func (c *consulRegistry) GetService(name string, opts ...registry.GetOption) ([]
 	return services, nil
 }
 
+// health returns the entries of a service matching the filter, blocking
+// until the index changes when set.
+func (c *consulRegistry) health(ctx context.Context, name string, index uint64) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
+	q := *c.queryOptions
+	q.WaitIndex = index
+	if len(c.filter) > 0 {
+		q.Filter = c.filter
+	}
+	qo := q.WithContext(ctx)
+
+	// if we're connect enabled only get connect services
+	if c.connect {
+		return c.Client().Health().Connect(name, "", false, qo)
+	}
+	return c.Client().Health().Service(name, "", false, qo)
+}
+
 func (c *consulRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
 	rsp, _, err := c.Client().Catalog().Services(c.queryOptions)
 	if err != nil {
@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/hashicorp/consul/api v1.9.0
 	github.com/mitchellh/hashstructure v1.1.0
+	github.com/open-micro/plugins/v5/registry/health v0.0.0-00010101000000-000000000000
 	go-micro.org/v5 v5.0.1
 )
 
@@ -65,3 +66,5 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/registry/health => ../health
@@ -19,6 +19,20 @@ func Connect() registry.Option {
 	}
 }
 
+// Filter sets a filter expression the nodes of services are selected by,
+// server side, eg: `Service.Meta.region == "eu"` or `"v2" in Service.Tags`.
+// See `Filtering` for more information [1].
+//
+// [1] https://www.consul.io/api-docs/features/filtering
+func Filter(expr string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_filter", expr)
+	}
+}
+
 func Config(c *consul.Config) registry.Option {
 	return func(o *registry.Options) {
 		if o.Context == nil {
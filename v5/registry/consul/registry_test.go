@@ -10,6 +10,7 @@ import (
 	"time"
 
 	consul "github.com/hashicorp/consul/api"
+	"github.com/open-micro/plugins/v5/registry/health"
 	"go-micro.org/v5/registry"
 )
 
@@ -206,3 +207,28 @@ func TestConsul_GetService_WithUnhealthyServiceNodes(t *testing.T) {
 		t.Fatalf("Expected len of nodes to be `%d`, got `%d`.", exp, act)
 	}
 }
+
+func TestStatusCheck(t *testing.T) {
+	testCases := []struct {
+		metadata map[string]string
+		status   string
+	}{
+		{nil, ""},
+		{map[string]string{health.MetadataKey: string(health.Starting)}, consul.HealthCritical},
+		{map[string]string{health.MetadataKey: string(health.Ready)}, consul.HealthPassing},
+		{map[string]string{health.MetadataKey: string(health.Draining)}, consul.HealthWarning},
+	}
+
+	for _, tc := range testCases {
+		check := statusCheck(&registry.Node{Id: "foo-1", Metadata: tc.metadata})
+		if check == nil {
+			if len(tc.status) > 0 {
+				t.Fatalf("expected a %s check", tc.status)
+			}
+			continue
+		}
+		if check.Status != tc.status || check.CheckID != "micro-status:foo-1" {
+			t.Fatalf("expected a %s check got %+v", tc.status, check)
+		}
+	}
+}
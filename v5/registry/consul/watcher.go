@@ -1,21 +1,34 @@
 package consul
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/consul/api"
-	"github.com/hashicorp/consul/api/watch"
 	"go-micro.org/v5/registry"
 	regutil "go-micro.org/v5/util/registry"
 )
 
+var (
+	// watchRetryMin and watchRetryMax bound the backoff of failed queries.
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+// consulWatcher watches services with blocking queries, asking consul for
+// changes since the index of the last answer rather than polling.
 type consulWatcher struct {
-	r        *consulRegistry
-	wo       registry.WatchOptions
-	wp       *watch.Plan
-	watchers map[string]*watch.Plan
+	r  *consulRegistry
+	wo registry.WatchOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// stops the watches of services, by name
+	watchers map[string]context.CancelFunc
 
 	next chan *registry.Result
 	exit chan bool
@@ -30,41 +43,106 @@ func newConsulWatcher(cr *consulRegistry, opts ...registry.WatchOption) (registr
 		o(&wo)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	cw := &consulWatcher{
 		r:        cr,
 		wo:       wo,
+		ctx:      ctx,
+		cancel:   cancel,
 		exit:     make(chan bool),
 		next:     make(chan *registry.Result, 10),
-		watchers: make(map[string]*watch.Plan),
+		watchers: make(map[string]context.CancelFunc),
 		services: make(map[string][]*registry.Service),
 	}
 
-	wp, err := watch.Parse(map[string]interface{}{
-		"service": wo.Service,
-		"type":    "service",
-	})
-	if err != nil {
-		return nil, err
+	if len(wo.Service) > 0 {
+		go cw.watchService(ctx, wo.Service)
+	} else {
+		go cw.watchServices()
 	}
 
-	wp.Handler = cw.serviceHandler
-	go wp.RunWithClientAndHclog(cr.Client(), wp.Logger)
-	cw.wp = wp
-
 	return cw, nil
 }
 
-func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
-	entries, ok := data.([]*api.ServiceEntry)
-	if !ok {
-		return
+// blocking runs a blocking query until the context is done, calling fn with
+// its results as the index changes. Failed queries are retried with backoff.
+func blocking(ctx context.Context, query func(index uint64) (*api.QueryMeta, error), fn func()) {
+	var index uint64
+	backoff := watchRetryMin
+
+	for {
+		meta, err := query(index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > watchRetryMax {
+				backoff = watchRetryMax
+			}
+			continue
+		}
+		backoff = watchRetryMin
+
+		switch {
+		// the wait timed out, nothing changed
+		case meta.LastIndex == index:
+			continue
+		// the index went backwards, eg: a snapshot was restored, start over
+		case meta.LastIndex < index:
+			index = 0
+		default:
+			index = meta.LastIndex
+		}
+
+		fn()
 	}
+}
+
+// watchServices watches the catalog for services, and each service for nodes.
+func (cw *consulWatcher) watchServices() {
+	var services map[string][]string
+
+	blocking(cw.ctx, func(index uint64) (*api.QueryMeta, error) {
+		q := *cw.r.queryOptions
+		q.WaitIndex = index
+
+		var meta *api.QueryMeta
+		var err error
+		services, meta, err = cw.r.Client().Catalog().Services(q.WithContext(cw.ctx))
+		return meta, err
+	}, func() {
+		cw.handle(services)
+	})
+}
 
+// watchService watches the nodes of a service.
+func (cw *consulWatcher) watchService(ctx context.Context, name string) {
+	var entries []*api.ServiceEntry
+
+	blocking(ctx, func(index uint64) (*api.QueryMeta, error) {
+		var meta *api.QueryMeta
+		var err error
+		entries, meta, err = cw.r.health(ctx, name, index)
+		return meta, err
+	}, func() {
+		cw.update(name, entries)
+	})
+}
+
+// update sends the changes of the nodes of a service.
+func (cw *consulWatcher) update(serviceName string, entries []*api.ServiceEntry) {
 	serviceMap := map[string]*registry.Service{}
-	serviceName := ""
 
 	for _, e := range entries {
-		serviceName = e.Service.Service
+		if e.Service.Service != serviceName {
+			continue
+		}
 		// version is now a tag
 		version, _ := decodeVersion(e.Service.Tags)
 		// service ID is now the node id
@@ -184,26 +262,14 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 		}
 	}
 
-	// there are no services in the service, empty all services
-	if len(rservices) != 0 && serviceName == "" {
-		for _, services := range rservices {
-			for _, service := range services {
-				cw.next <- &registry.Result{Action: "delete", Service: service}
-			}
-		}
-	}
-
 	cw.Lock()
 	cw.services[serviceName] = newServices
 	cw.Unlock()
 }
 
-func (cw *consulWatcher) handle(idx uint64, data interface{}) {
-	services, ok := data.(map[string][]string)
-	if !ok {
-		return
-	}
-
+// handle starts watching new services and sends the deletes of the services
+// gone from the catalog.
+func (cw *consulWatcher) handle(services map[string][]string) {
 	// add new watchers
 	for service := range services {
 		// Filter on watch options
@@ -215,16 +281,11 @@ func (cw *consulWatcher) handle(idx uint64, data interface{}) {
 		if _, ok := cw.watchers[service]; ok {
 			continue
 		}
-		wp, err := watch.Parse(map[string]interface{}{
-			"type":    "service",
-			"service": service,
-		})
-		if err == nil {
-			wp.Handler = cw.serviceHandler
-			go wp.RunWithClientAndHclog(cw.r.Client(), wp.Logger)
-			cw.watchers[service] = wp
-			cw.next <- &registry.Result{Action: "create", Service: &registry.Service{Name: service}}
-		}
+
+		ctx, cancel := context.WithCancel(cw.ctx)
+		go cw.watchService(ctx, service)
+		cw.watchers[service] = cancel
+		cw.next <- &registry.Result{Action: "create", Service: &registry.Service{Name: service}}
 	}
 
 	cw.RLock()
@@ -250,9 +311,9 @@ func (cw *consulWatcher) handle(idx uint64, data interface{}) {
 	}
 
 	// remove unknown services from watchers
-	for service, w := range cw.watchers {
+	for service, stop := range cw.watchers {
 		if _, ok := services[service]; !ok {
-			w.Stop()
+			stop()
 			delete(cw.watchers, service)
 			for _, oldService := range deleted[service] {
 				// send a delete for the service nodes that we're removing
@@ -282,10 +343,10 @@ func (cw *consulWatcher) Stop() {
 		return
 	default:
 		close(cw.exit)
-		if cw.wp == nil {
+		if cw.cancel == nil {
 			return
 		}
-		cw.wp.Stop()
+		cw.cancel()
 
 		// drain results
 		for {
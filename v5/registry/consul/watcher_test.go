@@ -1,6 +1,7 @@
 package consul
 
 import (
+	"context"
 	"testing"
 
 	"github.com/hashicorp/consul/api"
@@ -16,7 +17,7 @@ func TestHealthyServiceHandler(t *testing.T) {
 		},
 	)
 
-	watcher.serviceHandler(1234, []*api.ServiceEntry{serviceEntry})
+	watcher.update("service-name", []*api.ServiceEntry{serviceEntry})
 
 	if len(watcher.services["service-name"][0].Nodes) != 1 {
 		t.Errorf("Expected length of the service nodes to be 1")
@@ -32,7 +33,7 @@ func TestUnhealthyServiceHandler(t *testing.T) {
 		},
 	)
 
-	watcher.serviceHandler(1234, []*api.ServiceEntry{serviceEntry})
+	watcher.update("service-name", []*api.ServiceEntry{serviceEntry})
 
 	if len(watcher.services["service-name"][0].Nodes) != 0 {
 		t.Errorf("Expected length of the service nodes to be 0")
@@ -49,7 +50,7 @@ func TestUnhealthyNodeServiceHandler(t *testing.T) {
 		},
 	)
 
-	watcher.serviceHandler(1234, []*api.ServiceEntry{serviceEntry})
+	watcher.update("service-name", []*api.ServiceEntry{serviceEntry})
 
 	if len(watcher.services["service-name"][0].Nodes) != 0 {
 		t.Errorf("Expected length of the service nodes to be 0")
@@ -84,3 +85,36 @@ func newServiceEntry(node, address, name, version string, checks []*api.HealthCh
 		Checks: checks,
 	}
 }
+
+func TestBlocking(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// answers, the index 1 twice as the wait times out, then a backwards
+	// index as consul is restored
+	indexes := []uint64{1, 1, 3, 2}
+	var waited []uint64
+	var calls int
+
+	blocking(ctx, func(index uint64) (*api.QueryMeta, error) {
+		waited = append(waited, index)
+		if len(waited) > len(indexes) {
+			cancel()
+			return nil, ctx.Err()
+		}
+		return &api.QueryMeta{LastIndex: indexes[len(waited)-1]}, nil
+	}, func() {
+		calls++
+	})
+
+	if calls != 3 {
+		t.Errorf("Expected 3 changes, got %d", calls)
+	}
+
+	expected := []uint64{0, 1, 1, 3, 0}
+	for i, index := range expected {
+		if waited[i] != index {
+			t.Fatalf("Expected to wait on %v, got %v", expected, waited)
+		}
+	}
+}
@@ -25,15 +25,22 @@ import (
 
 var (
 	prefix = "/micro/registry/"
+
+	// keepAliveBackoff is the wait between registering again nodes which
+	// lost their lease.
+	keepAliveBackoff = time.Second
 )
 
 type etcdRegistry struct {
 	client  *clientv3.Client
 	options registry.Options
+	prefix  string
 
 	sync.RWMutex
 	register map[string]uint64
 	leases   map[string]clientv3.LeaseID
+	// cancels the lease keep alive of nodes
+	keepAlives map[string]context.CancelFunc
 }
 
 func init() {
@@ -42,9 +49,10 @@ func init() {
 
 func NewRegistry(opts ...registry.Option) registry.Registry {
 	e := &etcdRegistry{
-		options:  registry.Options{},
-		register: make(map[string]uint64),
-		leases:   make(map[string]clientv3.LeaseID),
+		options:    registry.Options{},
+		register:   make(map[string]uint64),
+		leases:     make(map[string]clientv3.LeaseID),
+		keepAlives: make(map[string]context.CancelFunc),
 	}
 	username, password := os.Getenv("ETCD_USERNAME"), os.Getenv("ETCD_PASSWORD")
 	if len(username) > 0 && len(password) > 0 {
@@ -77,6 +85,8 @@ func configure(e *etcdRegistry, opts ...registry.Option) error {
 
 	config.DialTimeout = e.options.Timeout
 
+	e.prefix = prefix
+
 	if e.options.Secure || e.options.TLSConfig != nil {
 		tlsConfig := e.options.TLSConfig
 		if tlsConfig == nil {
@@ -98,6 +108,9 @@ func configure(e *etcdRegistry, opts ...registry.Option) error {
 		if ok && cfg != nil {
 			config.LogConfig = cfg
 		}
+		if p, ok := e.options.Context.Value(prefixKey{}).(string); ok && len(p) > 0 {
+			e.prefix = strings.TrimSuffix(p, "/") + "/"
+		}
 	}
 
 	var cAddrs []string
@@ -140,13 +153,13 @@ func decode(ds []byte) *registry.Service {
 	return s
 }
 
-func nodePath(s, id string) string {
+func nodePath(prefix, s, id string) string {
 	service := strings.Replace(s, "/", "-", -1)
 	node := strings.Replace(id, "/", "-", -1)
 	return path.Join(prefix, service, node)
 }
 
-func servicePath(s string) string {
+func servicePath(prefix, s string) string {
 	return path.Join(prefix, strings.Replace(s, "/", "-", -1))
 }
 
@@ -176,7 +189,7 @@ func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, op
 		defer cancel()
 
 		// look for the existing key
-		rsp, err := e.client.Get(ctx, nodePath(s.Name, node.Id), clientv3.WithSerializable())
+		rsp, err := e.client.Get(ctx, nodePath(e.prefix, s.Name, node.Id), clientv3.WithSerializable())
 		if err != nil {
 			return err
 		}
@@ -258,21 +271,30 @@ func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, op
 	ctx, cancel := context.WithTimeout(context.Background(), e.options.Timeout)
 	defer cancel()
 
-	var lgr *clientv3.LeaseGrantResponse
+	var lease clientv3.LeaseID
+	var granted bool
 	if options.TTL.Seconds() > 0 {
-		// get a lease used to expire keys since we have a ttl
-		lgr, err = e.client.Grant(ctx, int64(options.TTL.Seconds()))
-		if err != nil {
-			return err
+		if leaseID > 0 && !leaseNotFound {
+			// the node changed, eg: its health status, rewrite it under
+			// the lease just renewed rather than leaving it to expire
+			lease = leaseID
+		} else {
+			granted = true
+			// get a lease used to expire keys since we have a ttl
+			lgr, err := e.client.Grant(ctx, int64(options.TTL.Seconds()))
+			if err != nil {
+				return err
+			}
+			lease = lgr.ID
 		}
 	}
 
-	log.Logf(logger.TraceLevel, "Registering %s id %s with lease %v and leaseID %v and ttl %v", service.Name, node.Id, lgr, lgr.ID, options.TTL)
+	log.Logf(logger.TraceLevel, "Registering %s id %s with leaseID %v and ttl %v", service.Name, node.Id, lease, options.TTL)
 	// create an entry for the node
-	if lgr != nil {
-		_, err = e.client.Put(ctx, nodePath(service.Name, node.Id), encode(service), clientv3.WithLease(lgr.ID))
+	if lease > 0 {
+		_, err = e.client.Put(ctx, nodePath(e.prefix, service.Name, node.Id), encode(service), clientv3.WithLease(lease))
 	} else {
-		_, err = e.client.Put(ctx, nodePath(service.Name, node.Id), encode(service))
+		_, err = e.client.Put(ctx, nodePath(e.prefix, service.Name, node.Id), encode(service))
 	}
 	if err != nil {
 		return err
@@ -282,14 +304,70 @@ func (e *etcdRegistry) registerNode(s *registry.Service, node *registry.Node, op
 	// save our hash of the service
 	e.register[s.Name+node.Id] = h
 	// save our leaseID of the service
-	if lgr != nil {
-		e.leases[s.Name+node.Id] = lgr.ID
+	if lease > 0 {
+		e.leases[s.Name+node.Id] = lease
 	}
 	e.Unlock()
 
+	if granted {
+		e.keepAlive(s, node, lease, opts...)
+	}
+
 	return nil
 }
 
+// keepAlive keeps the lease of a node alive between registrations, and
+// registers the node again if the lease is lost, eg: it expired while etcd
+// was unreachable.
+func (e *etcdRegistry) keepAlive(s *registry.Service, node *registry.Node, lease clientv3.LeaseID, opts ...registry.RegisterOption) {
+	key := s.Name + node.Id
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e.Lock()
+	if stop, ok := e.keepAlives[key]; ok {
+		stop()
+	}
+	e.keepAlives[key] = cancel
+	e.Unlock()
+
+	log := e.options.Logger
+
+	go func() {
+		ch, err := e.client.KeepAlive(ctx, lease)
+		if err == nil {
+			//nolint:revive
+			for range ch {
+			}
+		}
+
+		// deregistered, or registered with another lease
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Logf(logger.WarnLevel, "Lost lease %d of %s node %s, registering again", lease, s.Name, node.Id)
+
+		e.Lock()
+		delete(e.leases, key)
+		delete(e.register, key)
+		e.Unlock()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(keepAliveBackoff):
+			}
+
+			if err := e.registerNode(s, node, opts...); err != nil {
+				log.Logf(logger.ErrorLevel, "Failed to register %s node %s again: %v", s.Name, node.Id, err)
+				continue
+			}
+			return
+		}
+	}()
+}
+
 func (e *etcdRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
 	if len(s.Nodes) == 0 {
 		return errors.New("Require at least one node")
@@ -301,13 +379,18 @@ func (e *etcdRegistry) Deregister(s *registry.Service, opts ...registry.Deregist
 		delete(e.register, s.Name+node.Id)
 		// delete our lease of the service
 		delete(e.leases, s.Name+node.Id)
+		// stop keeping it alive
+		if stop, ok := e.keepAlives[s.Name+node.Id]; ok {
+			stop()
+			delete(e.keepAlives, s.Name+node.Id)
+		}
 		e.Unlock()
 
 		ctx, cancel := context.WithTimeout(context.Background(), e.options.Timeout)
 		defer cancel()
 
 		e.options.Logger.Logf(logger.TraceLevel, "Deregistering %s id %s", s.Name, node.Id)
-		_, err := e.client.Delete(ctx, nodePath(s.Name, node.Id))
+		_, err := e.client.Delete(ctx, nodePath(e.prefix, s.Name, node.Id))
 		if err != nil {
 			return err
 		}
@@ -338,7 +421,7 @@ func (e *etcdRegistry) GetService(name string, opts ...registry.GetOption) ([]*r
 	ctx, cancel := context.WithTimeout(context.Background(), e.options.Timeout)
 	defer cancel()
 
-	rsp, err := e.client.Get(ctx, servicePath(name)+"/", clientv3.WithPrefix(), clientv3.WithSerializable())
+	rsp, err := e.client.Get(ctx, servicePath(e.prefix, name)+"/", clientv3.WithPrefix(), clientv3.WithSerializable())
 	if err != nil {
 		return nil, err
 	}
@@ -380,7 +463,7 @@ func (e *etcdRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Se
 	ctx, cancel := context.WithTimeout(context.Background(), e.options.Timeout)
 	defer cancel()
 
-	rsp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithSerializable())
+	rsp, err := e.client.Get(ctx, e.prefix, clientv3.WithPrefix(), clientv3.WithSerializable())
 	if err != nil {
 		return nil, err
 	}
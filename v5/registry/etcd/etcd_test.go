@@ -0,0 +1,34 @@
+package etcd
+
+import (
+	"testing"
+
+	"go-micro.org/v5/registry"
+)
+
+func TestPaths(t *testing.T) {
+	testCases := []struct {
+		opts    []registry.Option
+		prefix  string
+		node    string
+		service string
+	}{
+		{nil, "/micro/registry/", "/micro/registry/foo/foo-1", "/micro/registry/foo"},
+		{[]registry.Option{Prefix("/envs/dev")}, "/envs/dev/", "/envs/dev/foo/foo-1", "/envs/dev/foo"},
+		{[]registry.Option{Namespace("staging")}, "/micro/staging/registry/", "/micro/staging/registry/foo/foo-1", "/micro/staging/registry/foo"},
+	}
+
+	for _, tc := range testCases {
+		e := NewRegistry(tc.opts...).(*etcdRegistry)
+
+		if e.prefix != tc.prefix {
+			t.Fatalf("expected prefix %s got %s", tc.prefix, e.prefix)
+		}
+		if p := nodePath(e.prefix, "foo", "foo-1"); p != tc.node {
+			t.Fatalf("expected node path %s got %s", tc.node, p)
+		}
+		if p := servicePath(e.prefix, "foo"); p != tc.service {
+			t.Fatalf("expected service path %s got %s", tc.service, p)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package etcd
 
 import (
 	"context"
+	"path"
 
 	"go-micro.org/v5/registry"
 	"go.uber.org/zap"
@@ -11,6 +12,8 @@ type authKey struct{}
 
 type logConfigKey struct{}
 
+type prefixKey struct{}
+
 type authCreds struct {
 	Username string
 	Password string
@@ -35,3 +38,20 @@ func LogConfig(config *zap.Config) registry.Option {
 		o.Context = context.WithValue(o.Context, logConfigKey{}, config)
 	}
 }
+
+// Prefix sets the prefix of the keys of the registry, /micro/registry/ by
+// default.
+func Prefix(p string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, prefixKey{}, p)
+	}
+}
+
+// Namespace keeps the services of a namespace, eg: an environment or tenant,
+// apart from the others sharing the cluster, under /micro/<namespace>/registry/.
+func Namespace(ns string) registry.Option {
+	return Prefix(path.Join("/micro", ns, "registry"))
+}
@@ -5,15 +5,31 @@ import (
 	"errors"
 	"time"
 
+	"go-micro.org/v5/logger"
 	"go-micro.org/v5/registry"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
+var (
+	// resumeBackoff is the wait before resuming a failed watch.
+	resumeBackoff = time.Second
+)
+
+// etcdWatcher watches from the last revision seen, resuming where it left
+// off when the watch fails, and resyncing the services when that revision
+// has been compacted.
 type etcdWatcher struct {
-	stop    chan bool
-	w       clientv3.WatchChan
+	ctx     context.Context
+	cancel  context.CancelFunc
 	client  *clientv3.Client
 	timeout time.Duration
+	logger  logger.Logger
+	path    string
+	next    chan *registry.Result
+
+	// the services of the keys watched, and the revision they are at
+	services map[string]*registry.Service
+	revision int64
 }
 
 func newEtcdWatcher(r *etcdRegistry, timeout time.Duration, opts ...registry.WatchOption) (registry.Watcher, error) {
@@ -22,70 +38,182 @@ func newEtcdWatcher(r *etcdRegistry, timeout time.Duration, opts ...registry.Wat
 		o(&wo)
 	}
 
+	watchPath := r.prefix
+	if len(wo.Service) > 0 {
+		watchPath = servicePath(r.prefix, wo.Service) + "/"
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	stop := make(chan bool, 1)
 
-	go func() {
-		<-stop
+	ew := &etcdWatcher{
+		ctx:      ctx,
+		cancel:   cancel,
+		client:   r.client,
+		timeout:  timeout,
+		logger:   r.options.Logger,
+		path:     watchPath,
+		next:     make(chan *registry.Result),
+		services: make(map[string]*registry.Service),
+	}
+
+	// the services watched from now on
+	if err := ew.resync(false); err != nil {
 		cancel()
-	}()
+		return nil, err
+	}
 
-	watchPath := prefix
-	if len(wo.Service) > 0 {
-		watchPath = servicePath(wo.Service) + "/"
+	go ew.run()
+
+	return ew, nil
+}
+
+// resync gets the services watched, and sends the changes since the last
+// revision seen if send is set.
+func (ew *etcdWatcher) resync(send bool) error {
+	ctx, cancel := context.WithTimeout(ew.ctx, ew.timeout)
+	defer cancel()
+
+	rsp, err := ew.client.Get(ctx, ew.path, clientv3.WithPrefix())
+	if err != nil {
+		return err
 	}
 
-	return &etcdWatcher{
-		stop:    stop,
-		w:       r.client.Watch(ctx, watchPath, clientv3.WithPrefix(), clientv3.WithPrevKV()),
-		client:  r.client,
-		timeout: timeout,
-	}, nil
+	services := make(map[string]*registry.Service, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		if s := decode(kv.Value); s != nil {
+			services[string(kv.Key)] = s
+		}
+	}
+
+	if send {
+		for key, s := range services {
+			old, ok := ew.services[key]
+			switch {
+			case !ok:
+				ew.send("create", s)
+			case encode(old) != encode(s):
+				ew.send("update", s)
+			}
+		}
+		for key, s := range ew.services {
+			if _, ok := services[key]; !ok {
+				ew.send("delete", s)
+			}
+		}
+	}
+
+	ew.services = services
+	ew.revision = rsp.Header.Revision
+
+	return nil
 }
 
-func (ew *etcdWatcher) Next() (*registry.Result, error) {
-	for wresp := range ew.w {
-		if wresp.Err() != nil {
-			return nil, wresp.Err()
+func (ew *etcdWatcher) send(action string, s *registry.Service) {
+	select {
+	case ew.next <- &registry.Result{Action: action, Service: s}:
+	case <-ew.ctx.Done():
+	}
+}
+
+func (ew *etcdWatcher) run() {
+	var compacted bool
+
+	for {
+		if compacted {
+			ew.logger.Logf(logger.WarnLevel, "Watch of %s compacted past revision %d, resyncing", ew.path, ew.revision)
+			if err := ew.resync(true); err != nil {
+				ew.logger.Logf(logger.ErrorLevel, "Failed to resync %s: %v", ew.path, err)
+			} else {
+				compacted = false
+			}
+		}
+
+		if !compacted {
+			compacted = ew.watch()
 		}
-		if wresp.Canceled {
-			return nil, errors.New("could not get next")
+
+		select {
+		case <-ew.ctx.Done():
+			return
+		case <-time.After(resumeBackoff):
 		}
+	}
+}
+
+// watch watches from the revision after the last seen until the watch fails,
+// returning whether it failed as the revision was compacted.
+func (ew *etcdWatcher) watch() bool {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ew.ctx))
+	defer cancel()
+
+	ch := ew.client.Watch(ctx, ew.path,
+		clientv3.WithPrefix(),
+		clientv3.WithPrevKV(),
+		clientv3.WithRev(ew.revision+1),
+	)
+
+	for wresp := range ch {
+		if wresp.CompactRevision != 0 {
+			return true
+		}
+		if err := wresp.Err(); err != nil {
+			ew.logger.Logf(logger.WarnLevel, "Watch of %s failed at revision %d: %v", ew.path, ew.revision, err)
+			return false
+		}
+
 		for _, ev := range wresp.Events {
-			service := decode(ev.Kv.Value)
-			var action string
+			key := string(ev.Kv.Key)
 
 			switch ev.Type {
 			case clientv3.EventTypePut:
+				service := decode(ev.Kv.Value)
+				if service == nil {
+					continue
+				}
+
+				action := "update"
 				if ev.IsCreate() {
 					action = "create"
-				} else if ev.IsModify() {
-					action = "update"
 				}
+				ew.services[key] = service
+				ew.send(action, service)
 			case clientv3.EventTypeDelete:
-				action = "delete"
-
 				// get service from prevKv
-				service = decode(ev.PrevKv.Value)
-			}
+				var service *registry.Service
+				if ev.PrevKv != nil {
+					service = decode(ev.PrevKv.Value)
+				}
+				if service == nil {
+					service = ew.services[key]
+				}
+				delete(ew.services, key)
 
-			if service == nil {
-				continue
+				if service == nil {
+					continue
+				}
+				ew.send("delete", service)
 			}
-			return &registry.Result{
-				Action:  action,
-				Service: service,
-			}, nil
+
+			ew.revision = ev.Kv.ModRevision
+		}
+
+		if wresp.Header.Revision > ew.revision {
+			ew.revision = wresp.Header.Revision
 		}
 	}
-	return nil, errors.New("could not get next")
+
+	return false
 }
 
-func (ew *etcdWatcher) Stop() {
+func (ew *etcdWatcher) Next() (*registry.Result, error) {
 	select {
-	case <-ew.stop:
-		return
-	default:
-		close(ew.stop)
+	case r := <-ew.next:
+		return r, nil
+	case <-ew.ctx.Done():
+		return nil, errors.New("watcher stopped")
 	}
 }
+
+func (ew *etcdWatcher) Stop() {
+	ew.cancel()
+}
@@ -0,0 +1,294 @@
+// Package federation looks services up across datacenters
+package federation
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	log "go-micro.org/v5/logger"
+	"go-micro.org/v5/registry"
+	"go-micro.org/v5/selector"
+)
+
+var (
+	// DefaultDatacenter is the datacenter of the local registry unless set.
+	DefaultDatacenter = "local"
+)
+
+// DatacenterKey is the node metadata key of the datacenter of a node.
+const DatacenterKey = "datacenter"
+
+// federatedRegistry reads services from the local registry, and from the
+// registries of remote datacenters while the local one has no nodes.
+type federatedRegistry struct {
+	opts    registry.Options
+	local   *remote
+	remotes []*remote
+}
+
+func (f *federatedRegistry) Init(opts ...registry.Option) error {
+	return configure(f, opts...)
+}
+
+func (f *federatedRegistry) Options() registry.Options {
+	return f.opts
+}
+
+// Register registers with the local registry.
+func (f *federatedRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	return f.local.registry.Register(s, opts...)
+}
+
+// Deregister deregisters from the local registry.
+func (f *federatedRegistry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
+	return f.local.registry.Deregister(s, opts...)
+}
+
+// GetService returns the local services, or the services of the remote
+// datacenters if there are no local nodes. With the Datacenter option only
+// that datacenter is asked.
+func (f *federatedRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	var o registry.GetOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.Context != nil {
+		if dc, ok := o.Context.Value(datacenterKey{}).(string); ok && len(dc) > 0 {
+			r := f.datacenter(dc)
+			if r == nil {
+				return nil, registry.ErrNotFound
+			}
+			services, err := r.registry.GetService(name, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return tag(services, r.datacenter), nil
+		}
+	}
+
+	services, err := f.local.registry.GetService(name, opts...)
+	if err == nil && countNodes(services) > 0 {
+		return tag(services, f.local.datacenter), nil
+	}
+	if err != nil && !errors.Is(err, registry.ErrNotFound) {
+		log.Errorf("[federation] Error getting %s from %s: %v", name, f.local.datacenter, err)
+	}
+
+	// failover to the remote datacenters
+	results := make([][]*registry.Service, len(f.remotes))
+	errs := make([]error, len(f.remotes))
+
+	var wg sync.WaitGroup
+	for i, r := range f.remotes {
+		wg.Add(1)
+		go func(i int, r *remote) {
+			defer wg.Done()
+			svcs, err := r.registry.GetService(name, opts...)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = tag(svcs, r.datacenter)
+		}(i, r)
+	}
+	wg.Wait()
+
+	merged := tag(services, f.local.datacenter)
+	for i, svcs := range results {
+		if err := errs[i]; err != nil && !errors.Is(err, registry.ErrNotFound) {
+			log.Errorf("[federation] Error getting %s from %s: %v", name, f.remotes[i].datacenter, err)
+		}
+		merged = merge(merged, svcs)
+	}
+
+	if len(merged) == 0 {
+		// report the first failure, if the service wasn't just missing
+		for _, e := range append([]error{err}, errs...) {
+			if e != nil && !errors.Is(e, registry.ErrNotFound) {
+				return nil, e
+			}
+		}
+		return nil, registry.ErrNotFound
+	}
+
+	return merged, nil
+}
+
+// ListServices lists the services of every datacenter.
+func (f *federatedRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	local, err := f.local.registry.ListServices(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]*registry.Service, len(f.remotes))
+
+	var wg sync.WaitGroup
+	for i, r := range f.remotes {
+		wg.Add(1)
+		go func(i int, r *remote) {
+			defer wg.Done()
+			svcs, err := r.registry.ListServices(opts...)
+			if err != nil {
+				log.Errorf("[federation] Error listing services of %s: %v", r.datacenter, err)
+				return
+			}
+			results[i] = svcs
+		}(i, r)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	var services []*registry.Service
+	for _, svcs := range append([][]*registry.Service{local}, results...) {
+		for _, s := range svcs {
+			if seen[s.Name+":"+s.Version] {
+				continue
+			}
+			seen[s.Name+":"+s.Version] = true
+			services = append(services, s)
+		}
+	}
+
+	return services, nil
+}
+
+// Watch watches every datacenter, results are tagged with their datacenter.
+// Remote results are only sent for services without local nodes, as with
+// GetService, and remote datacenters failing to be watched are retried.
+func (f *federatedRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	return newWatcher(f, opts...)
+}
+
+func (f *federatedRegistry) String() string {
+	return "federation"
+}
+
+func (f *federatedRegistry) datacenter(dc string) *remote {
+	if f.local.datacenter == dc {
+		return f.local
+	}
+	for _, r := range f.remotes {
+		if r.datacenter == dc {
+			return r
+		}
+	}
+	return nil
+}
+
+func countNodes(services []*registry.Service) int {
+	var n int
+	for _, s := range services {
+		n += len(s.Nodes)
+	}
+	return n
+}
+
+// tag returns copies of the services with the datacenter set on their nodes,
+// unless already set, eg: by the registry of a remote federation.
+func tag(services []*registry.Service, dc string) []*registry.Service {
+	tagged := make([]*registry.Service, 0, len(services))
+	for _, s := range services {
+		cp := *s
+		cp.Nodes = make([]*registry.Node, len(s.Nodes))
+		for i, n := range s.Nodes {
+			node := *n
+			node.Metadata = make(map[string]string, len(n.Metadata)+1)
+			for k, v := range n.Metadata {
+				node.Metadata[k] = v
+			}
+			if _, ok := node.Metadata[DatacenterKey]; !ok {
+				node.Metadata[DatacenterKey] = dc
+			}
+			cp.Nodes[i] = &node
+		}
+		tagged = append(tagged, &cp)
+	}
+	return tagged
+}
+
+// merge adds the versions and nodes of services to others.
+func merge(services, add []*registry.Service) []*registry.Service {
+	for _, s := range add {
+		var found *registry.Service
+		for _, cur := range services {
+			if cur.Version == s.Version {
+				found = cur
+				break
+			}
+		}
+		if found == nil {
+			services = append(services, s)
+			continue
+		}
+		found.Nodes = append(found.Nodes, s.Nodes...)
+	}
+	return services
+}
+
+// Prefer is a selector filter keeping the nodes of a datacenter, or every
+// node while it has none.
+func Prefer(dc string) selector.Filter {
+	return func(services []*registry.Service) []*registry.Service {
+		var filtered []*registry.Service
+		for _, s := range services {
+			var nodes []*registry.Node
+			for _, n := range s.Nodes {
+				if n.Metadata[DatacenterKey] == dc {
+					nodes = append(nodes, n)
+				}
+			}
+			if len(nodes) == 0 {
+				continue
+			}
+			cp := *s
+			cp.Nodes = nodes
+			filtered = append(filtered, &cp)
+		}
+
+		if len(filtered) == 0 {
+			return services
+		}
+		return filtered
+	}
+}
+
+// NewRegistry returns a registry federating the local registry with the
+// registries of remote datacenters.
+func NewRegistry(opts ...registry.Option) registry.Registry {
+	f := &federatedRegistry{
+		opts: registry.Options{
+			Context: context.Background(),
+		},
+	}
+
+	if err := configure(f, opts...); err != nil {
+		log.Fatalf("[federation] Error configuring registry: %v", err)
+	}
+
+	return f
+}
+
+func configure(f *federatedRegistry, opts ...registry.Option) error {
+	for _, o := range opts {
+		o(&f.opts)
+	}
+
+	f.local = &remote{datacenter: DefaultDatacenter, registry: registry.DefaultRegistry}
+	if l, ok := f.opts.Context.Value(localKey{}).(*remote); ok && l != nil {
+		f.local = l
+	}
+	f.remotes, _ = f.opts.Context.Value(remoteKey{}).([]*remote)
+
+	seen := map[string]bool{f.local.datacenter: true}
+	for _, r := range f.remotes {
+		if seen[r.datacenter] {
+			return errors.New("duplicate datacenter " + r.datacenter)
+		}
+		seen[r.datacenter] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package federation
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+var errDown = errors.New("down")
+
+// downRegistry fails every read.
+type downRegistry struct {
+	registry.Registry
+}
+
+func (downRegistry) GetService(string, ...registry.GetOption) ([]*registry.Service, error) {
+	return nil, errDown
+}
+
+func (downRegistry) ListServices(...registry.ListOption) ([]*registry.Service, error) {
+	return nil, errDown
+}
+
+func (downRegistry) Watch(...registry.WatchOption) (registry.Watcher, error) {
+	return nil, errDown
+}
+
+// flakyRegistry fails to watch a number of times.
+type flakyRegistry struct {
+	registry.Registry
+
+	sync.Mutex
+	failures int
+}
+
+func (f *flakyRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return nil, errDown
+	}
+	return f.Registry.Watch(opts...)
+}
+
+// downWatcher fails on Next.
+type downWatcher struct{}
+
+func (downWatcher) Next() (*registry.Result, error) { return nil, errDown }
+func (downWatcher) Stop()                           {}
+
+// brokenRegistry starts watchers failing a number of times.
+type brokenRegistry struct {
+	registry.Registry
+
+	sync.Mutex
+	failures int
+}
+
+func (b *brokenRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	b.Lock()
+	defer b.Unlock()
+	if b.failures > 0 {
+		b.failures--
+		return downWatcher{}, nil
+	}
+	return b.Registry.Watch(opts...)
+}
+
+func testService(name string, nodes ...string) *registry.Service {
+	s := &registry.Service{Name: name, Version: "1.0.0"}
+	for _, n := range nodes {
+		s.Nodes = append(s.Nodes, &registry.Node{Id: n, Address: n + ":8080"})
+	}
+	return s
+}
+
+func datacenters(services []*registry.Service) map[string]string {
+	dcs := make(map[string]string)
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			dcs[n.Id] = n.Metadata[DatacenterKey]
+		}
+	}
+	return dcs
+}
+
+func setup(t *testing.T) registry.Registry {
+	t.Helper()
+
+	dc1, dc2 := registry.NewMemoryRegistry(), registry.NewMemoryRegistry()
+	for _, s := range []*registry.Service{testService("foo", "foo-1"), testService("bar", "bar-1")} {
+		if err := dc1.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dc2.Register(testService("foo", "foo-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dc2.Register(testService("baz", "baz-2")); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewRegistry(
+		Local("dc1", dc1),
+		Remote("dc2", dc2),
+		Remote("dc3", downRegistry{}),
+	)
+}
+
+func TestGetService(t *testing.T) {
+	r := setup(t)
+
+	// local nodes only
+	services, err := r.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dcs := datacenters(services); len(dcs) != 1 || dcs["foo-1"] != "dc1" {
+		t.Fatalf("expected the local node, got %v", dcs)
+	}
+
+	// failover to the remote datacenters
+	services, err = r.GetService("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dcs := datacenters(services); len(dcs) != 1 || dcs["baz-2"] != "dc2" {
+		t.Fatalf("expected the remote node, got %v", dcs)
+	}
+
+	// a datacenter asked for
+	services, err = r.GetService("foo", Datacenter("dc2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dcs := datacenters(services); len(dcs) != 1 || dcs["foo-2"] != "dc2" {
+		t.Fatalf("expected the node of dc2, got %v", dcs)
+	}
+
+	if _, err := r.GetService("foo", Datacenter("dc4")); err != registry.ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	// missing everywhere, reporting the datacenter down
+	if _, err := r.GetService("qux"); !errors.Is(err, errDown) {
+		t.Fatalf("expected the remote error, got %v", err)
+	}
+}
+
+func TestListServices(t *testing.T) {
+	services, err := setup(t).ListServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 3 {
+		t.Fatalf("expected foo, bar and baz, got %d services", len(services))
+	}
+}
+
+func TestPrefer(t *testing.T) {
+	services := []*registry.Service{{
+		Name: "foo",
+		Nodes: []*registry.Node{
+			{Id: "foo-1", Metadata: map[string]string{DatacenterKey: "dc1"}},
+			{Id: "foo-2", Metadata: map[string]string{DatacenterKey: "dc2"}},
+		},
+	}}
+
+	if dcs := datacenters(Prefer("dc2")(services)); len(dcs) != 1 || dcs["foo-2"] != "dc2" {
+		t.Fatalf("expected the node of dc2, got %v", dcs)
+	}
+	if dcs := datacenters(Prefer("dc3")(services)); len(dcs) != 2 {
+		t.Fatalf("expected every node, got %v", dcs)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	watchRetryMin = 10 * time.Millisecond
+	defer func() { watchRetryMin = time.Second }()
+
+	dc1, dc2, dc3 := registry.NewMemoryRegistry(), registry.NewMemoryRegistry(), registry.NewMemoryRegistry()
+	if err := dc1.Register(testService("foo", "foo-1")); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(
+		Local("dc1", dc1),
+		Remote("dc2", dc2),
+		Remote("dc3", &flakyRegistry{Registry: dc3, failures: 2}),
+	)
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	results := make(chan *registry.Result, 10)
+	go func() {
+		for {
+			res, err := w.Next()
+			if err != nil {
+				return
+			}
+			results <- res
+		}
+	}()
+
+	next := func(action, node string) {
+		t.Helper()
+		select {
+		case res := <-results:
+			if res.Action != action || len(res.Service.Nodes) != 1 || res.Service.Nodes[0].Id != node {
+				t.Fatalf("expected %s of %s, got %s %+v", action, node, res.Action, res.Service)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s of %s", action, node)
+		}
+	}
+	register := func(r registry.Registry, s *registry.Service) {
+		t.Helper()
+		if err := r.Register(s); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// remote nodes of a service with local nodes aren't sent
+	register(dc2, testService("foo", "foo-2"))
+	register(dc2, testService("baz", "baz-2"))
+	next("update", "baz-2")
+
+	// local nodes replace the remote ones
+	register(dc1, testService("baz", "baz-1"))
+	next("update", "baz-1")
+	next("delete", "baz-2")
+
+	// and the remote ones are sent again once gone
+	if err := dc1.Deregister(testService("baz", "baz-1")); err != nil {
+		t.Fatal(err)
+	}
+	next("delete", "baz-1")
+	next("create", "baz-2")
+
+	// the watcher failing to start is retried
+	register(dc3, testService("qux", "qux-3"))
+	next("update", "qux-3")
+}
+
+func TestWatchLocalFails(t *testing.T) {
+	watchRetryMin = 10 * time.Millisecond
+	defer func() { watchRetryMin = time.Second }()
+
+	dc1 := registry.NewMemoryRegistry()
+	r := NewRegistry(Local("dc1", &brokenRegistry{Registry: dc1, failures: 2}))
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	res := make(chan *registry.Result, 1)
+	go func() {
+		if r, err := w.Next(); err == nil {
+			res <- r
+		}
+	}()
+
+	// the local watcher failing is restarted
+	time.Sleep(100 * time.Millisecond)
+	if err := dc1.Register(testService("foo", "foo-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-res:
+		if r.Action != "update" || r.Service.Nodes[0].Id != "foo-1" {
+			t.Fatalf("expected update of foo-1, got %s %+v", r.Action, r.Service)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected update of foo-1")
+	}
+}
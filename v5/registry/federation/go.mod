@@ -0,0 +1,15 @@
+module github.com/open-micro/plugins/v5/registry/federation
+
+go 1.19
+
+require go-micro.org/v5 v5.0.1
+
+require (
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/miekg/dns v1.1.61 // indirect
+	golang.org/x/mod v0.18.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/tools v0.22.0 // indirect
+)
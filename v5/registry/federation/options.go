@@ -0,0 +1,55 @@
+package federation
+
+import (
+	"context"
+
+	"go-micro.org/v5/registry"
+)
+
+type localKey struct{}
+type remoteKey struct{}
+type datacenterKey struct{}
+
+// remote is the registry of a remote datacenter.
+type remote struct {
+	datacenter string
+	registry   registry.Registry
+}
+
+// Local sets the registry of the local datacenter, services register with it.
+func Local(datacenter string, r registry.Registry) registry.Option {
+	return setRegistryOption(localKey{}, &remote{datacenter: datacenter, registry: r})
+}
+
+// Remote adds the registry of a remote datacenter, eg: a consul registry
+// querying another datacenter, or a proxy registry of another cluster.
+func Remote(datacenter string, r registry.Registry) registry.Option {
+	return func(o *registry.Options) {
+		var remotes []*remote
+		if o.Context != nil {
+			remotes, _ = o.Context.Value(remoteKey{}).([]*remote)
+		}
+		remotes = append(remotes[:len(remotes):len(remotes)], &remote{datacenter: datacenter, registry: r})
+		setRegistryOption(remoteKey{}, remotes)(o)
+	}
+}
+
+// Datacenter gets the service of a datacenter only.
+func Datacenter(dc string) registry.GetOption {
+	return func(o *registry.GetOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, datacenterKey{}, dc)
+	}
+}
+
+// helper for setting registry options.
+func setRegistryOption(k, v interface{}) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
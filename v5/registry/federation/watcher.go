@@ -0,0 +1,293 @@
+package federation
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "go-micro.org/v5/logger"
+	"go-micro.org/v5/registry"
+)
+
+var (
+	// watchRetryMin and watchRetryMax bound the backoff of watchers failing
+	// to start, or failing once started.
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+// watcher merges the results of the watchers of every datacenter. As with
+// GetService, results of remote datacenters are only forwarded for services
+// without local nodes.
+type watcher struct {
+	f    *federatedRegistry
+	opts []registry.WatchOption
+	next chan *registry.Result
+	exit chan bool
+	once sync.Once
+
+	sync.Mutex
+	watchers []registry.Watcher
+	// remote services forwarded, by name
+	remote map[string][]*registry.Service
+}
+
+func newWatcher(f *federatedRegistry, opts ...registry.WatchOption) (registry.Watcher, error) {
+	w := &watcher{
+		f:      f,
+		opts:   opts,
+		next:   make(chan *registry.Result),
+		exit:   make(chan bool),
+		remote: make(map[string][]*registry.Service),
+	}
+
+	local, err := f.local.registry.Watch(opts...)
+	if err != nil {
+		return nil, err
+	}
+	go w.watch(f.local, local, true)
+
+	for _, r := range f.remotes {
+		go w.watch(r, nil, false)
+	}
+
+	return w, nil
+}
+
+// watch watches a datacenter, starting with rw if set, retrying with backoff
+// until its watcher starts, and again should it fail.
+func (w *watcher) watch(r *remote, rw registry.Watcher, local bool) {
+	backoff := watchRetryMin
+
+	for {
+		if rw == nil {
+			var err error
+			if rw, err = r.registry.Watch(w.opts...); err != nil {
+				log.Errorf("[federation] Error watching %s: %v", r.datacenter, err)
+				rw = nil
+			}
+		}
+		if rw != nil {
+			if !w.run(rw, r.datacenter, local) {
+				return
+			}
+			rw, backoff = nil, watchRetryMin
+		}
+
+		select {
+		case <-w.exit:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > watchRetryMax {
+			backoff = watchRetryMax
+		}
+	}
+}
+
+// run forwards the results of a watcher until it fails, returning false once
+// the watcher is stopped.
+func (w *watcher) run(rw registry.Watcher, dc string, local bool) bool {
+	w.Lock()
+	select {
+	case <-w.exit:
+		w.Unlock()
+		rw.Stop()
+		return false
+	default:
+	}
+	w.watchers = append(w.watchers, rw)
+	w.Unlock()
+
+	w.forward(rw, dc, local)
+
+	w.Lock()
+	defer w.Unlock()
+	select {
+	case <-w.exit:
+		// stopped by Stop
+		return false
+	default:
+	}
+	for i, cur := range w.watchers {
+		if cur == rw {
+			w.watchers = append(w.watchers[:i], w.watchers[i+1:]...)
+			break
+		}
+	}
+	rw.Stop()
+	return true
+}
+
+func (w *watcher) forward(rw registry.Watcher, dc string, local bool) {
+	for {
+		res, err := rw.Next()
+		if err != nil {
+			select {
+			case <-w.exit:
+			default:
+				log.Errorf("[federation] Error watching %s: %v", dc, err)
+			}
+			return
+		}
+		if res.Service != nil {
+			res.Service = tag([]*registry.Service{res.Service}, dc)[0]
+		}
+
+		var results []*registry.Result
+		switch {
+		case res.Service == nil:
+			results = []*registry.Result{res}
+		case local:
+			results = w.local(res)
+		default:
+			results = w.fromRemote(res)
+		}
+
+		for _, r := range results {
+			select {
+			case w.next <- r:
+			case <-w.exit:
+				return
+			}
+		}
+	}
+}
+
+// local returns the results to send for a result of the local datacenter:
+// the nodes of the remote datacenters are deleted once there are local ones,
+// and looked up again once there are none.
+func (w *watcher) local(res *registry.Result) []*registry.Result {
+	name := res.Service.Name
+	results := []*registry.Result{res}
+
+	if res.Action != "delete" {
+		if len(res.Service.Nodes) == 0 {
+			return results
+		}
+		w.Lock()
+		forwarded := w.remote[name]
+		delete(w.remote, name)
+		w.Unlock()
+
+		for _, s := range forwarded {
+			results = append(results, &registry.Result{Action: "delete", Service: s})
+		}
+		return results
+	}
+
+	if w.hasLocalNodes(name) {
+		return results
+	}
+
+	// failover to the remote datacenters
+	for _, r := range w.f.remotes {
+		services, err := r.registry.GetService(name)
+		if err != nil {
+			if !errors.Is(err, registry.ErrNotFound) {
+				log.Errorf("[federation] Error getting %s from %s: %v", name, r.datacenter, err)
+			}
+			continue
+		}
+		for _, s := range tag(services, r.datacenter) {
+			results = append(results, w.fromRemote(&registry.Result{Action: "create", Service: s})...)
+		}
+	}
+	return results
+}
+
+// fromRemote returns the result of a remote datacenter to send, if the
+// service has no local nodes, and remembers the nodes forwarded.
+func (w *watcher) fromRemote(res *registry.Result) []*registry.Result {
+	if w.hasLocalNodes(res.Service.Name) {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	name := res.Service.Name
+	if res.Action == "delete" {
+		w.remote[name] = removeNodes(w.remote[name], res.Service)
+		if len(w.remote[name]) == 0 {
+			delete(w.remote, name)
+		}
+	} else {
+		w.remote[name] = addNodes(w.remote[name], res.Service)
+	}
+
+	return []*registry.Result{res}
+}
+
+func (w *watcher) hasLocalNodes(name string) bool {
+	services, err := w.f.local.registry.GetService(name)
+	return err == nil && countNodes(services) > 0
+}
+
+func (w *watcher) Next() (*registry.Result, error) {
+	select {
+	case res := <-w.next:
+		return res, nil
+	case <-w.exit:
+		return nil, errors.New("watcher stopped")
+	}
+}
+
+func (w *watcher) Stop() {
+	w.once.Do(func() {
+		w.Lock()
+		defer w.Unlock()
+
+		close(w.exit)
+		for _, rw := range w.watchers {
+			rw.Stop()
+		}
+	})
+}
+
+// addNodes adds the nodes of s to the services of its version, replacing
+// nodes of the same id.
+func addNodes(services []*registry.Service, s *registry.Service) []*registry.Service {
+	for _, cur := range services {
+		if cur.Version != s.Version {
+			continue
+		}
+		for _, n := range s.Nodes {
+			cur.Nodes = append(removeNode(cur.Nodes, n.Id), n)
+		}
+		return services
+	}
+	cp := *s
+	cp.Nodes = append([]*registry.Node(nil), s.Nodes...)
+	return append(services, &cp)
+}
+
+// removeNodes removes the nodes of s from the services of its version, or the
+// version if s has no nodes.
+func removeNodes(services []*registry.Service, s *registry.Service) []*registry.Service {
+	var kept []*registry.Service
+	for _, cur := range services {
+		if cur.Version == s.Version {
+			if len(s.Nodes) == 0 {
+				continue
+			}
+			for _, n := range s.Nodes {
+				cur.Nodes = removeNode(cur.Nodes, n.Id)
+			}
+			if len(cur.Nodes) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, cur)
+	}
+	return kept
+}
+
+func removeNode(nodes []*registry.Node, id string) []*registry.Node {
+	for i, n := range nodes {
+		if n.Id == id {
+			return append(nodes[:i:i], nodes[i+1:]...)
+		}
+	}
+	return nodes
+}
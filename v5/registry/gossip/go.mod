@@ -5,7 +5,7 @@ go 1.19
 require (
 	github.com/golang/protobuf v1.5.4
 	github.com/google/uuid v1.6.0
-	github.com/hashicorp/memberlist v0.1.5
+	github.com/hashicorp/memberlist v0.3.1
 	github.com/mitchellh/hashstructure v1.1.0
 	go-micro.org/v5 v5.0.1
 )
@@ -2,6 +2,7 @@
 package gossip
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -70,6 +71,7 @@ type broadcast struct {
 type delegate struct {
 	queue   *memberlist.TransmitLimitedQueue
 	updates chan *update
+	monitor *monitor
 }
 
 type event struct {
@@ -78,7 +80,8 @@ type event struct {
 }
 
 type eventDelegate struct {
-	events chan *event
+	events  chan *event
+	monitor *monitor
 }
 
 func init() {
@@ -86,13 +89,21 @@ func init() {
 }
 
 func (ed *eventDelegate) NotifyJoin(n *memberlist.Node) {
-	ed.events <- &event{action: nodeActionJoin, node: n.Address()}
+	ed.monitor.join(n)
+	if ed.events != nil {
+		ed.events <- &event{action: nodeActionJoin, node: n.Address()}
+	}
 }
 func (ed *eventDelegate) NotifyLeave(n *memberlist.Node) {
-	ed.events <- &event{action: nodeActionLeave, node: n.Address()}
+	ed.monitor.leave(n)
+	if ed.events != nil {
+		ed.events <- &event{action: nodeActionLeave, node: n.Address()}
+	}
 }
 func (ed *eventDelegate) NotifyUpdate(n *memberlist.Node) {
-	ed.events <- &event{action: nodeActionUpdate, node: n.Address()}
+	if ed.events != nil {
+		ed.events <- &event{action: nodeActionUpdate, node: n.Address()}
+	}
 }
 
 type gossipRegistry struct {
@@ -104,6 +115,11 @@ type gossipRegistry struct {
 	interval    time.Duration
 	tcpInterval time.Duration
 
+	keyring *memberlist.Keyring
+	// keys of the Keys option the keyring was created with
+	keys    [][]byte
+	monitor *monitor
+
 	connectRetry   bool
 	connectTimeout time.Duration
 	sync.RWMutex
@@ -185,7 +201,9 @@ func configure(g *gossipRegistry, opts ...registry.Option) error {
 
 	// set config from options
 	if config, ok := g.options.Context.Value(configKey{}).(*memberlist.Config); ok && config != nil {
-		c = config
+		// copied, as the delegates and keyring are set below
+		cp := *config
+		c = &cp
 	}
 
 	// set address
@@ -221,8 +239,24 @@ func configure(g *gossipRegistry, opts ...registry.Option) error {
 	// set the name
 	c.Name = strings.Join([]string{"micro", hostname, uuid.New().String()}, "-")
 
-	// set a secret key if secure
-	if g.options.Secure {
+	// set the keyring or a secret key if secure
+	keys, _ := g.options.Context.Value(keysKey{}).([][]byte)
+	switch {
+	case c.Keyring != nil:
+		// keyring of the memberlist config
+	case len(keys) > 0 && !equalKeys(keys, g.keys):
+		// new keys replace the keyring
+		keyring, err := memberlist.NewKeyring(keys, keys[0])
+		if err != nil {
+			g.Unlock()
+			return err
+		}
+		c.Keyring = keyring
+		g.keys = keys
+	case g.keyring != nil:
+		// keep the keys rotated before reconfiguring
+		c.Keyring = g.keyring
+	case g.options.Secure:
 		k, ok := g.options.Context.Value(secretKey{}).([]byte)
 		if !ok {
 			// use the default secret
@@ -231,6 +265,20 @@ func configure(g *gossipRegistry, opts ...registry.Option) error {
 		c.SecretKey = k
 	}
 
+	// load the last known peers
+	if path, ok := g.options.Context.Value(peersFileKey{}).(string); ok && len(path) > 0 {
+		peers, err := readPeers(path)
+		if err != nil {
+			log.Warnf("[gossip] Registry error reading peers file %s: %v", path, err)
+		}
+		g.monitor.Lock()
+		g.monitor.peersFile = path
+		if len(peers) > 0 {
+			g.monitor.peers = peers
+		}
+		g.monitor.Unlock()
+	}
+
 	// set connect retry
 	if v, ok := g.options.Context.Value(connectRetryKey{}).(bool); ok && v {
 		g.connectRetry = true
@@ -253,13 +301,17 @@ func configure(g *gossipRegistry, opts ...registry.Option) error {
 	c.Delegate = &delegate{
 		updates: g.updates,
 		queue:   queue,
+		monitor: g.monitor,
 	}
 
+	events := &eventDelegate{
+		monitor: g.monitor,
+	}
 	if g.connectRetry {
-		c.Events = &eventDelegate{
-			events: g.events,
-		}
+		events.events = g.events
 	}
+	c.Events = events
+
 	// create the memberlist
 	m, err := memberlist.Create(c)
 	if err != nil {
@@ -277,6 +329,11 @@ func configure(g *gossipRegistry, opts ...registry.Option) error {
 	g.queue = queue
 	g.member = m
 	g.interval = c.GossipInterval
+	// memberlist creates the keyring of a secret key
+	g.keyring = c.Keyring
+
+	// monitor the cluster
+	go g.monitorLoop(g.done)
 
 	g.Unlock()
 
@@ -318,6 +375,8 @@ func (d *delegate) NotifyMsg(b []byte) {
 		return
 	}
 
+	d.monitor.countReceived(b)
+
 	go func() {
 		up := new(pb.Update)
 		if err := proto.Unmarshal(b, up); err != nil {
@@ -350,7 +409,11 @@ func (d *delegate) NotifyMsg(b []byte) {
 }
 
 func (d *delegate) GetBroadcasts(overhead, limit int) [][]byte {
-	return d.queue.GetBroadcasts(overhead, limit)
+	msgs := d.queue.GetBroadcasts(overhead, limit)
+	for _, msg := range msgs {
+		d.monitor.countSent(msg)
+	}
+	return msgs
 }
 
 func (d *delegate) LocalState(join bool) []byte {
@@ -547,6 +610,56 @@ func (g *gossipRegistry) connectLoop() {
 	}
 }
 
+// monitorLoop checks the health of the cluster, remembers the peers and
+// rejoins the last known ones when this member is alone.
+func (g *gossipRegistry) monitorLoop(done chan bool) {
+	ticker := time.NewTicker(MonitorTick)
+	defer ticker.Stop()
+
+	backoff := MonitorTick
+	var next time.Time
+
+	for {
+		g.RLock()
+		member := g.member
+		g.RUnlock()
+
+		if member != nil {
+			now := time.Now()
+			local := member.LocalNode()
+			nodes := member.Members()
+
+			g.monitor.update(now, local.Name, nodes, member.GetHealthScore())
+			if err := g.monitor.remember(local.Address(), nodes); err != nil {
+				log.Warnf("[gossip] Registry error writing peers file: %v", err)
+			}
+
+			if len(nodes) > 1 {
+				backoff = MonitorTick
+				next = time.Time{}
+			} else if peers := g.monitor.knownPeers(); len(peers) > 0 && !now.Before(next) {
+				if n, err := member.Join(peers); err != nil {
+					log.Debugf("[gossip] Registry rejoin of %v failed: %v", peers, err)
+					next = now.Add(backoff)
+					if backoff *= 2; backoff > RejoinInterval {
+						backoff = RejoinInterval
+					}
+				} else {
+					log.Infof("[gossip] Registry rejoined %d of the last known peers", n)
+				}
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case <-g.options.Context.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (g *gossipRegistry) expiryLoop(updates *updates) {
 	ticker := time.NewTicker(ExpiryTick)
 	defer ticker.Stop()
@@ -820,6 +933,33 @@ func (g *gossipRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher,
 	return newGossipWatcher(n, e, opts...)
 }
 
+// Keyring returns the keyring encrypting gossip, or nil if it is not
+// encrypted. Keys added, used or removed take effect straight away, so a
+// key is rotated by adding the new key on every member, then using it and
+// finally removing the old one.
+func (g *gossipRegistry) Keyring() *memberlist.Keyring {
+	g.RLock()
+	defer g.RUnlock()
+	return g.keyring
+}
+
+func equalKeys(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Metrics returns the health of the cluster as seen by this member.
+func (g *gossipRegistry) Metrics() Metrics {
+	return g.monitor.metrics()
+}
+
 func (g *gossipRegistry) String() string {
 	return "gossip"
 }
@@ -835,6 +975,7 @@ func NewRegistry(opts ...registry.Option) registry.Registry {
 		services: make(map[string][]*registry.Service),
 		watchers: make(map[string]chan *registry.Result),
 		members:  make(map[string]int32),
+		monitor:  newMonitor(),
 	}
 	// run the updater
 	go g.run()
@@ -1,7 +1,9 @@
 package gossip
 
 import (
+	"bytes"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -213,3 +215,134 @@ func TestGossipRegistryRetry(t *testing.T) {
 	r1.(*gossipRegistry).Stop()
 	r2.(*gossipRegistry).Stop()
 }
+
+func TestGossipRegistryKeyRotation(t *testing.T) {
+	if tr := os.Getenv("TRAVIS"); len(tr) > 0 {
+		t.Skip()
+	}
+
+	oldKey := []byte("micro-gossip-old")
+	newKey := []byte("micro-gossip-new")
+
+	r1 := newRegistry(Config(newMemberlistConfig()), Address("127.0.0.1:54331"), Keys(oldKey))
+	r2 := newRegistry(Config(newMemberlistConfig()), Address("127.0.0.1:54332"), Keys(oldKey), registry.Addrs("127.0.0.1:54331"))
+
+	defer r1.(*gossipRegistry).Stop()
+	defer r2.(*gossipRegistry).Stop()
+
+	rings := []*memberlist.Keyring{r1.(*gossipRegistry).Keyring(), r2.(*gossipRegistry).Keyring()}
+	for _, ring := range rings {
+		if err := ring.AddKey(newKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ring := range rings {
+		if err := ring.UseKey(newKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ring := range rings {
+		if err := ring.RemoveKey(oldKey); err != nil {
+			t.Fatal(err)
+		}
+		if keys := ring.GetKeys(); len(keys) != 1 || !bytes.Equal(keys[0], newKey) {
+			t.Fatalf("Expected only the new key, got %q", keys)
+		}
+	}
+
+	svc := &registry.Service{Name: "service.1", Version: "0.0.0.1"}
+	if err := r1.Register(svc, registry.RegisterTTL(10*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r2.GetService("service.1"); err != nil {
+		t.Fatalf("[gossip registry] service.1 not found in r2 after key rotation: %v", err)
+	}
+	if m := r2.(*gossipRegistry).Metrics(); m.MessagesReceived == 0 {
+		t.Fatalf("[gossip registry] unexpected metrics after key rotation: %+v", m)
+	}
+}
+
+func TestGossipRegistryKeysInit(t *testing.T) {
+	if tr := os.Getenv("TRAVIS"); len(tr) > 0 {
+		t.Skip()
+	}
+
+	oldKey := []byte("micro-gossip-old")
+	newKey := []byte("micro-gossip-new")
+
+	r := newRegistry(Config(newMemberlistConfig()), Address("127.0.0.1:54341"), Keys(oldKey))
+	defer r.(*gossipRegistry).Stop()
+
+	if err := r.(*gossipRegistry).Keyring().AddKey(newKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// rotated keys are kept
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if keys := r.(*gossipRegistry).Keyring().GetKeys(); len(keys) != 2 {
+		t.Fatalf("Expected the rotated keys to be kept, got %q", keys)
+	}
+
+	// new keys replace them
+	if err := r.Init(Keys(newKey)); err != nil {
+		t.Fatal(err)
+	}
+	if keys := r.(*gossipRegistry).Keyring().GetKeys(); len(keys) != 1 || !bytes.Equal(keys[0], newKey) {
+		t.Fatalf("Expected only the new key, got %q", keys)
+	}
+}
+
+func TestGossipRegistryRejoin(t *testing.T) {
+	if tr := os.Getenv("TRAVIS"); len(tr) > 0 {
+		t.Skip()
+	}
+
+	tick := MonitorTick
+	MonitorTick = 100 * time.Millisecond
+	defer func() { MonitorTick = tick }()
+
+	path := filepath.Join(t.TempDir(), "peers.json")
+
+	r1 := newRegistry(Config(newMemberlistConfig()), Address("127.0.0.1:54333"))
+	defer r1.(*gossipRegistry).Stop()
+
+	r2 := newRegistry(Config(newMemberlistConfig()), Address("127.0.0.1:54334"), PeersFile(path), registry.Addrs("127.0.0.1:54333"))
+
+	wait := func(fn func() bool) bool {
+		for i := 0; i < 50; i++ {
+			if fn() {
+				return true
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return false
+	}
+
+	if !wait(func() bool {
+		peers, _ := readPeers(path)
+		return len(peers) == 1 && peers[0] == "127.0.0.1:54333"
+	}) {
+		t.Fatal("[gossip registry] peers file not written")
+	}
+
+	r2.(*gossipRegistry).Stop()
+
+	// no addresses, only the last known peers
+	r3 := newRegistry(Config(newMemberlistConfig()), Address("127.0.0.1:54334"), PeersFile(path))
+	defer r3.(*gossipRegistry).Stop()
+
+	if !wait(func() bool { return r3.(*gossipRegistry).Metrics().Members == 2 }) {
+		t.Fatal("[gossip registry] failed to rejoin the last known peers")
+	}
+
+	svc := &registry.Service{Name: "service.1", Version: "0.0.0.1"}
+	if err := r1.Register(svc, registry.RegisterTTL(10*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r3.GetService("service.1"); err != nil {
+		t.Fatalf("[gossip registry] service.1 not found after rejoin: %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package gossip
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+	log "go-micro.org/v5/logger"
+)
+
+var (
+	// MonitorTick is how often the cluster health is checked.
+	MonitorTick = time.Second * 5
+	// PartitionWindow is how long lost members count towards detecting a
+	// partition.
+	PartitionWindow = time.Minute
+	// RejoinInterval caps the backoff between attempts to rejoin the last
+	// known peers.
+	RejoinInterval = time.Minute
+)
+
+// Metrics describe the gossip cluster as seen by this member.
+type Metrics struct {
+	// Members is the number of live members, this one included.
+	Members int
+	// Suspect are the members memberlist suspects of having failed, which
+	// are declared dead unless they refute it in time.
+	Suspect []string
+	// HealthScore is the memberlist awareness of this member. Zero is
+	// healthy, higher values mean it is struggling to keep up with probes.
+	HealthScore int
+	// Partitioned is set while half or more of the cluster was lost within
+	// the partition window.
+	Partitioned bool
+	// MessagesSent and MessagesReceived count gossiped registry updates.
+	MessagesSent     uint64
+	MessagesReceived uint64
+	// BytesSent and BytesReceived are the size of those updates.
+	BytesSent     uint64
+	BytesReceived uint64
+	// SentRate and ReceivedRate are the messages per second over the last
+	// monitor tick.
+	SentRate     float64
+	ReceivedRate float64
+}
+
+// monitor tracks the health of the cluster and the peers to rejoin.
+type monitor struct {
+	// accessed atomically, kept first to be 64-bit aligned
+	sent          uint64
+	received      uint64
+	sentBytes     uint64
+	receivedBytes uint64
+
+	sync.Mutex
+	peersFile string
+	// last known peers, excluding this member
+	peers   []string
+	suspect map[string]bool
+	// members lost, and when
+	lost        map[string]time.Time
+	partitioned bool
+	members     int
+	health      int
+
+	last         time.Time
+	lastSent     uint64
+	lastReceived uint64
+	sentRate     float64
+	receivedRate float64
+}
+
+func newMonitor() *monitor {
+	return &monitor{
+		suspect: make(map[string]bool),
+		lost:    make(map[string]time.Time),
+	}
+}
+
+func (m *monitor) countSent(b []byte) {
+	atomic.AddUint64(&m.sent, 1)
+	atomic.AddUint64(&m.sentBytes, uint64(len(b)))
+}
+
+func (m *monitor) countReceived(b []byte) {
+	atomic.AddUint64(&m.received, 1)
+	atomic.AddUint64(&m.receivedBytes, uint64(len(b)))
+}
+
+func (m *monitor) join(n *memberlist.Node) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.lost, n.Name)
+}
+
+func (m *monitor) leave(n *memberlist.Node) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.suspect, n.Name)
+	m.lost[n.Name] = time.Now()
+	log.Infof("[gossip] Registry member %s (%s) left or failed", n.Name, n.Address())
+}
+
+// update checks the members for suspects and partitions and computes the
+// message rates since the last update. Suspects are the members in the
+// suspect state of memberlist, whose probes failed directly and indirectly.
+func (m *monitor) update(now time.Time, local string, nodes []*memberlist.Node, health int) {
+	m.Lock()
+	defer m.Unlock()
+
+	suspect := make(map[string]bool)
+	for _, n := range nodes {
+		if n.Name == local || n.State != memberlist.StateSuspect {
+			continue
+		}
+		suspect[n.Name] = true
+		if !m.suspect[n.Name] {
+			log.Warnf("[gossip] Registry member %s (%s) is suspect", n.Name, n.Address())
+		}
+	}
+	for name := range m.suspect {
+		if !suspect[name] {
+			log.Infof("[gossip] Registry member %s is no longer suspect", name)
+		}
+	}
+	m.suspect = suspect
+
+	for name, at := range m.lost {
+		if now.Sub(at) > PartitionWindow {
+			delete(m.lost, name)
+		}
+	}
+
+	total := len(nodes) + len(m.lost)
+	partitioned := len(m.lost) > 0 && total > 2 && len(m.lost)*2 >= total
+	switch {
+	case partitioned && !m.partitioned:
+		log.Warnf("[gossip] Registry possible network partition, lost %d of %d members within %v", len(m.lost), total, PartitionWindow)
+	case !partitioned && m.partitioned:
+		log.Infof("[gossip] Registry network partition healed, %d members", len(nodes))
+	}
+	m.partitioned = partitioned
+
+	switch {
+	case health > 0 && m.health == 0:
+		log.Warnf("[gossip] Registry health score degraded to %d", health)
+	case health == 0 && m.health > 0:
+		log.Infof("[gossip] Registry health score recovered")
+	}
+	m.health = health
+	m.members = len(nodes)
+
+	sent := atomic.LoadUint64(&m.sent)
+	received := atomic.LoadUint64(&m.received)
+	if elapsed := now.Sub(m.last).Seconds(); !m.last.IsZero() && elapsed > 0 {
+		m.sentRate = float64(sent-m.lastSent) / elapsed
+		m.receivedRate = float64(received-m.lastReceived) / elapsed
+	}
+	m.last, m.lastSent, m.lastReceived = now, sent, received
+}
+
+// remember records the peers of this member, persisting them if they
+// changed. An empty list is never recorded so the last known peers are
+// kept while isolated.
+func (m *monitor) remember(local string, nodes []*memberlist.Node) error {
+	var peers []string
+	for _, n := range nodes {
+		if addr := n.Address(); addr != local {
+			peers = append(peers, addr)
+		}
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+	sort.Strings(peers)
+
+	m.Lock()
+	defer m.Unlock()
+	if equalPeers(peers, m.peers) {
+		return nil
+	}
+	m.peers = peers
+	if len(m.peersFile) == 0 {
+		return nil
+	}
+	return writePeers(m.peersFile, peers)
+}
+
+// knownPeers returns the last known peers.
+func (m *monitor) knownPeers() []string {
+	m.Lock()
+	defer m.Unlock()
+	return append([]string(nil), m.peers...)
+}
+
+func (m *monitor) metrics() Metrics {
+	m.Lock()
+	defer m.Unlock()
+	suspect := make([]string, 0, len(m.suspect))
+	for name := range m.suspect {
+		suspect = append(suspect, name)
+	}
+	sort.Strings(suspect)
+	return Metrics{
+		Members:          m.members,
+		Suspect:          suspect,
+		HealthScore:      m.health,
+		Partitioned:      m.partitioned,
+		MessagesSent:     atomic.LoadUint64(&m.sent),
+		MessagesReceived: atomic.LoadUint64(&m.received),
+		BytesSent:        atomic.LoadUint64(&m.sentBytes),
+		BytesReceived:    atomic.LoadUint64(&m.receivedBytes),
+		SentRate:         m.sentRate,
+		ReceivedRate:     m.receivedRate,
+	}
+}
+
+func equalPeers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// readPeers reads the peers persisted to path, if any.
+func readPeers(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var peers []string
+	if err := json.Unmarshal(b, &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// writePeers atomically replaces the peers persisted to path.
+func writePeers(path string, peers []string) error {
+	b, err := json.Marshal(peers)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
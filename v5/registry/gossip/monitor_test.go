@@ -0,0 +1,155 @@
+package gossip
+
+import (
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+)
+
+func testNode(name, addr string) *memberlist.Node {
+	return &memberlist.Node{Name: name, Addr: net.ParseIP(addr), Port: 7946}
+}
+
+func TestMonitorSuspect(t *testing.T) {
+	m := newMonitor()
+
+	nodes := []*memberlist.Node{
+		testNode("local", "10.0.0.1"),
+		testNode("a", "10.0.0.2"),
+		testNode("b", "10.0.0.3"),
+	}
+	for _, n := range nodes {
+		m.join(n)
+	}
+
+	nodes[2].State = memberlist.StateSuspect
+	m.update(time.Now(), "local", nodes, 0)
+	if got := m.metrics().Suspect; !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("Expected b to be suspect, got %v", got)
+	}
+
+	// refuted
+	nodes[2].State = memberlist.StateAlive
+	m.update(time.Now(), "local", nodes, 0)
+	if got := m.metrics().Suspect; len(got) != 0 {
+		t.Fatalf("Expected no suspect members once refuted, got %v", got)
+	}
+
+	// declared dead
+	nodes[2].State = memberlist.StateSuspect
+	m.update(time.Now(), "local", nodes, 0)
+	m.leave(nodes[2])
+	if got := m.metrics().Suspect; len(got) != 0 {
+		t.Fatalf("Expected no suspect members once left, got %v", got)
+	}
+}
+
+func TestMonitorPartition(t *testing.T) {
+	m := newMonitor()
+
+	nodes := []*memberlist.Node{
+		testNode("local", "10.0.0.1"),
+		testNode("a", "10.0.0.2"),
+		testNode("b", "10.0.0.3"),
+		testNode("c", "10.0.0.4"),
+	}
+	for _, n := range nodes {
+		m.join(n)
+	}
+
+	m.leave(nodes[3])
+	m.update(time.Now(), "local", nodes[:3], 0)
+	if m.metrics().Partitioned {
+		t.Fatal("Expected a single lost member not to be a partition")
+	}
+
+	m.leave(nodes[2])
+	m.update(time.Now(), "local", nodes[:2], 0)
+	if !m.metrics().Partitioned {
+		t.Fatal("Expected losing half the cluster to be a partition")
+	}
+
+	m.join(nodes[2])
+	m.join(nodes[3])
+	m.update(time.Now(), "local", nodes, 0)
+	if m.metrics().Partitioned {
+		t.Fatal("Expected the partition to heal once members rejoin")
+	}
+
+	// lost members expire after the window
+	m.leave(nodes[2])
+	m.leave(nodes[3])
+	m.update(time.Now().Add(2*PartitionWindow), "local", nodes[:2], 0)
+	if m.metrics().Partitioned {
+		t.Fatal("Expected lost members to expire after the partition window")
+	}
+}
+
+func TestMonitorRates(t *testing.T) {
+	m := newMonitor()
+
+	now := time.Now()
+	m.update(now, "local", nil, 0)
+
+	for i := 0; i < 10; i++ {
+		m.countSent([]byte("update"))
+	}
+	m.countReceived([]byte("update"))
+	m.update(now.Add(2*time.Second), "local", nil, 2)
+
+	metrics := m.metrics()
+	if metrics.MessagesSent != 10 || metrics.BytesSent != 60 {
+		t.Fatalf("Expected 10 messages and 60 bytes sent, got %d and %d", metrics.MessagesSent, metrics.BytesSent)
+	}
+	if metrics.MessagesReceived != 1 || metrics.BytesReceived != 6 {
+		t.Fatalf("Expected 1 message and 6 bytes received, got %d and %d", metrics.MessagesReceived, metrics.BytesReceived)
+	}
+	if metrics.SentRate != 5 || metrics.ReceivedRate != 0.5 {
+		t.Fatalf("Expected rates of 5 and 0.5, got %v and %v", metrics.SentRate, metrics.ReceivedRate)
+	}
+	if metrics.HealthScore != 2 {
+		t.Fatalf("Expected health score 2, got %d", metrics.HealthScore)
+	}
+}
+
+func TestMonitorRemember(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gossip", "peers.json")
+
+	m := newMonitor()
+	m.peersFile = path
+
+	local := testNode("local", "10.0.0.1")
+	nodes := []*memberlist.Node{local, testNode("b", "10.0.0.3"), testNode("a", "10.0.0.2")}
+
+	if err := m.remember(local.Address(), nodes); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"10.0.0.2:7946", "10.0.0.3:7946"}
+	peers, err := readPeers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(peers, want) {
+		t.Fatalf("Expected peers %v, got %v", want, peers)
+	}
+
+	// alone, the last known peers are kept
+	if err := m.remember(local.Address(), nodes[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if peers, _ := readPeers(path); !reflect.DeepEqual(peers, want) {
+		t.Fatalf("Expected peers %v to be kept, got %v", want, peers)
+	}
+	if got := m.knownPeers(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected known peers %v, got %v", want, got)
+	}
+
+	if peers, err := readPeers(filepath.Join(t.TempDir(), "missing.json")); err != nil || peers != nil {
+		t.Fatalf("Expected no peers for a missing file, got %v: %v", peers, err)
+	}
+}
@@ -14,6 +14,8 @@ type configKey struct{}
 type advertiseKey struct{}
 type connectTimeoutKey struct{}
 type connectRetryKey struct{}
+type keysKey struct{}
+type peersFileKey struct{}
 
 // helper for setting registry options.
 func setRegistryOption(k, v interface{}) registry.Option {
@@ -56,3 +58,17 @@ func ConnectTimeout(td time.Duration) registry.Option {
 func ConnectRetry(v bool) registry.Option {
 	return setRegistryOption(connectRetryKey{}, v)
 }
+
+// Keys sets the keyring used to encrypt gossip. The primary key encrypts
+// outgoing messages while every key is tried on incoming ones, so members
+// can roll to a new key without downtime. Keys take precedence over Secret
+// and enable encryption whether or not the registry is secure.
+func Keys(primary []byte, secondary ...[]byte) registry.Option {
+	return setRegistryOption(keysKey{}, append([][]byte{primary}, secondary...))
+}
+
+// PeersFile persists the last known peers to the given path. They are
+// rejoined on start and whenever this member finds itself alone.
+func PeersFile(path string) registry.Option {
+	return setRegistryOption(peersFileKey{}, path)
+}
@@ -0,0 +1,89 @@
+// Package health adds readiness and draining states to registered nodes
+package health
+
+import (
+	"go-micro.org/v5/registry"
+)
+
+// MetadataKey is the node metadata key holding the status.
+const MetadataKey = "micro-status"
+
+// Status is the state of a registered node.
+type Status string
+
+const (
+	// Starting nodes are registered but don't serve yet.
+	Starting Status = "starting"
+	// Ready nodes serve requests.
+	Ready Status = "ready"
+	// Draining nodes finish their requests before deregistering.
+	Draining Status = "draining"
+)
+
+// StatusOf returns the status of a node, nodes without one are ready.
+func StatusOf(n *registry.Node) Status {
+	if n == nil || n.Metadata == nil {
+		return Ready
+	}
+	if s, ok := n.Metadata[MetadataKey]; ok && len(s) > 0 {
+		return Status(s)
+	}
+	return Ready
+}
+
+// WithStatus returns a copy of the service with the status set on its nodes.
+func WithStatus(s *registry.Service, status Status) *registry.Service {
+	cp := *s
+	cp.Nodes = make([]*registry.Node, len(s.Nodes))
+	for i, n := range s.Nodes {
+		node := *n
+		node.Metadata = make(map[string]string, len(n.Metadata)+1)
+		for k, v := range n.Metadata {
+			node.Metadata[k] = v
+		}
+		node.Metadata[MetadataKey] = string(status)
+		cp.Nodes[i] = &node
+	}
+	return &cp
+}
+
+// Filter is a selector filter keeping the ready nodes of services. Draining
+// nodes are kept while none is ready, as they still serve, starting nodes
+// never are.
+func Filter(services []*registry.Service) []*registry.Service {
+	var ready, draining bool
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			switch StatusOf(n) {
+			case Ready:
+				ready = true
+			case Draining:
+				draining = true
+			}
+		}
+	}
+
+	keep := Ready
+	if !ready && draining {
+		keep = Draining
+	}
+
+	filtered := make([]*registry.Service, 0, len(services))
+	for _, s := range services {
+		var nodes []*registry.Node
+		for _, n := range s.Nodes {
+			if StatusOf(n) == keep {
+				nodes = append(nodes, n)
+			}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+
+		cp := *s
+		cp.Nodes = nodes
+		filtered = append(filtered, &cp)
+	}
+
+	return filtered
+}
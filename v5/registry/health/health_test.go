@@ -0,0 +1,102 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+func node(id string, status Status) *registry.Node {
+	n := &registry.Node{Id: id, Address: id + ":8080"}
+	if len(status) > 0 {
+		n.Metadata = map[string]string{MetadataKey: string(status)}
+	}
+	return n
+}
+
+func nodeIDs(services []*registry.Service) []string {
+	var ids []string
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			ids = append(ids, n.Id)
+		}
+	}
+	return ids
+}
+
+func TestFilter(t *testing.T) {
+	testCases := []struct {
+		name  string
+		nodes []*registry.Node
+		want  []string
+	}{
+		{"unset", []*registry.Node{node("a", ""), node("b", Starting)}, []string{"a"}},
+		{"ready", []*registry.Node{node("a", Ready), node("b", Draining)}, []string{"a"}},
+		{"draining", []*registry.Node{node("a", Draining), node("b", Starting)}, []string{"a"}},
+		{"starting", []*registry.Node{node("a", Starting)}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			services := []*registry.Service{{Name: "foo", Nodes: tc.nodes}}
+			got := nodeIDs(Filter(services))
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("expected %v got %v", tc.want, got)
+				}
+			}
+			// the services passed in are left alone
+			if len(services[0].Nodes) != len(tc.nodes) {
+				t.Fatal("filter modified the services")
+			}
+		})
+	}
+}
+
+type recordRegistry struct {
+	registry.Registry
+	registered []*registry.Service
+}
+
+func (r *recordRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	r.registered = append(r.registered, s)
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	rec := &recordRegistry{}
+	r := NewRegistry(rec)
+
+	svc := &registry.Service{Name: "foo", Version: "1", Nodes: []*registry.Node{node("a", "")}}
+	if err := r.Register(svc); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetStatus(Ready); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Drain(ctx, time.Minute); err != context.Canceled {
+		t.Fatalf("expected the drain to be canceled got %v", err)
+	}
+
+	want := []Status{Starting, Ready, Draining}
+	if len(rec.registered) != len(want) {
+		t.Fatalf("expected %d registrations got %d", len(want), len(rec.registered))
+	}
+	for i, s := range rec.registered {
+		if got := StatusOf(s.Nodes[0]); got != want[i] {
+			t.Fatalf("registration %d: expected %s got %s", i, want[i], got)
+		}
+	}
+
+	if svc.Nodes[0].Metadata != nil {
+		t.Fatal("the registered service was modified")
+	}
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+// Registry registers services with the status of the process, starting
+// until set otherwise, and registers them again as it changes.
+//
+//	r := health.NewRegistry(consul.NewRegistry())
+//	service := micro.NewService(micro.Registry(r))
+//	...
+//	r.SetStatus(health.Ready)
+type Registry struct {
+	registry.Registry
+
+	sync.Mutex
+	status Status
+	// the services registered, by name and version, and their options
+	services map[string]*registration
+}
+
+type registration struct {
+	service *registry.Service
+	opts    []registry.RegisterOption
+}
+
+// NewRegistry wraps a registry.
+func NewRegistry(r registry.Registry) *Registry {
+	return &Registry{
+		Registry: r,
+		status:   Starting,
+		services: make(map[string]*registration),
+	}
+}
+
+func key(s *registry.Service) string {
+	return s.Name + ":" + s.Version
+}
+
+// Register registers the service with the current status.
+func (r *Registry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	r.Lock()
+	r.services[key(s)] = &registration{service: s, opts: opts}
+	status := r.status
+	r.Unlock()
+
+	return r.Registry.Register(WithStatus(s, status), opts...)
+}
+
+// Deregister deregisters the service.
+func (r *Registry) Deregister(s *registry.Service, opts ...registry.DeregisterOption) error {
+	r.Lock()
+	delete(r.services, key(s))
+	status := r.status
+	r.Unlock()
+
+	// registries compare nodes to find them, pass the registered ones
+	return r.Registry.Deregister(WithStatus(s, status), opts...)
+}
+
+// Status returns the current status.
+func (r *Registry) Status() Status {
+	r.Lock()
+	defer r.Unlock()
+	return r.status
+}
+
+// SetStatus sets the status and registers the services with it.
+func (r *Registry) SetStatus(status Status) error {
+	r.Lock()
+	r.status = status
+	regs := make([]*registration, 0, len(r.services))
+	for _, reg := range r.services {
+		regs = append(regs, reg)
+	}
+	r.Unlock()
+
+	for _, reg := range regs {
+		if err := r.Registry.Register(WithStatus(reg.service, status), reg.opts...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Drain sets the draining status and waits for the grace period, or the
+// context to be done, for clients to stop selecting the nodes. Services are
+// deregistered afterwards, usually by stopping the server.
+func (r *Registry) Drain(ctx context.Context, grace time.Duration) error {
+	if err := r.SetStatus(Draining); err != nil {
+		return err
+	}
+
+	t := time.NewTimer(grace)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Registry) String() string {
+	return r.Registry.String()
+}
@@ -47,6 +47,13 @@ var tests = []testcase{
 		Method: "GET",
 		URI:    "/api/v1/namespaces/default/pods/?labelSelector=foo%3Dbar",
 	},
+	{
+		ReqFn: func(opts *Options) *Request {
+			return NewRequest(opts).Get().Group("discovery.k8s.io/v1").Resource("endpointslices").Params(&Params{LabelSelector: map[string]string{"foo": "bar"}})
+		},
+		Method: "GET",
+		URI:    "/apis/discovery.k8s.io/v1/namespaces/default/endpointslices/?labelSelector=foo%3Dbar",
+	},
 	{
 		ReqFn: func(opts *Options) *Request {
 			return NewRequest(opts).Post().Resource("services").Name("foo").Body(map[string]string{"foo": "bar"})
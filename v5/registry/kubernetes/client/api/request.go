@@ -20,6 +20,8 @@ type Request struct {
 	method    string
 	host      string
 	namespace string
+	// api path of the resource group
+	group string
 
 	resource     string
 	resourceName *string
@@ -51,6 +53,7 @@ func NewRequest(opts *Options) *Request {
 		client:    opts.Client,
 		namespace: opts.Namespace,
 		host:      opts.Host,
+		group:     "api/v1",
 	}
 
 	if opts.BearerToken != nil {
@@ -98,6 +101,13 @@ func (r *Request) Namespace(s string) *Request {
 	return r
 }
 
+// Group sets the api group and version of the resource, such as
+// "discovery.k8s.io/v1", the core group is used by default.
+func (r *Request) Group(s string) *Request {
+	r.group = "apis/" + s
+	return r
+}
+
 // Resource is the type of resource the operation is
 // for, such as "services", "endpoints" or "pods".
 func (r *Request) Resource(s string) *Request {
@@ -150,7 +160,7 @@ func (r *Request) SetHeader(key, value string) *Request {
 
 // request builds the http.Request from the options.
 func (r *Request) request() (*http.Request, error) {
-	url := fmt.Sprintf("%s/api/v1/namespaces/%s/%s/", r.host, r.namespace, r.resource)
+	url := fmt.Sprintf("%s/%s/namespaces/%s/%s/", r.host, r.group, r.namespace, r.resource)
 
 	// append resourceName if it is present
 	if r.resourceName != nil {
@@ -7,6 +7,9 @@ type Kubernetes interface {
 	ListPods(labels map[string]string) (*PodList, error)
 	UpdatePod(podName string, pod *Pod) (*Pod, error)
 	WatchPods(labels map[string]string) (watch.Watch, error)
+	ListServices(labels map[string]string) (*ServiceList, error)
+	ListEndpointSlices(labels map[string]string) (*EndpointSliceList, error)
+	WatchEndpointSlices(labels map[string]string) (watch.Watch, error)
 }
 
 // PodList ...
@@ -23,6 +26,7 @@ type Pod struct {
 // Meta ...
 type Meta struct {
 	Name              string             `json:"name,omitempty"`
+	Namespace         string             `json:"namespace,omitempty"`
 	Labels            map[string]*string `json:"labels,omitempty"`
 	Annotations       map[string]*string `json:"annotations,omitempty"`
 	DeletionTimestamp string             `json:"deletionTimestamp,omitempty"`
@@ -30,6 +34,91 @@ type Meta struct {
 
 // Status ...
 type Status struct {
-	PodIP string `json:"podIP"`
-	Phase string `json:"phase"`
+	PodIP      string         `json:"podIP"`
+	Phase      string         `json:"phase"`
+	Conditions []PodCondition `json:"conditions,omitempty"`
+}
+
+// PodCondition ...
+type PodCondition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// ServiceList ...
+type ServiceList struct {
+	Items []Service `json:"items"`
+}
+
+// Service is a kubernetes service.
+type Service struct {
+	Metadata *Meta        `json:"metadata"`
+	Spec     *ServiceSpec `json:"spec,omitempty"`
+}
+
+// ServiceSpec ...
+type ServiceSpec struct {
+	Ports []ServicePort `json:"ports,omitempty"`
+}
+
+// ServicePort ...
+type ServicePort struct {
+	Name     string `json:"name,omitempty"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// EndpointSliceList ...
+type EndpointSliceList struct {
+	Items []EndpointSlice `json:"items"`
+}
+
+// EndpointSlice holds some of the endpoints of a service.
+type EndpointSlice struct {
+	Metadata    *Meta          `json:"metadata"`
+	AddressType string         `json:"addressType"`
+	Endpoints   []Endpoint     `json:"endpoints"`
+	Ports       []EndpointPort `json:"ports,omitempty"`
+}
+
+// Endpoint ...
+type Endpoint struct {
+	Addresses  []string           `json:"addresses"`
+	Conditions EndpointConditions `json:"conditions"`
+	Hints      *EndpointHints     `json:"hints,omitempty"`
+	NodeName   *string            `json:"nodeName,omitempty"`
+	Zone       *string            `json:"zone,omitempty"`
+	TargetRef  *ObjectReference   `json:"targetRef,omitempty"`
+}
+
+// EndpointConditions are nil when unknown.
+type EndpointConditions struct {
+	Ready       *bool `json:"ready,omitempty"`
+	Serving     *bool `json:"serving,omitempty"`
+	Terminating *bool `json:"terminating,omitempty"`
+}
+
+// EndpointHints ...
+type EndpointHints struct {
+	ForZones []ForZone `json:"forZones,omitempty"`
+}
+
+// ForZone ...
+type ForZone struct {
+	Name string `json:"name"`
+}
+
+// EndpointPort ...
+type EndpointPort struct {
+	Name        *string `json:"name,omitempty"`
+	Port        *int    `json:"port,omitempty"`
+	Protocol    *string `json:"protocol,omitempty"`
+	AppProtocol *string `json:"appProtocol,omitempty"`
+}
+
+// ObjectReference ...
+type ObjectReference struct {
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
 }
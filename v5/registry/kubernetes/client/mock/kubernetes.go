@@ -15,16 +15,21 @@ import (
 // Client ...
 type Client struct {
 	sync.RWMutex
-	Pods     map[string]*client.Pod
-	events   chan watch.Event
-	watchers []*mockWatcher
+	Pods           map[string]*client.Pod
+	Services       map[string]*client.Service
+	EndpointSlices map[string]*client.EndpointSlice
+	events         chan watch.Event
+	watchers       []*mockWatcher
+	sliceWatchers  []*mockWatcher
 }
 
 // NewClient ...
 func NewClient() *Client {
 	c := &Client{
-		Pods:   make(map[string]*client.Pod),
-		events: make(chan watch.Event),
+		Pods:           make(map[string]*client.Pod),
+		Services:       make(map[string]*client.Service),
+		EndpointSlices: make(map[string]*client.EndpointSlice),
+		events:         make(chan watch.Event),
 	}
 
 	// broadcast events to watchers
@@ -112,6 +117,97 @@ func (c *Client) WatchPods(labels map[string]string) (watch.Watch, error) {
 	return w, nil
 }
 
+// ListServices ...
+func (c *Client) ListServices(labels map[string]string) (*client.ServiceList, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var svcs client.ServiceList
+	for _, v := range c.Services {
+		if labelFilterMatch(v.Metadata.Labels, labels) {
+			svcs.Items = append(svcs.Items, *v)
+		}
+	}
+
+	return &svcs, nil
+}
+
+// ListEndpointSlices ...
+func (c *Client) ListEndpointSlices(labels map[string]string) (*client.EndpointSliceList, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var slices client.EndpointSliceList
+	for _, v := range c.EndpointSlices {
+		if labelFilterMatch(v.Metadata.Labels, labels) {
+			slices.Items = append(slices.Items, *v)
+		}
+	}
+
+	return &slices, nil
+}
+
+// WatchEndpointSlices ...
+func (c *Client) WatchEndpointSlices(labels map[string]string) (watch.Watch, error) {
+	w := &mockWatcher{
+		results: make(chan watch.Event),
+		stop:    make(chan bool),
+	}
+
+	c.Lock()
+	c.sliceWatchers = append(c.sliceWatchers, w)
+	c.Unlock()
+
+	return w, nil
+}
+
+// UpdateEndpointSlice adds or modifies a slice and notifies the watchers.
+func (c *Client) UpdateEndpointSlice(slice *client.EndpointSlice) {
+	c.Lock()
+	typ := watch.Added
+	if _, ok := c.EndpointSlices[slice.Metadata.Name]; ok {
+		typ = watch.Modified
+	}
+	c.EndpointSlices[slice.Metadata.Name] = slice
+	c.Unlock()
+
+	c.notifySlice(typ, slice)
+}
+
+// DeleteEndpointSlice deletes a slice and notifies the watchers.
+func (c *Client) DeleteEndpointSlice(name string) {
+	c.Lock()
+	slice, ok := c.EndpointSlices[name]
+	delete(c.EndpointSlices, name)
+	c.Unlock()
+
+	if ok {
+		c.notifySlice(watch.Deleted, slice)
+	}
+}
+
+func (c *Client) notifySlice(typ watch.EventType, slice *client.EndpointSlice) {
+	//nolint:errcheck
+	b, _ := json.Marshal(slice)
+
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, w := range c.sliceWatchers {
+		// stopped watchers have their results closed
+		select {
+		case <-w.stop:
+			continue
+		default:
+		}
+
+		select {
+		case <-w.stop:
+		case w.results <- watch.Event{Type: typ, Object: json.RawMessage(b)}:
+		}
+	}
+}
+
 // Teardown ...
 func Teardown(c *Client) {
 	for _, p := range c.Pods {
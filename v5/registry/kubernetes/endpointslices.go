@@ -0,0 +1,550 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-micro.org/v5/logger"
+	"go-micro.org/v5/registry"
+
+	"github.com/open-micro/plugins/v5/registry/health"
+	"github.com/open-micro/plugins/v5/registry/kubernetes/client"
+	"github.com/open-micro/plugins/v5/registry/kubernetes/client/watch"
+)
+
+var (
+	// used on k8s services to name the micro service and version they
+	// serve, the k8s service name and "latest" by default.
+	labelNameKey    = "micro.mu/name"
+	labelVersionKey = "micro.mu/version"
+
+	// used on k8s services to name the port nodes are addressed by, the
+	// first port of the endpoints by default.
+	annotationPortKey = "micro.mu/port"
+
+	// used on k8s services to set node metadata,
+	// eg: micro.mu/metadata-protocol: grpc
+	annotationMetadataPrefix = "micro.mu/metadata-"
+
+	// set on endpoint slices by kubernetes.
+	labelServiceNameKey = "kubernetes.io/service-name"
+
+	defaultVersion = "latest"
+)
+
+var (
+	// ServiceRefresh is how often the watcher lists the k8s services again,
+	// so relabeled services are seen.
+	ServiceRefresh = time.Minute
+	// serviceRetry bounds how often the k8s services are listed again for a
+	// slice whose service isn't known.
+	serviceRetry = 5 * time.Second
+)
+
+// serviceSelector selects the k8s services of micro services, their labels
+// are copied to their endpoint slices.
+var serviceSelector = map[string]string{
+	labelTypeKey: labelTypeValueService,
+}
+
+// sliceRegistry discovers services from endpoint slices.
+type sliceRegistry struct {
+	*kregistry
+	zone string
+}
+
+func newSliceRegistry(k *kregistry) *sliceRegistry {
+	s := &sliceRegistry{kregistry: k}
+	if z, ok := k.options.Context.Value(zoneKey{}).(string); ok {
+		s.zone = z
+	}
+	return s
+}
+
+// Register is a noop, kubernetes adds the endpoints of ready pods.
+func (s *sliceRegistry) Register(*registry.Service, ...registry.RegisterOption) error {
+	return nil
+}
+
+// Deregister is a noop, kubernetes removes the endpoints of pods not ready.
+func (s *sliceRegistry) Deregister(*registry.Service, ...registry.DeregisterOption) error {
+	return nil
+}
+
+// GetService builds the versions of a service from the endpoint slices of
+// the k8s services serving it.
+func (s *sliceRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	svcs, err := s.client.ListServices(serviceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]*registry.Service)
+	var list []*registry.Service
+
+	for i := range svcs.Items {
+		svc := &svcs.Items[i]
+		if n, _ := microName(svc); n != name {
+			continue
+		}
+
+		slices, err := s.client.ListEndpointSlices(map[string]string{
+			labelServiceNameKey: svc.Metadata.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		rs := buildService(svc, slices.Items, s.zone)
+		if len(rs.Nodes) == 0 {
+			continue
+		}
+		if vs, ok := versions[rs.Version]; ok {
+			vs.Nodes = append(vs.Nodes, rs.Nodes...)
+			continue
+		}
+		versions[rs.Version] = rs
+		list = append(list, rs)
+	}
+
+	if len(list) == 0 {
+		return nil, registry.ErrNotFound
+	}
+
+	return list, nil
+}
+
+// ListServices lists the services of the labelled k8s services.
+func (s *sliceRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	svcs, err := s.client.ListServices(serviceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var list []*registry.Service
+
+	for i := range svcs.Items {
+		name, version := microName(&svcs.Items[i])
+		if seen[name+version] {
+			continue
+		}
+		seen[name+version] = true
+		list = append(list, &registry.Service{Name: name, Version: version})
+	}
+
+	return list, nil
+}
+
+// Watch returns a watcher of the endpoint slices.
+func (s *sliceRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	return newSliceWatcher(s, opts...)
+}
+
+func label(m *client.Meta, key string) string {
+	if m == nil {
+		return ""
+	}
+	if v, ok := m.Labels[key]; ok && v != nil {
+		return *v
+	}
+	return ""
+}
+
+func annotation(m *client.Meta, key string) string {
+	if m == nil {
+		return ""
+	}
+	if v, ok := m.Annotations[key]; ok && v != nil {
+		return *v
+	}
+	return ""
+}
+
+// microName returns the name and version of the service a k8s service serves.
+func microName(svc *client.Service) (string, string) {
+	name := label(svc.Metadata, labelNameKey)
+	if len(name) == 0 {
+		name = svc.Metadata.Name
+	}
+	version := label(svc.Metadata, labelVersionKey)
+	if len(version) == 0 {
+		version = defaultVersion
+	}
+	return name, version
+}
+
+type candidate struct {
+	endpoint client.Endpoint
+	ports    []client.EndpointPort
+	draining bool
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// buildService builds the service of a k8s service from its endpoint slices.
+//
+// Nodes are the ready endpoints or, while none is ready, the ones still
+// serving as they terminate, as kube-proxy does. With a zone set, and every
+// endpoint hinted, the endpoints hinted for the zone are used if any.
+func buildService(svc *client.Service, slices []client.EndpointSlice, zone string) *registry.Service {
+	name, version := microName(svc)
+	rs := &registry.Service{
+		Name:    name,
+		Version: version,
+		Nodes:   []*registry.Node{},
+	}
+
+	var ready, terminating []candidate
+	for _, slice := range slices {
+		for _, ep := range slice.Endpoints {
+			if len(ep.Addresses) == 0 {
+				continue
+			}
+			c := candidate{endpoint: ep, ports: slice.Ports}
+			switch {
+			// unknown readiness is ready
+			case ep.Conditions.Ready == nil || *ep.Conditions.Ready:
+				ready = append(ready, c)
+			case isTrue(ep.Conditions.Serving) && isTrue(ep.Conditions.Terminating):
+				c.draining = true
+				terminating = append(terminating, c)
+			}
+		}
+	}
+
+	candidates := ready
+	if len(candidates) == 0 {
+		candidates = terminating
+	}
+	candidates = forZone(candidates, zone)
+
+	metadata := make(map[string]string)
+	if svc.Metadata != nil {
+		for k, v := range svc.Metadata.Annotations {
+			if strings.HasPrefix(k, annotationMetadataPrefix) && v != nil {
+				metadata[strings.TrimPrefix(k, annotationMetadataPrefix)] = *v
+			}
+		}
+	}
+	portName := annotation(svc.Metadata, annotationPortKey)
+
+	for _, c := range candidates {
+		port := pickPort(c.ports, portName)
+		if port == 0 {
+			continue
+		}
+
+		addr := net.JoinHostPort(c.endpoint.Addresses[0], strconv.Itoa(port))
+		node := &registry.Node{
+			Id:       addr,
+			Address:  addr,
+			Metadata: make(map[string]string, len(metadata)+len(c.ports)+3),
+		}
+		if ref := c.endpoint.TargetRef; ref != nil && ref.Kind == "Pod" && len(ref.Name) > 0 {
+			node.Id = ref.Name
+			node.Metadata["pod"] = ref.Name
+		}
+		for k, v := range metadata {
+			node.Metadata[k] = v
+		}
+		for _, p := range c.ports {
+			if p.Name != nil && len(*p.Name) > 0 && p.Port != nil {
+				node.Metadata["port-"+*p.Name] = strconv.Itoa(*p.Port)
+			}
+		}
+		if c.endpoint.Zone != nil {
+			node.Metadata["zone"] = *c.endpoint.Zone
+		}
+		if c.endpoint.NodeName != nil {
+			node.Metadata["node"] = *c.endpoint.NodeName
+		}
+		if c.draining {
+			node.Metadata[health.MetadataKey] = string(health.Draining)
+		}
+
+		rs.Nodes = append(rs.Nodes, node)
+	}
+
+	return rs
+}
+
+// forZone returns the candidates hinted for the zone, or all of them unless
+// every one is hinted and some are for the zone.
+func forZone(candidates []candidate, zone string) []candidate {
+	if len(zone) == 0 {
+		return candidates
+	}
+
+	var hinted []candidate
+	for _, c := range candidates {
+		if c.endpoint.Hints == nil || len(c.endpoint.Hints.ForZones) == 0 {
+			return candidates
+		}
+		for _, z := range c.endpoint.Hints.ForZones {
+			if z.Name == zone {
+				hinted = append(hinted, c)
+				break
+			}
+		}
+	}
+	if len(hinted) == 0 {
+		return candidates
+	}
+	return hinted
+}
+
+// pickPort returns the port with the name, or the first port.
+func pickPort(ports []client.EndpointPort, name string) int {
+	for _, p := range ports {
+		if p.Port == nil {
+			continue
+		}
+		if len(name) == 0 || (p.Name != nil && *p.Name == name) {
+			return *p.Port
+		}
+	}
+	return 0
+}
+
+// sliceWatcher watches the endpoint slices of micro services.
+type sliceWatcher struct {
+	registry *sliceRegistry
+	watcher  watch.Watch
+	wo       registry.WatchOptions
+	// sent on and closed by the goroutine handling events only
+	next chan *registry.Result
+	exit chan struct{}
+
+	sync.Mutex
+	// slices by k8s service and slice name
+	slices map[string]map[string]*client.EndpointSlice
+	// k8s services by name, as last listed
+	k8sServices map[string]*client.Service
+	listed      time.Time
+	// the service last built from the slices of a k8s service
+	services map[string]*registry.Service
+	sync.Once
+}
+
+func newSliceWatcher(s *sliceRegistry, opts ...registry.WatchOption) (registry.Watcher, error) {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	watcher, err := s.client.WatchEndpointSlices(serviceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &sliceWatcher{
+		registry:    s,
+		watcher:     watcher,
+		wo:          wo,
+		next:        make(chan *registry.Result),
+		exit:        make(chan struct{}),
+		slices:      make(map[string]map[string]*client.EndpointSlice),
+		k8sServices: make(map[string]*client.Service),
+		services:    make(map[string]*registry.Service),
+	}
+
+	// build the current services, but dont emit them
+	slices, err := s.client.ListEndpointSlices(serviceSelector)
+	if err != nil {
+		watcher.Stop()
+		return nil, err
+	}
+	for i := range slices.Items {
+		w.update(&slices.Items[i], false)
+	}
+
+	go func() {
+		defer close(w.next)
+		for event := range watcher.ResultChan() {
+			if !w.handleEvent(event) {
+				return
+			}
+		}
+
+		w.Stop()
+	}()
+
+	return w, nil
+}
+
+// handleEvent emits the changes of an event, it returns false once the
+// watcher was stopped.
+func (w *sliceWatcher) handleEvent(event watch.Event) bool {
+	//nolint:exhaustive
+	switch event.Type {
+	case watch.Added, watch.Modified, watch.Deleted:
+	default:
+		return true
+	}
+
+	var slice client.EndpointSlice
+	if err := json.Unmarshal([]byte(event.Object), &slice); err != nil || slice.Metadata == nil {
+		logger.Error("K8s Watcher: Couldnt unmarshal event object from endpoint slice")
+		return true
+	}
+
+	for _, result := range w.update(&slice, event.Type == watch.Deleted) {
+		if len(w.wo.Service) > 0 && result.Service.Name != w.wo.Service {
+			continue
+		}
+		select {
+		case w.next <- result:
+		case <-w.exit:
+			return false
+		}
+	}
+	return true
+}
+
+// update applies a slice to the cache and returns the changes to its service.
+func (w *sliceWatcher) update(slice *client.EndpointSlice, deleted bool) []*registry.Result {
+	svcName := label(slice.Metadata, labelServiceNameKey)
+	if len(svcName) == 0 {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if deleted {
+		delete(w.slices[svcName], slice.Metadata.Name)
+	} else {
+		if w.slices[svcName] == nil {
+			w.slices[svcName] = make(map[string]*client.EndpointSlice)
+		}
+		w.slices[svcName][slice.Metadata.Name] = slice
+	}
+
+	svc, err := w.service(svcName)
+	if err != nil {
+		logger.Errorf("K8s Watcher: Couldnt get service %s: %v", svcName, err)
+		return nil
+	}
+
+	var cur *registry.Service
+	if svc != nil {
+		slices := make([]client.EndpointSlice, 0, len(w.slices[svcName]))
+		for _, s := range w.slices[svcName] {
+			slices = append(slices, *s)
+		}
+		if cur = buildService(svc, slices, w.registry.zone); len(cur.Nodes) == 0 {
+			cur = nil
+		}
+	}
+
+	prev := w.services[svcName]
+	if cur == nil {
+		delete(w.services, svcName)
+	} else {
+		w.services[svcName] = cur
+	}
+
+	return serviceResults(prev, cur)
+}
+
+// service returns a k8s service as last listed. The services are listed
+// again every ServiceRefresh, or when the service isn't known and they were
+// not listed within serviceRetry, so a slice of a service without micro
+// labels doesn't list them on every event.
+func (w *sliceWatcher) service(name string) (*client.Service, error) {
+	age := time.Since(w.listed)
+	if svc, ok := w.k8sServices[name]; age < ServiceRefresh && (ok || age < serviceRetry) {
+		return svc, nil
+	}
+
+	svcs, err := w.registry.client.ListServices(serviceSelector)
+	if err != nil {
+		return nil, err
+	}
+	w.k8sServices = make(map[string]*client.Service, len(svcs.Items))
+	for i := range svcs.Items {
+		svc := &svcs.Items[i]
+		w.k8sServices[svc.Metadata.Name] = svc
+	}
+	w.listed = time.Now()
+	return w.k8sServices[name], nil
+}
+
+// serviceResults returns the results turning the prev service into cur,
+// either of which may be nil.
+func serviceResults(prev, cur *registry.Service) []*registry.Result {
+	switch {
+	case prev == nil && cur == nil:
+		return nil
+	case prev == nil:
+		return []*registry.Result{{Action: "create", Service: cur}}
+	case cur == nil:
+		return []*registry.Result{{Action: deleteAction, Service: prev}}
+	case prev.Name != cur.Name || prev.Version != cur.Version:
+		return []*registry.Result{
+			{Action: deleteAction, Service: prev},
+			{Action: "create", Service: cur},
+		}
+	}
+
+	var results []*registry.Result
+
+	removed := *prev
+	removed.Nodes = nil
+	for _, n := range prev.Nodes {
+		if findNode(cur.Nodes, n.Id) == nil {
+			removed.Nodes = append(removed.Nodes, n)
+		}
+	}
+	if len(removed.Nodes) > 0 {
+		results = append(results, &registry.Result{Action: deleteAction, Service: &removed})
+	}
+
+	for _, n := range cur.Nodes {
+		if o := findNode(prev.Nodes, n.Id); o == nil || !reflect.DeepEqual(o, n) {
+			results = append(results, &registry.Result{Action: "update", Service: cur})
+			break
+		}
+	}
+
+	return results
+}
+
+func findNode(nodes []*registry.Node, id string) *registry.Node {
+	for _, n := range nodes {
+		if n.Id == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// Next will block until a new result comes in.
+func (w *sliceWatcher) Next() (*registry.Result, error) {
+	select {
+	case r, ok := <-w.next:
+		if !ok {
+			return nil, errors.New("result chan closed")
+		}
+		return r, nil
+	case <-w.exit:
+		return nil, errors.New("watcher stopped")
+	}
+}
+
+// Stop will cancel any requests, and close channels.
+func (w *sliceWatcher) Stop() {
+	w.Do(func() {
+		close(w.exit)
+	})
+	w.watcher.Stop()
+}
@@ -0,0 +1,277 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/registry"
+
+	"github.com/open-micro/plugins/v5/registry/health"
+	"github.com/open-micro/plugins/v5/registry/kubernetes/client"
+	"github.com/open-micro/plugins/v5/registry/kubernetes/client/mock"
+)
+
+func strPtr(s string) *string { return &s }
+func intPtr(i int) *int       { return &i }
+func boolPtr(b bool) *bool    { return &b }
+
+func setupSliceRegistry(c *mock.Client, opts ...registry.Option) *sliceRegistry {
+	o := registry.Options{Context: context.Background()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return newSliceRegistry(&kregistry{
+		client:  c,
+		timeout: time.Second,
+		options: o,
+	})
+}
+
+func testService(c *mock.Client, name, microName, version string) {
+	c.Services[name] = &client.Service{
+		Metadata: &client.Meta{
+			Name: name,
+			Labels: map[string]*string{
+				labelTypeKey:    strPtr(labelTypeValueService),
+				labelNameKey:    strPtr(microName),
+				labelVersionKey: strPtr(version),
+			},
+			Annotations: map[string]*string{
+				annotationPortKey:                     strPtr("grpc"),
+				annotationMetadataPrefix + "protocol": strPtr("grpc"),
+			},
+		},
+	}
+}
+
+func testSlice(name, svc string, endpoints ...client.Endpoint) *client.EndpointSlice {
+	return &client.EndpointSlice{
+		Metadata: &client.Meta{
+			Name: name,
+			Labels: map[string]*string{
+				labelTypeKey:        strPtr(labelTypeValueService),
+				labelServiceNameKey: strPtr(svc),
+			},
+		},
+		AddressType: "IPv4",
+		Endpoints:   endpoints,
+		Ports: []client.EndpointPort{
+			{Name: strPtr("http"), Port: intPtr(8080)},
+			{Name: strPtr("grpc"), Port: intPtr(9090)},
+		},
+	}
+}
+
+func testEndpoint(pod, ip string, ready bool, zones ...string) client.Endpoint {
+	ep := client.Endpoint{
+		Addresses:  []string{ip},
+		Conditions: client.EndpointConditions{Ready: boolPtr(ready)},
+		TargetRef:  &client.ObjectReference{Kind: "Pod", Name: pod},
+	}
+	if len(zones) > 0 {
+		ep.Hints = &client.EndpointHints{}
+		for _, z := range zones {
+			ep.Hints.ForZones = append(ep.Hints.ForZones, client.ForZone{Name: z})
+		}
+	}
+	return ep
+}
+
+func TestEndpointSlicesGetService(t *testing.T) {
+	c := mock.NewClient()
+	r := setupSliceRegistry(c)
+
+	testService(c, "foo", "foo.service", "1.0.0")
+	c.EndpointSlices["foo-abc"] = testSlice("foo-abc", "foo",
+		testEndpoint("foo-1", "10.0.0.1", true),
+		testEndpoint("foo-2", "10.0.0.2", false),
+	)
+
+	if err := r.Register(&registry.Service{Name: "foo.service"}); err != nil {
+		t.Fatalf("Register should be a noop: %v", err)
+	}
+
+	svcs, err := r.GetService("foo.service")
+	if err != nil {
+		t.Fatalf("GetService: %v", err)
+	}
+	if len(svcs) != 1 || svcs[0].Version != "1.0.0" {
+		t.Fatalf("Expected version 1.0.0 got %+v", svcs)
+	}
+	if len(svcs[0].Nodes) != 1 {
+		t.Fatalf("Expected only the ready node got %+v", svcs[0].Nodes)
+	}
+
+	node := svcs[0].Nodes[0]
+	if node.Id != "foo-1" || node.Address != "10.0.0.1:9090" {
+		t.Fatalf("Expected foo-1 on the grpc port got %+v", node)
+	}
+	if node.Metadata["protocol"] != "grpc" || node.Metadata["port-http"] != "8080" {
+		t.Fatalf("Unexpected metadata %v", node.Metadata)
+	}
+
+	if _, err := r.GetService("bar.service"); err != registry.ErrNotFound {
+		t.Fatalf("Expected not found got %v", err)
+	}
+
+	list, err := r.ListServices()
+	if err != nil || len(list) != 1 || list[0].Name != "foo.service" {
+		t.Fatalf("Expected foo.service got %+v %v", list, err)
+	}
+}
+
+func TestEndpointSlicesConditions(t *testing.T) {
+	svc := &client.Service{Metadata: &client.Meta{Name: "foo"}}
+
+	terminating := testEndpoint("foo-1", "10.0.0.1", false)
+	terminating.Conditions.Serving = boolPtr(true)
+	terminating.Conditions.Terminating = boolPtr(true)
+	slice := testSlice("foo-abc", "foo", terminating, testEndpoint("foo-2", "10.0.0.2", false))
+
+	rs := buildService(svc, []client.EndpointSlice{*slice}, "")
+	if rs.Name != "foo" || rs.Version != defaultVersion {
+		t.Fatalf("Expected default name and version got %s %s", rs.Name, rs.Version)
+	}
+	if len(rs.Nodes) != 1 || rs.Nodes[0].Id != "foo-1" || rs.Nodes[0].Address != "10.0.0.1:8080" {
+		t.Fatalf("Expected the serving node while none is ready got %+v", rs.Nodes)
+	}
+	if rs.Nodes[0].Metadata[health.MetadataKey] != string(health.Draining) {
+		t.Fatalf("Expected the terminating node to be draining got %v", rs.Nodes[0].Metadata)
+	}
+
+	slice.Endpoints = append(slice.Endpoints, testEndpoint("foo-3", "10.0.0.3", true))
+	rs = buildService(svc, []client.EndpointSlice{*slice}, "")
+	if len(rs.Nodes) != 1 || rs.Nodes[0].Id != "foo-3" {
+		t.Fatalf("Expected only the ready node got %+v", rs.Nodes)
+	}
+}
+
+func TestEndpointSlicesZone(t *testing.T) {
+	svc := &client.Service{Metadata: &client.Meta{Name: "foo"}}
+	slice := testSlice("foo-abc", "foo",
+		testEndpoint("foo-1", "10.0.0.1", true, "a"),
+		testEndpoint("foo-2", "10.0.0.2", true, "b"),
+	)
+
+	testCases := []struct {
+		zone  string
+		nodes int
+	}{
+		{"", 2},
+		{"a", 1},
+		// no endpoint hinted for the zone
+		{"c", 2},
+	}
+
+	for _, tc := range testCases {
+		rs := buildService(svc, []client.EndpointSlice{*slice}, tc.zone)
+		if len(rs.Nodes) != tc.nodes {
+			t.Fatalf("Zone %q: expected %d nodes got %d", tc.zone, tc.nodes, len(rs.Nodes))
+		}
+	}
+
+	// endpoints without hints disable the filter
+	slice.Endpoints = append(slice.Endpoints, testEndpoint("foo-3", "10.0.0.3", true))
+	if rs := buildService(svc, []client.EndpointSlice{*slice}, "a"); len(rs.Nodes) != 3 {
+		t.Fatalf("Expected 3 nodes got %d", len(rs.Nodes))
+	}
+}
+
+func TestEndpointSlicesWatch(t *testing.T) {
+	c := mock.NewClient()
+	r := setupSliceRegistry(c)
+
+	testService(c, "foo", "foo.service", "1.0.0")
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer w.Stop()
+
+	next := func(action string, nodes int) {
+		t.Helper()
+
+		res, err := w.Next()
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if res.Action != action || res.Service.Name != "foo.service" || len(res.Service.Nodes) != nodes {
+			t.Fatalf("Expected %s with %d nodes got %s %+v", action, nodes, res.Action, res.Service)
+		}
+	}
+
+	slice := testSlice("foo-abc", "foo", testEndpoint("foo-1", "10.0.0.1", true))
+	go c.UpdateEndpointSlice(slice)
+	next("create", 1)
+
+	slice = testSlice("foo-abc", "foo",
+		testEndpoint("foo-1", "10.0.0.1", true),
+		testEndpoint("foo-2", "10.0.0.2", true),
+	)
+	go c.UpdateEndpointSlice(slice)
+	next("update", 2)
+
+	slice = testSlice("foo-abc", "foo", testEndpoint("foo-2", "10.0.0.2", true))
+	go c.UpdateEndpointSlice(slice)
+	next(deleteAction, 1)
+
+	go c.DeleteEndpointSlice("foo-abc")
+	next(deleteAction, 1)
+}
+
+func TestEndpointSlicesWatchStop(t *testing.T) {
+	c := mock.NewClient()
+	r := setupSliceRegistry(c)
+
+	testService(c, "foo", "foo.service", "1.0.0")
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	// the result is never read, stopping must not panic the sender
+	go c.UpdateEndpointSlice(testSlice("foo-abc", "foo", testEndpoint("foo-1", "10.0.0.1", true)))
+	time.Sleep(50 * time.Millisecond)
+	w.Stop()
+
+	if _, err := w.Next(); err == nil {
+		t.Fatal("Expected an error after Stop")
+	}
+}
+
+func TestEndpointSlicesServiceRefresh(t *testing.T) {
+	defer func(d time.Duration) { ServiceRefresh = d }(ServiceRefresh)
+	ServiceRefresh = 10 * time.Millisecond
+
+	c := mock.NewClient()
+	r := setupSliceRegistry(c)
+
+	testService(c, "foo", "foo.service", "1.0.0")
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer w.Stop()
+
+	go c.UpdateEndpointSlice(testSlice("foo-abc", "foo", testEndpoint("foo-1", "10.0.0.1", true)))
+	if res, err := w.Next(); err != nil || res.Service.Version != "1.0.0" {
+		t.Fatalf("Expected version 1.0.0, got %+v: %v", res, err)
+	}
+
+	// relabeled, seen once the services are listed again
+	testService(c, "foo", "foo.service", "2.0.0")
+	time.Sleep(20 * time.Millisecond)
+	go c.UpdateEndpointSlice(testSlice("foo-abc", "foo", testEndpoint("foo-1", "10.0.0.1", true)))
+
+	if res, err := w.Next(); err != nil || res.Action != deleteAction || res.Service.Version != "1.0.0" {
+		t.Fatalf("Expected version 1.0.0 deleted, got %+v: %v", res, err)
+	}
+	if res, err := w.Next(); err != nil || res.Action != "create" || res.Service.Version != "2.0.0" {
+		t.Fatalf("Expected version 2.0.0 created, got %+v: %v", res, err)
+	}
+}
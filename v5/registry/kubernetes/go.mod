@@ -3,6 +3,7 @@ module github.com/open-micro/plugins/v5/registry/kubernetes
 go 1.19
 
 require (
+	github.com/open-micro/plugins/v5/registry/health v0.0.0-00010101000000-000000000000
 	github.com/pkg/errors v0.9.1
 	go-micro.org/v5 v5.0.1
 )
@@ -48,3 +49,5 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/registry/health => ../health
@@ -14,6 +14,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/open-micro/plugins/v5/registry/health"
 	"github.com/open-micro/plugins/v5/registry/kubernetes/client"
 )
 
@@ -37,7 +38,8 @@ var (
 	annotationServiceKeyPrefix = "micro.mu/service-"
 
 	// Pod status.
-	podRunning = "Running"
+	podRunning        = "Running"
+	podReadyCondition = "Ready"
 
 	// label name regex.
 	labelRe = regexp.MustCompilePOSIX("[-A-Za-z0-9_.]")
@@ -223,6 +225,8 @@ func (c *kregistry) GetService(name string, opts ...registry.GetOption) ([]*regi
 			return nil, fmt.Errorf("could not unmarshal service '%s' from pod annotation", name)
 		}
 
+		setPodStatus(&pod, &svc)
+
 		// merge up pod service & ip with versioned service.
 		vs, ok := svcs[svc.Version]
 		if !ok {
@@ -241,6 +245,35 @@ func (c *kregistry) GetService(name string, opts ...registry.GetOption) ([]*regi
 	return list, nil
 }
 
+// podReady returns whether a pod is ready, pods without a ready condition are.
+func podReady(pod *client.Pod) bool {
+	if pod.Status == nil {
+		return true
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == podReadyCondition {
+			return c.Status == "True"
+		}
+	}
+	return true
+}
+
+// setPodStatus marks the nodes of pods not ready as starting, unless draining.
+func setPodStatus(pod *client.Pod, svc *registry.Service) {
+	if podReady(pod) {
+		return
+	}
+	for _, n := range svc.Nodes {
+		if health.StatusOf(n) == health.Draining {
+			continue
+		}
+		if n.Metadata == nil {
+			n.Metadata = make(map[string]string)
+		}
+		n.Metadata[health.MetadataKey] = string(health.Starting)
+	}
+}
+
 // ListServices will list all the service names.
 func (c *kregistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
 	pods, err := c.client.ListPods(podSelector)
@@ -308,6 +341,12 @@ func NewRegistry(opts ...registry.Option) registry.Registry {
 	//nolint:errcheck,gosec
 	configure(k, opts...)
 
+	if k.options.Context != nil {
+		if ok, _ := k.options.Context.Value(endpointSlicesKey{}).(bool); ok {
+			return newSliceRegistry(k)
+		}
+	}
+
 	return k
 }
 
@@ -12,6 +12,7 @@ import (
 	"go-micro.org/v5/logger"
 	"go-micro.org/v5/registry"
 
+	"github.com/open-micro/plugins/v5/registry/health"
 	"github.com/open-micro/plugins/v5/registry/kubernetes/client"
 	"github.com/open-micro/plugins/v5/registry/kubernetes/client/mock"
 )
@@ -571,6 +572,33 @@ func teardownRegistry() {
 	mock.Teardown(mockClient)
 }
 
+func TestPodStatus(t *testing.T) {
+	testCases := []struct {
+		conditions []client.PodCondition
+		metadata   map[string]string
+		status     health.Status
+	}{
+		{nil, nil, health.Ready},
+		{[]client.PodCondition{{Type: "Ready", Status: "True"}}, nil, health.Ready},
+		{[]client.PodCondition{{Type: "Ready", Status: "False"}}, nil, health.Starting},
+		{
+			[]client.PodCondition{{Type: "Ready", Status: "False"}},
+			map[string]string{health.MetadataKey: string(health.Draining)},
+			health.Draining,
+		},
+	}
+
+	for _, tc := range testCases {
+		pod := &client.Pod{Status: &client.Status{Phase: podRunning, Conditions: tc.conditions}}
+		svc := &registry.Service{Nodes: []*registry.Node{{Id: "foo", Metadata: tc.metadata}}}
+
+		setPodStatus(pod, svc)
+		if got := health.StatusOf(svc.Nodes[0]); got != tc.status {
+			t.Fatalf("Expected %s got %s", tc.status, got)
+		}
+	}
+}
+
 func setupRegistry(_ ...registry.Option) registry.Registry {
 	return &kregistry{
 		client:  mockClient,
@@ -0,0 +1,32 @@
+package kubernetes
+
+import (
+	"context"
+
+	"go-micro.org/v5/registry"
+)
+
+type endpointSlicesKey struct{}
+type zoneKey struct{}
+
+// EndpointSlices discovers services from the endpoint slices of kubernetes
+// services rather than pod annotations. Registering is left to kubernetes,
+// so no write access to pods is needed.
+func EndpointSlices() registry.Option {
+	return setRegistryOption(endpointSlicesKey{}, true)
+}
+
+// Zone sets the zone of the service, nodes hinted for it are preferred as
+// kube-proxy does with topology aware routing.
+func Zone(z string) registry.Option {
+	return setRegistryOption(zoneKey{}, z)
+}
+
+func setRegistryOption(k, v interface{}) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
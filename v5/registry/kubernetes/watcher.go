@@ -259,6 +259,7 @@ func podBuildResult(pod *client.Pod, cache *client.Pod) ([]*registry.Result, map
 		if err := json.Unmarshal([]byte(*annVal), &rslt.Service); err != nil {
 			continue
 		}
+		setPodStatus(pod, rslt.Service)
 
 		results = append(results, rslt)
 	}
@@ -0,0 +1,156 @@
+package multi
+
+import (
+	"reflect"
+
+	"go-micro.org/v5/registry"
+)
+
+// copyService copies a service and its nodes, the nodes are shared.
+func copyService(s *registry.Service) *registry.Service {
+	c := *s
+	c.Nodes = make([]*registry.Node, len(s.Nodes))
+	copy(c.Nodes, s.Nodes)
+	return &c
+}
+
+func findVersion(svcs []*registry.Service, version string) *registry.Service {
+	for _, s := range svcs {
+		if s.Version == version {
+			return s
+		}
+	}
+	return nil
+}
+
+func findNode(nodes []*registry.Node, id string) *registry.Node {
+	for _, n := range nodes {
+		if n.Id == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// merge merges services by name and version, and their nodes by id. The sets
+// are in priority order, a service or node found in several sets is taken
+// from the first.
+func merge(sets ...[]*registry.Service) []*registry.Service {
+	var merged []*registry.Service
+	index := map[string]*registry.Service{}
+
+	for _, set := range sets {
+		for _, s := range set {
+			if s == nil {
+				continue
+			}
+			k := s.Name + "\x00" + s.Version
+			m, ok := index[k]
+			if !ok {
+				m = copyService(s)
+				m.Nodes = m.Nodes[:0]
+				index[k] = m
+				merged = append(merged, m)
+			}
+			for _, n := range s.Nodes {
+				if findNode(m.Nodes, n.Id) == nil {
+					m.Nodes = append(m.Nodes, n)
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// apply applies a watch result to the versions of a service as seen by one
+// registry.
+func apply(svcs []*registry.Service, res *registry.Result) []*registry.Service {
+	svc := res.Service
+	cur := findVersion(svcs, svc.Version)
+
+	switch res.Action {
+	case "create", "update":
+		if cur == nil {
+			return append(svcs, copyService(svc))
+		}
+		nodes := cur.Nodes
+		*cur = *copyService(svc)
+		cur.Nodes = nodes
+		for _, n := range svc.Nodes {
+			for i, o := range cur.Nodes {
+				if o.Id == n.Id {
+					cur.Nodes = append(cur.Nodes[:i], cur.Nodes[i+1:]...)
+					break
+				}
+			}
+			cur.Nodes = append(cur.Nodes, n)
+		}
+		return svcs
+	case "delete":
+		if cur == nil {
+			return svcs
+		}
+		var nodes []*registry.Node
+		for _, n := range cur.Nodes {
+			if len(svc.Nodes) > 0 && findNode(svc.Nodes, n.Id) == nil {
+				nodes = append(nodes, n)
+			}
+		}
+		if len(nodes) > 0 {
+			cur.Nodes = nodes
+			return svcs
+		}
+		var rest []*registry.Service
+		for _, s := range svcs {
+			if s != cur {
+				rest = append(rest, s)
+			}
+		}
+		return rest
+	}
+	return svcs
+}
+
+// diff returns the results turning the prev versions of a service into cur.
+// Created and changed versions are sent whole, deleted nodes on their own.
+func diff(prev, cur []*registry.Service) []*registry.Result {
+	var results []*registry.Result
+
+	for _, c := range cur {
+		p := findVersion(prev, c.Version)
+		if p == nil {
+			results = append(results, &registry.Result{Action: "create", Service: copyService(c)})
+			continue
+		}
+
+		var deleted []*registry.Node
+		for _, n := range p.Nodes {
+			if findNode(c.Nodes, n.Id) == nil {
+				deleted = append(deleted, n)
+			}
+		}
+		if len(deleted) > 0 {
+			d := copyService(p)
+			d.Nodes = deleted
+			results = append(results, &registry.Result{Action: "delete", Service: d})
+		}
+
+		changed := !reflect.DeepEqual(p.Metadata, c.Metadata) || !reflect.DeepEqual(p.Endpoints, c.Endpoints)
+		for _, n := range c.Nodes {
+			if o := findNode(p.Nodes, n.Id); o == nil || !reflect.DeepEqual(o, n) {
+				changed = true
+				break
+			}
+		}
+		if changed {
+			results = append(results, &registry.Result{Action: "update", Service: copyService(c)})
+		}
+	}
+
+	for _, p := range prev {
+		if findVersion(cur, p.Version) == nil {
+			results = append(results, &registry.Result{Action: "delete", Service: copyService(p)})
+		}
+	}
+	return results
+}
@@ -2,6 +2,8 @@ package multi
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 
 	log "go-micro.org/v5/logger"
@@ -9,9 +11,38 @@ import (
 )
 
 type multiRegistry struct {
+	sync.RWMutex
 	r    []registry.Registry
 	w    []registry.Registry
 	opts registry.Options
+
+	// the read registries failing their last call
+	failed map[registry.Registry]error
+}
+
+// RegistryError is the error of one of the registries.
+type RegistryError struct {
+	Registry registry.Registry
+	Err      error
+}
+
+func (e *RegistryError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Registry, e.Err)
+}
+
+func (e *RegistryError) Unwrap() error {
+	return e.Err
+}
+
+// DegradedError lists the registries failing while the others answer.
+type DegradedError []*RegistryError
+
+func (e DegradedError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "degraded: " + strings.Join(msgs, ", ")
 }
 
 func (m *multiRegistry) Init(opts ...registry.Option) error {
@@ -105,115 +136,119 @@ func (m *multiRegistry) Deregister(s *registry.Service, opts ...registry.Deregis
 }
 
 func (m *multiRegistry) GetService(n string, opts ...registry.GetOption) ([]*registry.Service, error) {
-	var wg sync.WaitGroup
-	var errs []error
-	var svcs []*registry.Service
-	var mu sync.Mutex
-
-	done := make(chan bool)
-	cerr := make(chan error)
-	csvc := make(chan []*registry.Service)
-
-	wg.Add(len(m.r))
-
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case err := <-cerr:
-				errs = append(errs, err)
-				wg.Done()
-			case svc := <-csvc:
-				mu.Lock()
-				svcs = append(svcs, svc...)
-				mu.Unlock()
-				wg.Done()
-			}
-		}
-	}()
-
-	for _, mr := range m.r {
-		go func(r registry.Registry) {
-			svc, err := r.GetService(n)
-			if err != nil && err != registry.ErrNotFound {
-				cerr <- err
-			} else {
-				csvc <- svc
-			}
-		}(mr)
+	var o registry.GetOptions
+	for _, opt := range opts {
+		opt(&o)
 	}
-
-	wg.Wait()
-	defer close(done)
-
-	if len(errs) > 0 {
-		return nil, errs[0]
+	var degraded *error
+	if o.Context != nil {
+		degraded, _ = o.Context.Value(degradedKey{}).(*error)
 	}
 
-	mu.Lock()
+	svcs, err := m.read(degraded, func(r registry.Registry) ([]*registry.Service, error) {
+		svcs, err := r.GetService(n, opts...)
+		if err == registry.ErrNotFound {
+			return nil, nil
+		}
+		return svcs, err
+	})
+	if err != nil {
+		return nil, err
+	}
 	if len(svcs) == 0 {
 		return nil, registry.ErrNotFound
 	}
-	mu.Unlock()
-
 	return svcs, nil
 }
 
 func (m *multiRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
-	var wg sync.WaitGroup
-	var errs []error
-	var svcs []*registry.Service
-	var mu sync.Mutex
+	var o registry.ListOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var degraded *error
+	if o.Context != nil {
+		degraded, _ = o.Context.Value(degradedKey{}).(*error)
+	}
 
-	done := make(chan bool)
-	cerr := make(chan error)
-	csvc := make(chan []*registry.Service)
+	return m.read(degraded, func(r registry.Registry) ([]*registry.Service, error) {
+		return r.ListServices(opts...)
+	})
+}
+
+// read calls fn on the read registries concurrently and merges the results in
+// priority order. Registries failing are skipped as long as one answers, and
+// reported by Degraded, and to degraded if set.
+func (m *multiRegistry) read(degraded *error, fn func(registry.Registry) ([]*registry.Service, error)) ([]*registry.Service, error) {
+	var wg sync.WaitGroup
+	sets := make([][]*registry.Service, len(m.r))
+	errs := make([]error, len(m.r))
 
 	wg.Add(len(m.r))
+	for i, mr := range m.r {
+		go func(i int, r registry.Registry) {
+			defer wg.Done()
+			sets[i], errs[i] = fn(r)
+		}(i, mr)
+	}
+	wg.Wait()
 
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case err := <-cerr:
-				errs = append(errs, err)
-				wg.Done()
-			case svc := <-csvc:
-				mu.Lock()
-				svcs = append(svcs, svc...)
-				mu.Unlock()
-				wg.Done()
-			}
+	var failed DegradedError
+	for i, err := range errs {
+		m.setStatus(m.r[i], err)
+		if err != nil {
+			failed = append(failed, &RegistryError{Registry: m.r[i], Err: err})
 		}
-	}()
-
-	for _, mr := range m.r {
-		go func(r registry.Registry) {
-			if svc, err := r.ListServices(); err != nil {
-				cerr <- err
-			} else {
-				csvc <- svc
-			}
-		}(mr)
 	}
+	if len(failed) > 0 && len(failed) == len(m.r) {
+		return nil, errs[0]
+	}
+	if degraded != nil {
+		*degraded = nil
+		if len(failed) > 0 {
+			*degraded = failed
+		}
+	}
+	return merge(sets...), nil
+}
 
-	wg.Wait()
-	defer close(done)
+// setStatus records the outcome of the last call to a read registry.
+func (m *multiRegistry) setStatus(r registry.Registry, err error) {
+	m.Lock()
+	defer m.Unlock()
 
-	if len(errs) > 0 {
-		return nil, errs[0]
+	if err == nil {
+		delete(m.failed, r)
+		return
+	}
+	if _, ok := m.failed[r]; !ok {
+		log.Warnf("[multi] %s registry failed, serving from the others: %v", r, err)
 	}
+	m.failed[r] = err
+}
 
-	mu.Lock()
-	ret := svcs
-	mu.Unlock()
-	return ret, nil
+// Degraded returns a DegradedError listing the read registries that failed
+// the last call to them, nil when all answered. The last call may be any
+// caller's, so it is only an approximation of the health of the registries,
+// use GetDegraded and ListDegraded for the outcome of a call.
+func (m *multiRegistry) Degraded() error {
+	m.RLock()
+	defer m.RUnlock()
+
+	var errs DegradedError
+	for _, r := range m.r {
+		if err, ok := m.failed[r]; ok {
+			errs = append(errs, &RegistryError{Registry: r, Err: err})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
 }
 
 func (m *multiRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
-	return newMultiWatcher(m.r, opts...)
+	return newMultiWatcher(m, opts...)
 }
 
 func (m *multiRegistry) String() string {
@@ -225,6 +260,7 @@ func NewRegistry(opts ...registry.Option) registry.Registry {
 		opts: registry.Options{
 			Context: context.Background(),
 		},
+		failed: map[registry.Registry]error{},
 	}
 
 	if err := configure(m, opts...); err != nil {
@@ -244,10 +280,25 @@ func configure(m *multiRegistry, opts ...registry.Option) error {
 		m.w = w
 	}
 
-	m.r = m.w
+	// reads go to the write registries before the read ones, unless
+	// prioritised otherwise
+	var r []registry.Registry
+	if p, ok := m.opts.Context.Value(priorityKey{}).([]registry.Registry); ok {
+		r = append(r, p...)
+	}
+	r = append(r, m.w...)
+	if rr, ok := m.opts.Context.Value(readKey{}).([]registry.Registry); ok && rr != nil {
+		r = append(r, rr...)
+	}
 
-	if r, ok := m.opts.Context.Value(readKey{}).([]registry.Registry); ok && r != nil {
-		m.r = append(m.r, r...)
+	m.r = nil
+	seen := map[registry.Registry]bool{}
+	for _, reg := range r {
+		if seen[reg] {
+			continue
+		}
+		seen[reg] = true
+		m.r = append(m.r, reg)
 	}
 	return nil
 }
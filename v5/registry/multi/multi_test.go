@@ -0,0 +1,182 @@
+package multi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+var errDown = errors.New("down")
+
+// downRegistry fails every read.
+type downRegistry struct {
+	registry.Registry
+}
+
+func (downRegistry) GetService(string, ...registry.GetOption) ([]*registry.Service, error) {
+	return nil, errDown
+}
+
+func (downRegistry) ListServices(...registry.ListOption) ([]*registry.Service, error) {
+	return nil, errDown
+}
+
+func (downRegistry) Watch(...registry.WatchOption) (registry.Watcher, error) {
+	return nil, errDown
+}
+
+func (downRegistry) String() string {
+	return "down"
+}
+
+func testService(nodes ...string) *registry.Service {
+	s := &registry.Service{Name: "foo", Version: "1.0.0"}
+	for _, n := range nodes {
+		s.Nodes = append(s.Nodes, &registry.Node{Id: n, Address: n + ":8080"})
+	}
+	return s
+}
+
+func TestMerge(t *testing.T) {
+	a := registry.NewMemoryRegistry()
+	b := registry.NewMemoryRegistry()
+	m := NewRegistry(WriteRegistry(a), ReadRegistry(b))
+
+	// registered in both during a migration
+	if err := a.Register(testService("foo-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Register(testService("foo-1", "foo-2")); err != nil {
+		t.Fatal(err)
+	}
+
+	svcs, err := m.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcs) != 1 || len(svcs[0].Nodes) != 2 {
+		t.Fatalf("expected one service with 2 nodes, got %+v", svcs)
+	}
+
+	list, err := m.ListServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected one service, got %d", len(list))
+	}
+}
+
+func TestPriority(t *testing.T) {
+	a := registry.NewMemoryRegistry()
+	b := registry.NewMemoryRegistry()
+
+	sa := testService("foo-1")
+	sa.Nodes[0].Address = "a:8080"
+	sb := testService("foo-1")
+	sb.Nodes[0].Address = "b:8080"
+	a.Register(sa)
+	b.Register(sb)
+
+	svcs, err := NewRegistry(ReadRegistry(a, b), Priority(b)).GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := svcs[0].Nodes[0].Address; addr != "b:8080" {
+		t.Fatalf("expected the node of b, got %s", addr)
+	}
+}
+
+func TestDegraded(t *testing.T) {
+	a := registry.NewMemoryRegistry()
+	a.Register(testService("foo-1"))
+	down := downRegistry{}
+
+	m := NewRegistry(ReadRegistry(down, a))
+	svcs, err := m.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcs) != 1 {
+		t.Fatalf("expected the service of the registry up, got %+v", svcs)
+	}
+
+	err = m.(interface{ Degraded() error }).Degraded()
+	var d DegradedError
+	if !errors.As(err, &d) || len(d) != 1 || d[0].Registry != down || !errors.Is(d[0], errDown) {
+		t.Fatalf("expected the down registry to be reported, got %v", err)
+	}
+
+	// per call
+	var degraded error
+	if _, err := m.ListServices(ListDegraded(&degraded)); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.As(degraded, &d) || len(d) != 1 || d[0].Registry != down {
+		t.Fatalf("expected the down registry to be reported, got %v", degraded)
+	}
+	if _, err := NewRegistry(ReadRegistry(a)).GetService("foo", GetDegraded(&degraded)); err != nil {
+		t.Fatal(err)
+	}
+	if degraded != nil {
+		t.Fatalf("expected no degradation, got %v", degraded)
+	}
+
+	if _, err := NewRegistry(ReadRegistry(down)).ListServices(); err != errDown {
+		t.Fatalf("expected %v when all registries fail, got %v", errDown, err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	a := registry.NewMemoryRegistry()
+	b := registry.NewMemoryRegistry()
+	m := NewRegistry(ReadRegistry(a, b, downRegistry{}))
+
+	w, err := m.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	results := make(chan *registry.Result, 10)
+	go func() {
+		for {
+			res, err := w.Next()
+			if err != nil {
+				return
+			}
+			results <- res
+		}
+	}()
+	next := func() *registry.Result {
+		select {
+		case res := <-results:
+			return res
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+
+	a.Register(testService("foo-1"))
+	if res := next(); res == nil || res.Action != "create" || len(res.Service.Nodes) != 1 {
+		t.Fatalf("expected the node to be created, got %+v", res)
+	}
+
+	// the same node in another registry changes nothing
+	b.Register(testService("foo-1"))
+	if res := next(); res != nil {
+		t.Fatalf("expected no result, got %+v", res)
+	}
+
+	// and it is only deleted once gone from both
+	a.Deregister(testService("foo-1"))
+	if res := next(); res != nil {
+		t.Fatalf("expected no result, got %+v", res)
+	}
+	b.Deregister(testService("foo-1"))
+	if res := next(); res == nil || res.Action != "delete" || len(res.Service.Nodes) != 1 {
+		t.Fatalf("expected the node to be deleted, got %+v", res)
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 type writeKey struct{}
 type readKey struct{}
+type priorityKey struct{}
+type degradedKey struct{}
 
 // helper for setting registry options.
 func setRegistryOption(k, v interface{}) registry.Option {
@@ -28,3 +30,32 @@ func WriteRegistry(w ...registry.Registry) registry.Option {
 func ReadRegistry(r ...registry.Registry) registry.Option {
 	return setRegistryOption(readKey{}, r)
 }
+
+// Priority orders the registries read from. Services and nodes found in
+// several registries are taken from the first, registries not listed follow
+// in the order they were added, write registries first.
+func Priority(r ...registry.Registry) registry.Option {
+	return setRegistryOption(priorityKey{}, r)
+}
+
+// GetDegraded sets err to a DegradedError listing the registries failing the
+// GetService call while others answered, or to nil.
+func GetDegraded(err *error) registry.GetOption {
+	return func(o *registry.GetOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, degradedKey{}, err)
+	}
+}
+
+// ListDegraded sets err to a DegradedError listing the registries failing the
+// ListServices call while others answered, or to nil.
+func ListDegraded(err *error) registry.ListOption {
+	return func(o *registry.ListOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, degradedKey{}, err)
+	}
+}
@@ -2,86 +2,283 @@ package multi
 
 import (
 	"sync"
+	"time"
 
 	"go-micro.org/v5/registry"
 )
 
+var (
+	// watchRetryMin and watchRetryMax bound the backoff between attempts to
+	// watch a failed registry.
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+// event is a result of one of the watched registries.
+type event struct {
+	index  int
+	result *registry.Result
+	// the watch restarted, results may have been missed
+	reset bool
+}
+
+// multiWatcher merges the results of the read registries. It keeps the view
+// every registry has of the services it saw results for, and sends the
+// changes of their merge, so a node in several registries is created once
+// and only deleted when gone from all.
 type multiWatcher struct {
-	wo   registry.WatchOptions
-	w    []registry.Watcher
-	next chan *registry.Result
-	stop chan bool
+	m      *multiRegistry
+	wo     registry.WatchOptions
+	events chan event
+	stop   chan bool
+	once   sync.Once
+
+	// the current watcher of every registry, stopped with the multi watcher
+	wmu      sync.Mutex
+	watchers []registry.Watcher
+
+	mu sync.Mutex
+	// per registry, services by name
+	views  []map[string][]*registry.Service
+	merged map[string][]*registry.Service
+	queue  []*registry.Result
 }
 
-func newMultiWatcher(r []registry.Registry, opts ...registry.WatchOption) (registry.Watcher, error) {
+func newMultiWatcher(m *multiRegistry, opts ...registry.WatchOption) (registry.Watcher, error) {
 	var wo registry.WatchOptions
 	for _, o := range opts {
 		o(&wo)
 	}
 
 	mw := &multiWatcher{
-		wo:   wo,
-		next: make(chan *registry.Result),
-		stop: make(chan bool),
+		m:        m,
+		wo:       wo,
+		events:   make(chan event),
+		stop:     make(chan bool),
+		watchers: make([]registry.Watcher, len(m.r)),
+		views:    make([]map[string][]*registry.Service, len(m.r)),
+		merged:   map[string][]*registry.Service{},
 	}
 
-	for _, wr := range r {
-		w, err := wr.Watch(opts...)
+	// registries failing to watch are retried in the background, as long
+	// as one can be watched
+	var errs []error
+	for i, r := range m.r {
+		w, err := r.Watch(opts...)
+		m.setStatus(r, err)
 		if err != nil {
-			return nil, err
+			errs = append(errs, err)
+			continue
 		}
-		mw.w = append(mw.w, w)
+		mw.watchers[i] = w
+	}
+	if len(m.r) > 0 && len(errs) == len(m.r) {
+		return nil, errs[0]
 	}
 
+	for i, r := range m.r {
+		mw.views[i] = map[string][]*registry.Service{}
+		go mw.watch(i, r, mw.watchers[i], opts...)
+	}
 	return mw, nil
 }
 
-func (mw *multiWatcher) Next() (*registry.Result, error) {
-	cerr := make(chan error)
+// watch forwards the results of a registry, watching it again when it fails.
+func (mw *multiWatcher) watch(index int, r registry.Registry, w registry.Watcher, opts ...registry.WatchOption) {
+	backoff := watchRetryMin
+	for {
+		if w == nil {
+			select {
+			case <-mw.stop:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > watchRetryMax {
+				backoff = watchRetryMax
+			}
+
+			var err error
+			w, err = r.Watch(opts...)
+			mw.m.setStatus(r, err)
+			if err != nil {
+				w = nil
+				continue
+			}
+			if !mw.setWatcher(index, w) {
+				w.Stop()
+				return
+			}
+			backoff = watchRetryMin
+			if !mw.send(event{index: index, reset: true}) {
+				w.Stop()
+				return
+			}
+		}
 
-	for _, wt := range mw.w {
-		go func(w registry.Watcher) {
-			r, err := w.Next()
-			if err != nil && err != registry.ErrNotFound {
-				cerr <- err
+		res, err := w.Next()
+		if err != nil {
+			w.Stop()
+			w = nil
+			select {
+			case <-mw.stop:
+				return
+			default:
+			}
+			if err != registry.ErrWatcherStopped {
+				mw.m.setStatus(r, err)
 			}
-			mw.next <- r
-		}(wt)
+			continue
+		}
+		if res == nil || res.Service == nil {
+			continue
+		}
+		if !mw.send(event{index: index, result: res}) {
+			w.Stop()
+			return
+		}
 	}
+}
 
+// setWatcher records the current watcher of a registry, false once stopped.
+func (mw *multiWatcher) setWatcher(index int, w registry.Watcher) bool {
+	mw.wmu.Lock()
+	defer mw.wmu.Unlock()
+
+	select {
+	case <-mw.stop:
+		return false
+	default:
+	}
+	mw.watchers[index] = w
+	return true
+}
+
+func (mw *multiWatcher) send(ev event) bool {
+	select {
+	case mw.events <- ev:
+		return true
+	case <-mw.stop:
+		return false
+	}
+}
+
+func (mw *multiWatcher) Next() (*registry.Result, error) {
 	for {
+		mw.mu.Lock()
+		if len(mw.queue) > 0 {
+			res := mw.queue[0]
+			mw.queue = mw.queue[1:]
+			mw.mu.Unlock()
+			return res, nil
+		}
+		mw.mu.Unlock()
+
 		select {
-		case err := <-cerr:
-			return nil, err
-		case r, ok := <-mw.next:
-			if !ok {
-				return nil, registry.ErrWatcherStopped
-			}
-			nr := &registry.Result{}
-			*nr = *r
-			return nr, nil
 		case <-mw.stop:
 			return nil, registry.ErrWatcherStopped
+		case ev := <-mw.events:
+			mw.apply(ev)
 		}
 	}
 }
 
-func (mw *multiWatcher) Stop() {
-	var wg sync.WaitGroup
-	wg.Add(len(mw.w))
+// apply updates the view of the registry the event came from, and queues
+// the changes of the merged service.
+func (mw *multiWatcher) apply(ev event) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
 
-	for _, w := range mw.w {
-		go func(w registry.Watcher) {
-			w.Stop()
-			wg.Done()
-		}(w)
+	if ev.reset {
+		// reload the services from the registry when next seen
+		mw.views[ev.index] = map[string][]*registry.Service{}
+		return
 	}
 
-	wg.Wait()
-	select {
-	case <-mw.stop:
+	name := ev.result.Service.Name
+	prev, seen := mw.merged[name]
+	if !seen {
+		mw.load(name)
+	} else if _, ok := mw.views[ev.index][name]; !ok {
+		mw.loadFrom(ev.index, name)
+	}
+	mw.views[ev.index][name] = apply(mw.views[ev.index][name], ev.result)
+
+	sets := make([][]*registry.Service, len(mw.views))
+	for i, v := range mw.views {
+		sets[i] = v[name]
+	}
+	cur := merge(sets...)
+	mw.merged[name] = cur
+
+	if !seen {
+		// the loaded services may include the result already, pass it on
+		// merged rather than diffed
+		mw.queue = append(mw.queue, first(ev.result, cur)...)
 		return
-	default:
-		close(mw.stop)
 	}
+	mw.queue = append(mw.queue, diff(prev, cur)...)
+}
+
+// load reads a service from every registry.
+func (mw *multiWatcher) load(name string) {
+	for i := range mw.views {
+		mw.loadFrom(i, name)
+	}
+}
+
+func (mw *multiWatcher) loadFrom(index int, name string) {
+	r := mw.m.r[index]
+	svcs, err := r.GetService(name)
+	if err == registry.ErrNotFound {
+		err = nil
+	}
+	mw.m.setStatus(r, err)
+	if err != nil {
+		return
+	}
+	cp := make([]*registry.Service, len(svcs))
+	for i, s := range svcs {
+		cp[i] = copyService(s)
+	}
+	mw.views[index][name] = cp
+}
+
+// first returns the result for a service seen for the first time.
+func first(res *registry.Result, cur []*registry.Service) []*registry.Result {
+	c := findVersion(cur, res.Service.Version)
+
+	if res.Action != "delete" {
+		if c == nil {
+			return nil
+		}
+		return []*registry.Result{{Action: res.Action, Service: copyService(c)}}
+	}
+
+	// nodes still in another registry are not deleted
+	d := copyService(res.Service)
+	d.Nodes = d.Nodes[:0]
+	for _, n := range res.Service.Nodes {
+		if c == nil || findNode(c.Nodes, n.Id) == nil {
+			d.Nodes = append(d.Nodes, n)
+		}
+	}
+	if c != nil && len(d.Nodes) == 0 {
+		return nil
+	}
+	return []*registry.Result{{Action: "delete", Service: d}}
+}
+
+func (mw *multiWatcher) Stop() {
+	mw.once.Do(func() {
+		mw.wmu.Lock()
+		close(mw.stop)
+		watchers := mw.watchers
+		mw.wmu.Unlock()
+
+		for _, w := range watchers {
+			if w != nil {
+				w.Stop()
+			}
+		}
+	})
 }
@@ -0,0 +1,65 @@
+package static
+
+import (
+	"context"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+type fileKey struct{}
+type dnsKey struct{}
+type domainKey struct{}
+type intervalKey struct{}
+type fallbackKey struct{}
+
+// File sets the yaml or json file services are read from, the first
+// registry address by default.
+func File(path string) registry.Option {
+	return setRegistryOption(fileKey{}, path)
+}
+
+// DNS adds a service resolved from a dns record, either host:port for the A
+// records of the host or the name of a SRV record, eg: _foo._tcp.example.com.
+// Names without a dot are looked up as SRV records of the Domain as the dns
+// selector does.
+func DNS(service, record string) registry.Option {
+	return func(o *registry.Options) {
+		var records map[string]string
+		if o.Context != nil {
+			records, _ = o.Context.Value(dnsKey{}).(map[string]string)
+		}
+		cp := make(map[string]string, len(records)+1)
+		for k, v := range records {
+			cp[k] = v
+		}
+		cp[service] = record
+		setRegistryOption(dnsKey{}, cp)(o)
+	}
+}
+
+// Domain sets the domain SRV records are looked up in, DefaultDomain by default.
+func Domain(d string) registry.Option {
+	return setRegistryOption(domainKey{}, d)
+}
+
+// Interval sets how often the file is polled for changes and records resolved.
+func Interval(d time.Duration) registry.Option {
+	return setRegistryOption(intervalKey{}, d)
+}
+
+// Fallback sets a registry services are registered with, and read from
+// after the static ones. Without it Register fails with ErrReadOnly.
+func Fallback(r registry.Registry) registry.Option {
+	return setRegistryOption(fallbackKey{}, r)
+}
+
+// helper for setting registry options.
+func setRegistryOption(k, v interface{}) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
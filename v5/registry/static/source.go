@@ -0,0 +1,98 @@
+package static
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"go-micro.org/v5/registry"
+)
+
+var (
+	// overridden in tests.
+	lookupHost = net.LookupHost
+	lookupSRV  = net.LookupSRV
+)
+
+// fileService is a service of the file, its nodes are resolved from the dns
+// record when set.
+type fileService struct {
+	registry.Service
+	DNS string `json:"dns,omitempty"`
+}
+
+type fileConfig struct {
+	Services []*fileService `json:"services"`
+}
+
+// parse parses the yaml or json services of a file.
+func parse(b []byte) ([]*fileService, error) {
+	var f fileConfig
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+
+	for i, s := range f.Services {
+		if s == nil || len(s.Name) == 0 {
+			return nil, fmt.Errorf("service %d has no name", i)
+		}
+		for _, n := range s.Nodes {
+			if n == nil || len(n.Address) == 0 {
+				return nil, fmt.Errorf("service %s has a node without address", s.Name)
+			}
+			if len(n.Id) == 0 {
+				n.Id = n.Address
+			}
+		}
+	}
+
+	return f.Services, nil
+}
+
+// resolve returns the nodes of a dns record, the A records of a host:port or
+// the targets of a SRV record.
+func resolve(record, domain string) ([]*registry.Node, error) {
+	var nodes []*registry.Node
+
+	if host, port, err := net.SplitHostPort(record); err == nil {
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, errors.New("invalid port " + port)
+		}
+
+		ips, err := lookupHost(host)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range ips {
+			addr := net.JoinHostPort(ip, port)
+			nodes = append(nodes, &registry.Node{Id: addr, Address: addr})
+		}
+	} else {
+		var srvs []*net.SRV
+		if strings.Contains(record, ".") {
+			_, srvs, err = lookupSRV("", "", record)
+		} else {
+			_, srvs, err = lookupSRV(record, "tcp", domain)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, srv := range srvs {
+			addr := net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port)))
+			nodes = append(nodes, &registry.Node{Id: addr, Address: addr})
+		}
+	}
+
+	// resolvers shuffle records, keep nodes stable between lookups
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+
+	return nodes, nil
+}
@@ -0,0 +1,473 @@
+// Package static provides a registry of services read from a file and dns
+package static
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go-micro.org/v5/logger"
+	"go-micro.org/v5/registry"
+	"go-micro.org/v5/util/cmd"
+)
+
+var (
+	// DefaultDomain is the domain SRV records are looked up in.
+	DefaultDomain = "local"
+	// DefaultInterval is how often the file is polled for changes and
+	// records resolved.
+	DefaultInterval = 10 * time.Second
+
+	// ErrReadOnly is returned registering without a fallback registry.
+	ErrReadOnly = errors.New("static registry is read only")
+
+	sendEventTime = 10 * time.Millisecond
+)
+
+type staticRegistry struct {
+	options registry.Options
+
+	sync.RWMutex
+	file     string
+	records  map[string]string
+	domain   string
+	interval time.Duration
+	fallback registry.Registry
+	// services by name and version
+	services map[string]map[string]*registry.Service
+	watchers map[string]*watcher
+
+	// serializes refreshes
+	refreshMu sync.Mutex
+	// the content and services last read from the file
+	content      []byte
+	fileServices []*fileService
+	// nodes last resolved by record, kept while lookups fail
+	resolved map[string][]*registry.Node
+
+	exit chan struct{}
+	once sync.Once
+}
+
+func init() {
+	cmd.DefaultRegistries["static"] = NewRegistry
+}
+
+// NewRegistry returns a registry of the services of a file and dns records,
+// reloaded every interval until it is stopped, or its context is done.
+func NewRegistry(opts ...registry.Option) registry.Registry {
+	s := &staticRegistry{
+		options: registry.Options{
+			Context: context.Background(),
+			Logger:  logger.DefaultLogger,
+		},
+		services: make(map[string]map[string]*registry.Service),
+		watchers: make(map[string]*watcher),
+		resolved: make(map[string][]*registry.Node),
+		exit:     make(chan struct{}),
+	}
+
+	s.configure(opts...)
+	s.refresh()
+
+	go s.run()
+
+	return s
+}
+
+func (s *staticRegistry) configure(opts ...registry.Option) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, o := range opts {
+		o(&s.options)
+	}
+
+	ctx := s.options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s.file, _ = ctx.Value(fileKey{}).(string)
+	if len(s.file) == 0 && len(s.options.Addrs) > 0 {
+		s.file = s.options.Addrs[0]
+	}
+	s.records, _ = ctx.Value(dnsKey{}).(map[string]string)
+	s.fallback, _ = ctx.Value(fallbackKey{}).(registry.Registry)
+
+	s.domain = DefaultDomain
+	if d, ok := ctx.Value(domainKey{}).(string); ok && len(d) > 0 {
+		s.domain = d
+	}
+	s.interval = DefaultInterval
+	if d, ok := ctx.Value(intervalKey{}).(time.Duration); ok && d > 0 {
+		s.interval = d
+	}
+}
+
+func (s *staticRegistry) run() {
+	for {
+		s.RLock()
+		interval := s.interval
+		var done <-chan struct{}
+		if s.options.Context != nil {
+			done = s.options.Context.Done()
+		}
+		s.RUnlock()
+
+		t := time.NewTimer(interval)
+		select {
+		case <-s.exit:
+			t.Stop()
+			return
+		case <-done:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		s.refresh()
+	}
+}
+
+// Stop stops reloading the file and resolving the records.
+func (s *staticRegistry) Stop() {
+	s.once.Do(func() {
+		close(s.exit)
+	})
+}
+
+// refresh reloads the file if it changed, resolves the records and sends
+// the changes to the watchers.
+func (s *staticRegistry) refresh() {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.RLock()
+	file, records, domain := s.file, s.records, s.domain
+	s.RUnlock()
+
+	log := s.options.Logger
+
+	if len(file) > 0 {
+		if b, err := os.ReadFile(file); err != nil {
+			log.Logf(logger.ErrorLevel, "Static registry failed to read %s: %v", file, err)
+		} else if !bytes.Equal(b, s.content) {
+			if svcs, err := parse(b); err != nil {
+				log.Logf(logger.ErrorLevel, "Static registry failed to parse %s: %v", file, err)
+			} else {
+				s.content = b
+				s.fileServices = svcs
+			}
+		}
+	}
+
+	cur := make(map[string]map[string]*registry.Service)
+	resolved := make(map[string][]*registry.Node)
+
+	add := func(svc *registry.Service) {
+		if cur[svc.Name] == nil {
+			cur[svc.Name] = make(map[string]*registry.Service)
+		}
+		if v, ok := cur[svc.Name][svc.Version]; ok {
+			v.Nodes = append(v.Nodes, svc.Nodes...)
+			return
+		}
+		cur[svc.Name][svc.Version] = svc
+	}
+
+	lookup := func(name, record string) []*registry.Node {
+		key := name + "/" + record
+		nodes, err := resolve(record, domain)
+		if err != nil {
+			log.Logf(logger.ErrorLevel, "Static registry failed to resolve %s for %s: %v", record, name, err)
+			nodes = s.resolved[key]
+		}
+		resolved[key] = nodes
+		return nodes
+	}
+
+	for _, fs := range s.fileServices {
+		svc := copyService(&fs.Service)
+		if len(fs.DNS) > 0 {
+			svc.Nodes = append(svc.Nodes, lookup(fs.Name, fs.DNS)...)
+		}
+		add(svc)
+	}
+
+	for name, record := range records {
+		add(&registry.Service{Name: name, Nodes: lookup(name, record)})
+	}
+
+	s.Lock()
+	prev := s.services
+	s.services = cur
+	s.resolved = resolved
+	s.Unlock()
+
+	for _, r := range diff(prev, cur) {
+		s.sendEvent(r)
+	}
+}
+
+// diff returns the results turning prev into cur: removed nodes are deleted,
+// as watchers apply updates by adding or replacing nodes.
+func diff(prev, cur map[string]map[string]*registry.Service) []*registry.Result {
+	var results []*registry.Result
+
+	for _, name := range sortedKeys(cur) {
+		for _, version := range sortedKeys(cur[name]) {
+			svc := cur[name][version]
+			old, ok := prev[name][version]
+			if !ok {
+				results = append(results, &registry.Result{Action: "create", Service: copyService(svc)})
+				continue
+			}
+
+			var deleted []*registry.Node
+			for _, n := range old.Nodes {
+				if findNode(svc.Nodes, n.Id) == nil {
+					deleted = append(deleted, n)
+				}
+			}
+			if len(deleted) > 0 {
+				d := copyService(old)
+				d.Nodes = deleted
+				results = append(results, &registry.Result{Action: "delete", Service: d})
+			}
+
+			changed := !reflect.DeepEqual(old.Metadata, svc.Metadata) || !reflect.DeepEqual(old.Endpoints, svc.Endpoints)
+			for _, n := range svc.Nodes {
+				if o := findNode(old.Nodes, n.Id); o == nil || !reflect.DeepEqual(o, n) {
+					changed = true
+					break
+				}
+			}
+			if changed {
+				results = append(results, &registry.Result{Action: "update", Service: copyService(svc)})
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(prev) {
+		for _, version := range sortedKeys(prev[name]) {
+			if _, ok := cur[name][version]; !ok {
+				results = append(results, &registry.Result{Action: "delete", Service: copyService(prev[name][version])})
+			}
+		}
+	}
+
+	return results
+}
+
+func findNode(nodes []*registry.Node, id string) *registry.Node {
+	for _, n := range nodes {
+		if n.Id == id {
+			return n
+		}
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyService(s *registry.Service) *registry.Service {
+	cp := *s
+	cp.Nodes = make([]*registry.Node, len(s.Nodes))
+	for i, n := range s.Nodes {
+		node := *n
+		cp.Nodes[i] = &node
+	}
+	return &cp
+}
+
+func (s *staticRegistry) sendEvent(r *registry.Result) {
+	s.RLock()
+	watchers := make([]*watcher, 0, len(s.watchers))
+	for _, w := range s.watchers {
+		watchers = append(watchers, w)
+	}
+	s.RUnlock()
+
+	for _, w := range watchers {
+		select {
+		case <-w.exit:
+			s.Lock()
+			delete(s.watchers, w.id)
+			s.Unlock()
+		default:
+			select {
+			case w.res <- r:
+			case <-time.After(sendEventTime):
+			}
+		}
+	}
+}
+
+func (s *staticRegistry) Init(opts ...registry.Option) error {
+	s.configure(opts...)
+	s.refresh()
+	return nil
+}
+
+func (s *staticRegistry) Options() registry.Options {
+	return s.options
+}
+
+// Register registers with the fallback registry.
+func (s *staticRegistry) Register(svc *registry.Service, opts ...registry.RegisterOption) error {
+	s.RLock()
+	fallback := s.fallback
+	s.RUnlock()
+
+	if fallback == nil {
+		return ErrReadOnly
+	}
+	return fallback.Register(svc, opts...)
+}
+
+// Deregister deregisters from the fallback registry.
+func (s *staticRegistry) Deregister(svc *registry.Service, opts ...registry.DeregisterOption) error {
+	s.RLock()
+	fallback := s.fallback
+	s.RUnlock()
+
+	if fallback == nil {
+		return ErrReadOnly
+	}
+	return fallback.Deregister(svc, opts...)
+}
+
+// GetService returns the static versions of a service, with the nodes of the
+// fallback registry added.
+func (s *staticRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	s.RLock()
+	var services []*registry.Service
+	for _, version := range sortedKeys(s.services[name]) {
+		services = append(services, copyService(s.services[name][version]))
+	}
+	fallback := s.fallback
+	s.RUnlock()
+
+	if fallback != nil {
+		svcs, err := fallback.GetService(name, opts...)
+		if err != nil && len(services) == 0 {
+			return nil, err
+		}
+		services = merge(services, svcs)
+	}
+
+	if len(services) == 0 {
+		return nil, registry.ErrNotFound
+	}
+
+	return services, nil
+}
+
+// merge adds the versions and nodes of the fallback services not static.
+func merge(services, fallback []*registry.Service) []*registry.Service {
+	for _, svc := range fallback {
+		var found *registry.Service
+		for _, s := range services {
+			if s.Version == svc.Version {
+				found = s
+				break
+			}
+		}
+		if found == nil {
+			services = append(services, svc)
+			continue
+		}
+
+	nodes:
+		for _, n := range svc.Nodes {
+			for _, cur := range found.Nodes {
+				if cur.Id == n.Id {
+					continue nodes
+				}
+			}
+			found.Nodes = append(found.Nodes, n)
+		}
+	}
+
+	return services
+}
+
+func (s *staticRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	s.RLock()
+	seen := make(map[string]bool)
+	var services []*registry.Service
+	for _, name := range sortedKeys(s.services) {
+		for _, version := range sortedKeys(s.services[name]) {
+			seen[name+":"+version] = true
+			services = append(services, copyService(s.services[name][version]))
+		}
+	}
+	fallback := s.fallback
+	s.RUnlock()
+
+	if fallback != nil {
+		svcs, err := fallback.ListServices(opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range svcs {
+			if !seen[svc.Name+":"+svc.Version] {
+				services = append(services, svc)
+			}
+		}
+	}
+
+	return services, nil
+}
+
+// Watch watches the static services and those of the fallback registry.
+func (s *staticRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	w := &watcher{
+		id:     uuid.New().String(),
+		wo:     wo,
+		res:    make(chan *registry.Result),
+		exit:   make(chan bool),
+		failed: make(chan struct{}),
+	}
+
+	s.RLock()
+	fallback := s.fallback
+	s.RUnlock()
+
+	if fallback != nil {
+		fw, err := fallback.Watch(opts...)
+		if err != nil {
+			return nil, err
+		}
+		w.fallback = fw
+		go w.forward()
+	}
+
+	s.Lock()
+	s.watchers[w.id] = w
+	s.Unlock()
+
+	return w, nil
+}
+
+func (s *staticRegistry) String() string {
+	return "static"
+}
@@ -0,0 +1,208 @@
+package static
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-micro.org/v5/registry"
+)
+
+const testFile = `
+services:
+  - name: foo
+    version: 1.0.0
+    metadata:
+      team: core
+    nodes:
+      - id: foo-1
+        address: 10.0.0.1:8080
+  - name: bar
+    dns: bar.internal:9090
+`
+
+func setup(t *testing.T, content string, opts ...registry.Option) (*staticRegistry, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "services.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	lookupHost = func(host string) ([]string, error) {
+		if host != "bar.internal" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"10.0.1.2", "10.0.1.1"}, nil
+	}
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return "", []*net.SRV{{Target: "baz.local.", Port: 7070}}, nil
+	}
+
+	opts = append([]registry.Option{File(path), Interval(time.Hour)}, opts...)
+	return NewRegistry(opts...).(*staticRegistry), path
+}
+
+func TestGetService(t *testing.T) {
+	r, _ := setup(t, testFile, DNS("baz", "baz"))
+
+	svcs, err := r.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcs) != 1 || svcs[0].Version != "1.0.0" || svcs[0].Metadata["team"] != "core" {
+		t.Fatalf("unexpected services %+v", svcs)
+	}
+
+	svcs, err = r.GetService("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcs[0].Nodes) != 2 || svcs[0].Nodes[0].Address != "10.0.1.1:9090" {
+		t.Fatalf("expected the A records sorted, got %+v", svcs[0].Nodes)
+	}
+
+	svcs, err = r.GetService("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcs[0].Nodes) != 1 || svcs[0].Nodes[0].Address != "baz.local:7070" {
+		t.Fatalf("expected the SRV record, got %+v", svcs[0].Nodes)
+	}
+
+	if _, err := r.GetService("missing"); err != registry.ErrNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	list, err := r.ListServices()
+	if err != nil || len(list) != 3 {
+		t.Fatalf("expected 3 services, got %d %v", len(list), err)
+	}
+
+	if err := r.Register(&registry.Service{Name: "foo"}); err != ErrReadOnly {
+		t.Fatalf("expected read only, got %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	r, path := setup(t, testFile)
+
+	w, err := r.Watch(registry.WatchService("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	next := func(action string, nodes int) {
+		t.Helper()
+
+		res := make(chan *registry.Result, 1)
+		go func() {
+			if got, err := w.Next(); err == nil {
+				res <- got
+			}
+		}()
+		time.Sleep(time.Millisecond)
+
+		r.refresh()
+
+		select {
+		case got := <-res:
+			if got.Action != action || len(got.Service.Nodes) != nodes {
+				t.Fatalf("expected %s with %d nodes, got %s %+v", action, nodes, got.Action, got.Service)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s", action)
+		}
+	}
+
+	update := `
+services:
+  - name: foo
+    version: 1.0.0
+    metadata:
+      team: core
+    nodes:
+      - id: foo-1
+        address: 10.0.0.1:8080
+      - address: 10.0.0.2:8080
+`
+	if err := os.WriteFile(path, []byte(update), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	next("update", 2)
+
+	// invalid files keep the services
+	if err := os.WriteFile(path, []byte("services: ["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r.refresh()
+	if svcs, err := r.GetService("foo"); err != nil || len(svcs[0].Nodes) != 2 {
+		t.Fatalf("expected the last services, got %+v %v", svcs, err)
+	}
+
+	// removed nodes are deleted alone
+	if err := os.WriteFile(path, []byte(testFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	next("delete", 1)
+
+	if err := os.WriteFile(path, []byte("services: []"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	next("delete", 1)
+
+	update = `{"services": [{"name": "foo", "version": "2.0.0", "nodes": [{"address": "10.0.0.3:8080"}]}]}`
+	if err := os.WriteFile(path, []byte(update), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	next("create", 1)
+}
+
+func TestStop(t *testing.T) {
+	r, path := setup(t, testFile, Interval(10*time.Millisecond))
+	r.Stop()
+	r.Stop()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := os.WriteFile(path, []byte("services: []"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if svcs, err := r.GetService("foo"); err != nil || len(svcs) == 0 {
+		t.Fatalf("expected no reload once stopped, got %+v %v", svcs, err)
+	}
+}
+
+var errWatch = errors.New("watch failed")
+
+type failingWatcher struct{}
+
+func (failingWatcher) Next() (*registry.Result, error) { return nil, errWatch }
+func (failingWatcher) Stop()                           {}
+
+type failingRegistry struct {
+	registry.Registry
+}
+
+func (failingRegistry) Watch(...registry.WatchOption) (registry.Watcher, error) {
+	return failingWatcher{}, nil
+}
+
+func TestFallbackWatchFails(t *testing.T) {
+	r, _ := setup(t, testFile, Fallback(failingRegistry{registry.NewMemoryRegistry()}))
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if _, err := w.Next(); err != errWatch {
+		t.Fatalf("expected %v, got %v", errWatch, err)
+	}
+}
@@ -0,0 +1,70 @@
+package static
+
+import (
+	"errors"
+
+	"go-micro.org/v5/registry"
+)
+
+type watcher struct {
+	id       string
+	wo       registry.WatchOptions
+	res      chan *registry.Result
+	exit     chan bool
+	fallback registry.Watcher
+
+	// closed once the fallback watcher failed with err
+	failed chan struct{}
+	err    error
+}
+
+// forward sends the results of the fallback watcher, and fails the watcher
+// with its error.
+func (w *watcher) forward() {
+	for {
+		r, err := w.fallback.Next()
+		if err != nil {
+			select {
+			case <-w.exit:
+			default:
+				w.err = err
+				close(w.failed)
+			}
+			return
+		}
+
+		select {
+		case w.res <- r:
+		case <-w.exit:
+			return
+		}
+	}
+}
+
+func (w *watcher) Next() (*registry.Result, error) {
+	for {
+		select {
+		case r := <-w.res:
+			if len(w.wo.Service) > 0 && w.wo.Service != r.Service.Name {
+				continue
+			}
+			return r, nil
+		case <-w.failed:
+			return nil, w.err
+		case <-w.exit:
+			return nil, errors.New("watcher stopped")
+		}
+	}
+}
+
+func (w *watcher) Stop() {
+	select {
+	case <-w.exit:
+		return
+	default:
+		close(w.exit)
+		if w.fallback != nil {
+			w.fallback.Stop()
+		}
+	}
+}
@@ -2,7 +2,10 @@ module github.com/open-micro/plugins/v5/selector/label
 
 go 1.19
 
-require go-micro.org/v5 v5.0.1
+require (
+	github.com/open-micro/plugins/v5/registry/health v0.0.0-00010101000000-000000000000
+	go-micro.org/v5 v5.0.1
+)
 
 require (
 	dario.cat/mergo v1.0.0 // indirect
@@ -46,3 +49,5 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/registry/health => ../../registry/health
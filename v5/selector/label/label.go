@@ -5,6 +5,7 @@ import (
 	"context"
 	"sync"
 
+	"github.com/open-micro/plugins/v5/registry/health"
 	"go-micro.org/v5/registry"
 	"go-micro.org/v5/selector"
 	"go-micro.org/v5/util/cmd"
@@ -105,6 +106,9 @@ func (r *labelSelector) Select(service string, opts ...selector.SelectOption) (s
 		return nil, err
 	}
 
+	// skip nodes not ready
+	services = health.Filter(services)
+
 	// apply the filters
 	for _, filter := range sopts.Filters {
 		services = filter(services)
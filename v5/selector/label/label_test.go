@@ -3,6 +3,7 @@ package label
 import (
 	"testing"
 
+	"github.com/open-micro/plugins/v5/registry/health"
 	"go-micro.org/v5/registry"
 	"go-micro.org/v5/selector"
 )
@@ -133,3 +134,40 @@ func TestLabelSelector(t *testing.T) {
 
 	t.Logf("Label Select Counts %v", counts)
 }
+
+func TestLabelSelectorStatus(t *testing.T) {
+	r := registry.NewMemoryRegistry()
+	r.Register(&registry.Service{
+		Name:    "foo",
+		Version: "latest",
+		Nodes: []*registry.Node{
+			{
+				Id: "1",
+				Metadata: map[string]string{
+					health.MetadataKey: string(health.Draining),
+				},
+			},
+			{
+				Id: "2",
+				Metadata: map[string]string{
+					health.MetadataKey: string(health.Ready),
+				},
+			},
+		},
+	})
+
+	next, err := NewSelector(selector.Registry(r)).Select("foo")
+	if err != nil {
+		t.Fatalf("Unexpected error calling ls select: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatalf("Expected node err, got err: %v", err)
+		}
+		if node.Id != "2" {
+			t.Fatalf("Expected the ready node, got id: %s", node.Id)
+		}
+	}
+}
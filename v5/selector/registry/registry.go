@@ -2,15 +2,27 @@
 package registry
 
 import (
+	"github.com/open-micro/plugins/v5/registry/health"
 	"go-micro.org/v5/selector"
 	"go-micro.org/v5/util/cmd"
 )
 
+type registrySelector struct {
+	selector.Selector
+}
+
 func init() {
 	cmd.DefaultSelectors["registry"] = NewSelector
 }
 
+// Select selects the ready nodes of a service, or the draining ones while
+// none is ready.
+func (r *registrySelector) Select(service string, opts ...selector.SelectOption) (selector.Next, error) {
+	opts = append([]selector.SelectOption{selector.WithFilter(health.Filter)}, opts...)
+	return r.Selector.Select(service, opts...)
+}
+
 // NewSelector returns a new registry selector.
 func NewSelector(opts ...selector.Option) selector.Selector {
-	return selector.NewSelector(opts...)
+	return &registrySelector{selector.NewSelector(opts...)}
 }
@@ -23,7 +23,15 @@ type consulSync struct {
 	c       *api.Client
 
 	mtx   gosync.Mutex
-	locks map[string]*api.Lock
+	locks map[string]*consulLock
+}
+
+type consulLock struct {
+	l *api.Lock
+	// closed when the lock is lost
+	lost <-chan struct{}
+	// modify index of the key when it was acquired
+	index uint64
 }
 
 type consulElected struct {
@@ -32,8 +40,9 @@ type consulElected struct {
 	id  string
 	key string
 
-	mtx gosync.RWMutex
-	rv  <-chan struct{}
+	mtx   gosync.RWMutex
+	rv    <-chan struct{}
+	index uint64
 }
 
 func (c *consulElected) Reelect() error {
@@ -41,9 +50,14 @@ func (c *consulElected) Reelect() error {
 	if err != nil {
 		return err
 	}
+	index, err := lockIndex(c.c, c.key)
+	if err != nil {
+		return err
+	}
 
 	c.mtx.Lock()
 	c.rv = rv
+	c.index = index
 	c.mtx.Unlock()
 	return nil
 }
@@ -67,9 +81,36 @@ func (c *consulElected) Resign() error {
 	return c.l.Unlock()
 }
 
+// Token returns the fencing token of the leadership, the modify index of
+// the leader key when it was acquired.
+func (c *consulElected) Token() uint64 {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.index
+}
+
+// Status is signalled once the leadership is lost or resigned.
 func (c *consulElected) Status() chan bool {
 	ch := make(chan bool, 1)
 
+	var once gosync.Once
+	signal := func() {
+		once.Do(func() {
+			ch <- true
+			close(ch)
+		})
+	}
+
+	c.mtx.RLock()
+	rv := c.rv
+	c.mtx.RUnlock()
+
+	// the lock monitor closes rv when the session loses the key
+	go func() {
+		<-rv
+		signal()
+	}()
+
 	p, err := watch.Parse(map[string]interface{}{
 		"type": "key",
 		"key":  c.key,
@@ -78,14 +119,10 @@ func (c *consulElected) Status() chan bool {
 		return ch
 	}
 	p.Handler = func(idx uint64, raw interface{}) {
-		if raw == nil {
-			ch <- true
-			return // ignore
-		}
 		v, ok := raw.(*api.KVPair)
-		if !ok || v == nil || string(v.Value) != c.id {
-			ch <- true
-			return // ignore
+		if !ok || v == nil || len(v.Session) == 0 || string(v.Value) != c.id {
+			signal()
+			p.Stop()
 		}
 	}
 
@@ -116,13 +153,19 @@ func (c *consulSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader,
 	if err != nil {
 		return nil, err
 	}
+	index, err := lockIndex(c.c, key)
+	if err != nil {
+		lc.Unlock()
+		return nil, err
+	}
 
 	return &consulElected{
-		c:   c.c,
-		key: c.path,
-		rv:  rv,
-		id:  id,
-		l:   lc,
+		c:     c.c,
+		key:   key,
+		rv:    rv,
+		id:    id,
+		l:     lc,
+		index: index,
 	}, nil
 }
 
@@ -143,7 +186,10 @@ func (c *consulSync) Lock(id string, opts ...sync.LockOption) error {
 		o(&options)
 	}
 
-	if options.Wait <= time.Duration(0) {
+	// give up after waiting once if a wait is set, block until locked
+	// otherwise
+	tryOnce := options.Wait > time.Duration(0)
+	if !tryOnce {
 		options.Wait = api.DefaultLockWaitTime
 	}
 
@@ -154,9 +200,11 @@ func (c *consulSync) Lock(id string, opts ...sync.LockOption) error {
 
 	key := path.Join(c.path, strings.Replace(c.options.Prefix+id, "/", "-", -1))
 
+	// the session of the lock is renewed in the background until unlocked
 	l, err := c.c.LockOpts(&api.LockOptions{
 		Key:          key,
 		LockWaitTime: options.Wait,
+		LockTryOnce:  tryOnce,
 		SessionTTL:   ttl,
 	})
 
@@ -164,18 +212,44 @@ func (c *consulSync) Lock(id string, opts ...sync.LockOption) error {
 		return err
 	}
 
-	_, err = l.Lock(nil)
+	lost, err := l.Lock(nil)
 	if err != nil {
 		return err
 	}
+	if lost == nil {
+		return sync.ErrLockTimeout
+	}
+
+	index, err := lockIndex(c.c, key)
+	if err != nil {
+		l.Unlock()
+		return err
+	}
 
 	c.mtx.Lock()
-	c.locks[id] = l
+	c.locks[id] = &consulLock{
+		l:     l,
+		lost:  lost,
+		index: index,
+	}
 	c.mtx.Unlock()
 
 	return nil
 }
 
+// lockIndex returns the modify index of the held lock key, which increases
+// with every acquisition.
+func lockIndex(c *api.Client, key string) (uint64, error) {
+	kv, _, err := c.KV().Get(key, nil)
+	if err != nil {
+		return 0, err
+	}
+	if kv == nil {
+		return 0, errors.New("lock key not found")
+	}
+	return kv.ModifyIndex, nil
+}
+
 func (c *consulSync) Unlock(id string) error {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
@@ -183,11 +257,34 @@ func (c *consulSync) Unlock(id string) error {
 	if !ok {
 		return errors.New("lock not found")
 	}
-	err := l.Unlock()
+	err := l.l.Unlock()
 	delete(c.locks, id)
 	return err
 }
 
+// Token returns the fencing token of the held lock, the modify index of its
+// key when it was acquired.
+func (c *consulSync) Token(id string) (uint64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	l, ok := c.locks[id]
+	if !ok {
+		return 0, errors.New("lock not found")
+	}
+	return l.index, nil
+}
+
+// Lost returns a channel closed once the held lock is lost or unlocked.
+func (c *consulSync) Lost(id string) (<-chan struct{}, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	l, ok := c.locks[id]
+	if !ok {
+		return nil, errors.New("lock not found")
+	}
+	return l.lost, nil
+}
+
 func (c *consulSync) String() string {
 	return "consul"
 }
@@ -218,6 +315,6 @@ func NewSync(opts ...sync.Option) sync.Sync {
 	return &consulSync{
 		c:       client,
 		options: options,
-		locks:   make(map[string]*api.Lock),
+		locks:   make(map[string]*consulLock),
 	}
 }
@@ -5,7 +5,9 @@ import (
 	"strings"
 	gosync "sync"
 	"testing"
+	"time"
 
+	"github.com/open-micro/plugins/v5/sync/fence/synctest"
 	"go-micro.org/v5/sync"
 )
 
@@ -66,3 +68,13 @@ func TestSyncLock(t *testing.T) {
 
 	t.Log("Test complete")
 }
+
+func TestConformance(t *testing.T) {
+	consulHost := os.Getenv("MICRO_TEST_CONSUL")
+	if consulHost == "" {
+		t.Skip("MICRO_TEST_CONSUL not defined")
+	}
+
+	// consul sessions have a ttl of at least 10s
+	synctest.Run(t, NewSync(sync.Nodes(consulHost)), synctest.Options{TTL: 10 * time.Second})
+}
@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/hashicorp/consul/api v1.9.0
 	github.com/hashicorp/go-hclog v0.16.2
+	github.com/open-micro/plugins/v5/sync/fence v0.0.0-00010101000000-000000000000
 	go-micro.org/v5 v5.0.1
 )
 
@@ -26,3 +27,5 @@ require (
 	github.com/stretchr/objx v0.5.2 // indirect
 	golang.org/x/sys v0.21.0 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/sync/fence => ../fence
@@ -8,12 +8,17 @@ import (
 	"path"
 	"strings"
 	gosync "sync"
+	"time"
 
 	"go-micro.org/v5/sync"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	cc "go.etcd.io/etcd/client/v3/concurrency"
 )
 
+// ErrInvalidTTL is returned when locking with a TTL under a second, the
+// resolution of etcd leases.
+var ErrInvalidTTL = errors.New("lock ttl under 1s")
+
 type etcdSync struct {
 	options sync.Options
 	path    string
@@ -33,6 +38,8 @@ type etcdLeader struct {
 	s    *cc.Session
 	e    *cc.Election
 	id   string
+	// revision the leadership was won at
+	rev int64
 }
 
 func (e *etcdSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
@@ -44,6 +51,7 @@ func (e *etcdSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, er
 	// make path
 	path := path.Join(e.path, strings.Replace(e.options.Prefix+id, "/", "-", -1))
 
+	// the session keeps its lease alive until closed
 	s, err := cc.NewSession(e.client)
 	if err != nil {
 		return nil, err
@@ -52,26 +60,44 @@ func (e *etcdSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, er
 	l := cc.NewElection(s, path)
 
 	if err := l.Campaign(context.TODO(), id); err != nil {
+		s.Close()
 		return nil, err
 	}
 
 	return &etcdLeader{
 		opts: options,
+		s:    s,
 		e:    l,
 		id:   id,
+		rev:  l.Rev(),
 	}, nil
 }
 
+// Status is signalled once the leadership is lost, either because another
+// candidate was elected or the session lease expired, or resigned.
 func (e *etcdLeader) Status() chan bool {
 	ch := make(chan bool, 1)
-	ech := e.e.Observe(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	ech := e.e.Observe(ctx)
 
 	go func() {
-		for r := range ech {
-			if string(r.Kvs[0].Value) != e.id {
+		defer cancel()
+		defer close(ch)
+
+		for {
+			select {
+			case <-e.s.Done():
 				ch <- true
-				close(ch)
 				return
+			case r, ok := <-ech:
+				if !ok {
+					ch <- true
+					return
+				}
+				if len(r.Kvs) == 0 || r.Kvs[0].CreateRevision != e.rev {
+					ch <- true
+					return
+				}
 			}
 		}
 	}()
@@ -80,7 +106,16 @@ func (e *etcdLeader) Status() chan bool {
 }
 
 func (e *etcdLeader) Resign() error {
-	return e.e.Resign(context.Background())
+	if err := e.e.Resign(context.Background()); err != nil {
+		return err
+	}
+	return e.s.Close()
+}
+
+// Token returns the fencing token of the leadership, the revision the
+// leader key was created at.
+func (e *etcdLeader) Token() uint64 {
+	return uint64(e.rev)
 }
 
 func (e *etcdSync) Init(opts ...sync.Option) error {
@@ -104,7 +139,9 @@ func (e *etcdSync) Lock(id string, opts ...sync.LockOption) error {
 	path := path.Join(e.path, strings.Replace(e.options.Prefix+id, "/", "-", -1))
 
 	var sopts []cc.SessionOption
-	if options.TTL > 0 {
+	if options.TTL > 0 && options.TTL < time.Second {
+		return ErrInvalidTTL
+	} else if options.TTL > 0 {
 		sopts = append(sopts, cc.WithTTL(int(options.TTL.Seconds())))
 	}
 
@@ -122,8 +159,10 @@ func (e *etcdSync) Lock(id string, opts ...sync.LockOption) error {
 		defer cancel()
 	}
 	if err := m.Lock(lockCtx); err != nil && err == context.DeadlineExceeded {
+		s.Close()
 		return sync.ErrLockTimeout
 	} else if err != nil {
+		s.Close()
 		return err
 	}
 
@@ -152,6 +191,30 @@ func (e *etcdSync) Unlock(id string) error {
 	return v.s.Close()
 }
 
+// Token returns the fencing token of the held lock, the revision its key
+// was created at.
+func (e *etcdSync) Token(id string) (uint64, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	v, ok := e.locks[id]
+	if !ok {
+		return 0, errors.New("lock not found")
+	}
+	return uint64(v.m.Header().Revision), nil
+}
+
+// Lost returns a channel closed once the session of the held lock ends,
+// because it was unlocked or its lease expired.
+func (e *etcdSync) Lost(id string) (<-chan struct{}, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	v, ok := e.locks[id]
+	if !ok {
+		return nil, errors.New("lock not found")
+	}
+	return v.s.Done(), nil
+}
+
 func (e *etcdSync) String() string {
 	return "etcd"
 }
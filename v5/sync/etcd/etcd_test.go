@@ -0,0 +1,18 @@
+package etcd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/open-micro/plugins/v5/sync/fence/synctest"
+	"go-micro.org/v5/sync"
+)
+
+func TestConformance(t *testing.T) {
+	addr := os.Getenv("MICRO_TEST_ETCD")
+	if len(addr) == 0 {
+		t.Skip("MICRO_TEST_ETCD not defined")
+	}
+	synctest.Run(t, NewSync(sync.Nodes(addr)), synctest.Options{TTL: 5 * time.Second})
+}
@@ -3,6 +3,7 @@ module github.com/open-micro/plugins/v5/sync/etcd
 go 1.19
 
 require (
+	github.com/open-micro/plugins/v5/sync/fence v0.0.0-00010101000000-000000000000
 	go-micro.org/v5 v5.0.1
 	go.etcd.io/etcd/client/v3 v3.5.0
 )
@@ -28,3 +29,5 @@ require (
 	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/open-micro/plugins/v5/sync/fence => ../fence
@@ -0,0 +1,56 @@
+// Package fence provides fencing tokens and lock loss notification for sync implementations
+package fence
+
+import (
+	"errors"
+
+	"go-micro.org/v5/sync"
+)
+
+var ErrNotSupported = errors.New("fencing not supported")
+
+// Locker is implemented by sync.Sync implementations issuing fencing tokens.
+// Tokens increase with every grant of a lock, a resource guarded by a lock
+// rejects writes carrying a lower token than the highest one it has seen,
+// so a holder whose lease expired can't overwrite the next holder.
+type Locker interface {
+	// Token returns the fencing token of the held lock.
+	Token(id string) (uint64, error)
+	// Lost returns a channel closed once the held lock is released, or lost
+	// because its lease could not be renewed.
+	Lost(id string) (<-chan struct{}, error)
+}
+
+// Leader is implemented by leaders issuing fencing tokens.
+type Leader interface {
+	sync.Leader
+	// Token returns the fencing token of the leadership.
+	Token() uint64
+}
+
+// Token returns the fencing token of the lock held with s.
+func Token(s sync.Sync, id string) (uint64, error) {
+	l, ok := s.(Locker)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return l.Token(id)
+}
+
+// Lost returns a channel closed once the lock held with s is lost.
+func Lost(s sync.Sync, id string) (<-chan struct{}, error) {
+	l, ok := s.(Locker)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return l.Lost(id)
+}
+
+// LeaderToken returns the fencing token of the leadership.
+func LeaderToken(l sync.Leader) (uint64, error) {
+	f, ok := l.(Leader)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return f.Token(), nil
+}
@@ -0,0 +1,7 @@
+module github.com/open-micro/plugins/v5/sync/fence
+
+go 1.19
+
+require go-micro.org/v5 v5.0.1
+
+require github.com/google/uuid v1.6.0 // indirect
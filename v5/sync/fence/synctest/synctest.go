@@ -0,0 +1,161 @@
+// Package synctest is a conformance test suite for sync implementations
+package synctest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/open-micro/plugins/v5/sync/fence"
+	"go-micro.org/v5/sync"
+)
+
+// Options of the suite.
+type Options struct {
+	// TTL of the leases locked by the suite, the renewal test waits past
+	// it. Defaults to a second, use the minimum TTL of the implementation
+	// if it is longer.
+	TTL time.Duration
+}
+
+// Run runs the suite against s. Locks are taken under ids unique to the run.
+// A lock TTL of a nanosecond, under the resolution of any lease, must be
+// refused.
+func Run(t *testing.T, s sync.Sync, opts Options) {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Second
+	}
+
+	id := func(name string) string {
+		return fmt.Sprintf("synctest-%s-%d", name, time.Now().UnixNano())
+	}
+
+	t.Run("Wait", func(t *testing.T) {
+		lock := id("wait")
+		if err := s.Lock(lock); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Lock(lock, sync.LockWait(200*time.Millisecond)); err != sync.ErrLockTimeout {
+			t.Fatalf("expected lock timeout, got %v", err)
+		}
+		if err := s.Unlock(lock); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("InvalidTTL", func(t *testing.T) {
+		lock := id("ttl")
+		if err := s.Lock(lock, sync.LockTTL(time.Nanosecond)); err == nil {
+			s.Unlock(lock)
+			t.Fatal("expected locking with a 1ns ttl to fail")
+		}
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		lock := id("release")
+		if err := s.Lock(lock); err != nil {
+			t.Fatal(err)
+		}
+		first, err := fence.Token(s, lock)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.Lock(lock, sync.LockWait(10*time.Second))
+		}()
+		time.Sleep(100 * time.Millisecond)
+		if err := s.Unlock(lock); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+
+		second, err := fence.Token(s, lock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second <= first {
+			t.Fatalf("expected fencing token to increase, got %d after %d", second, first)
+		}
+		if err := s.Unlock(lock); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Renewal", func(t *testing.T) {
+		lock := id("renewal")
+		if err := s.Lock(lock, sync.LockTTL(opts.TTL)); err != nil {
+			t.Fatal(err)
+		}
+		lost, err := fence.Lost(s, lock)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the lease is kept alive past its ttl while held
+		time.Sleep(opts.TTL * 3 / 2)
+		select {
+		case <-lost:
+			t.Fatal("lock lost while held")
+		default:
+		}
+		if err := s.Lock(lock, sync.LockWait(200*time.Millisecond)); err != sync.ErrLockTimeout {
+			t.Fatalf("expected lock timeout, got %v", err)
+		}
+
+		if err := s.Unlock(lock); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-lost:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected lost to be closed on unlock")
+		}
+	})
+
+	t.Run("Leader", func(t *testing.T) {
+		leader := id("leader")
+		l, err := s.Leader(leader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, err := fence.LeaderToken(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status := l.Status()
+
+		select {
+		case <-status:
+			t.Fatal("unexpected status change")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		if err := l.Resign(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-status:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected status change after resign")
+		}
+
+		l, err = s.Leader(leader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := fence.LeaderToken(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second <= first {
+			t.Fatalf("expected fencing token to increase, got %d after %d", second, first)
+		}
+		if err := l.Resign(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
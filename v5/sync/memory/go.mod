@@ -2,6 +2,11 @@ module github.com/open-micro/plugins/v5/sync/memory
 
 go 1.19
 
-require go-micro.org/v5 v5.0.1
+require (
+	github.com/open-micro/plugins/v5/sync/fence v0.0.0-00010101000000-000000000000
+	go-micro.org/v5 v5.0.1
+)
 
 require github.com/google/uuid v1.6.0 // indirect
+
+replace github.com/open-micro/plugins/v5/sync/fence => ../fence
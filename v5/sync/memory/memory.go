@@ -2,29 +2,41 @@
 package memory
 
 import (
+	"errors"
 	gosync "sync"
 	"time"
 
 	"go-micro.org/v5/sync"
 )
 
+var (
+	ErrLockNotFound = errors.New("lock not found")
+	// ErrInvalidTTL is returned when locking with a TTL under a millisecond,
+	// too short to be renewed.
+	ErrInvalidTTL = errors.New("lock ttl under 1ms")
+)
+
 type memorySync struct {
 	options sync.Options
 
 	mtx   gosync.RWMutex
 	locks map[string]*memoryLock
+	// last fencing token issued per id
+	fences map[string]uint64
 }
 
 type memoryLock struct {
 	id      string
 	time    time.Time
 	ttl     time.Duration
+	fence   uint64
 	release chan bool
 }
 
 type memoryLeader struct {
 	opts   sync.LeaderOptions
 	id     string
+	fence  uint64
 	resign func(id string) error
 	status chan bool
 }
@@ -33,10 +45,16 @@ func (m *memoryLeader) Resign() error {
 	return m.resign(m.id)
 }
 
+// Status is signalled once the leadership is resigned or its lock unlocked.
 func (m *memoryLeader) Status() chan bool {
 	return m.status
 }
 
+// Token returns the fencing token of the leadership.
+func (m *memoryLeader) Token() uint64 {
+	return m.fence
+}
+
 func (m *memorySync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
 	var once gosync.Once
 	var options sync.LeaderOptions
@@ -49,18 +67,29 @@ func (m *memorySync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader,
 		return nil, err
 	}
 
+	m.mtx.RLock()
+	lk := m.locks[id]
+	m.mtx.RUnlock()
+
+	status := make(chan bool, 1)
+	go func() {
+		<-lk.release
+		status <- true
+		close(status)
+	}()
+
 	// return the leader
 	return &memoryLeader{
-		opts: options,
-		id:   id,
+		opts:  options,
+		id:    id,
+		fence: lk.fence,
 		resign: func(id string) error {
 			once.Do(func() {
-				m.Unlock(id)
+				m.unlock(id, lk)
 			})
 			return nil
 		},
-		// TODO: signal when Unlock is called
-		status: make(chan bool, 1),
+		status: status,
 	}, nil
 }
 
@@ -76,90 +105,106 @@ func (m *memorySync) Options() sync.Options {
 }
 
 func (m *memorySync) Lock(id string, opts ...sync.LockOption) error {
-	// lock our access
-	m.mtx.Lock()
-
 	var options sync.LockOptions
 	for _, o := range opts {
 		o(&options)
 	}
-
-	lk, ok := m.locks[id]
-	if !ok {
-		m.locks[id] = &memoryLock{
-			id:      id,
-			time:    time.Now(),
-			ttl:     options.TTL,
-			release: make(chan bool),
-		}
-		// unlock
-		m.mtx.Unlock()
-		return nil
+	if options.TTL > 0 && options.TTL < time.Millisecond {
+		return ErrInvalidTTL
 	}
 
-	m.mtx.Unlock()
-
-	// set wait time
-	var wait <-chan time.Time
-	var ttl <-chan time.Time
-
 	// decide if we should wait
+	var wait <-chan time.Time
 	if options.Wait > time.Duration(0) {
 		wait = time.After(options.Wait)
 	}
 
-	// check the ttl of the lock
-	if lk.ttl > time.Duration(0) {
-		// time lived for the lock
-		live := time.Since(lk.time)
+	for {
+		// lock our access
+		m.mtx.Lock()
 
-		// set a timer for the leftover ttl
-		if live > lk.ttl {
+		lk, ok := m.locks[id]
+		if ok && lk.ttl > time.Duration(0) && time.Since(lk.time) > lk.ttl {
 			// release the lock if it expired
-			_ = m.Unlock(id)
-		} else {
-			ttl = time.After(lk.ttl - live)
+			m.release(id, lk)
+			ok = false
 		}
-	}
-
-lockLoop:
-	for {
-		// wait for the lock to be released
-		select {
-		case <-lk.release:
-			m.mtx.Lock()
 
-			// someone locked before us
-			lk, ok = m.locks[id]
-			if ok {
-				m.mtx.Unlock()
-				continue
-			}
-
-			// got chance to lock
-			m.locks[id] = &memoryLock{
+		if !ok {
+			m.fences[id]++
+			lk = &memoryLock{
 				id:      id,
 				time:    time.Now(),
 				ttl:     options.TTL,
+				fence:   m.fences[id],
 				release: make(chan bool),
 			}
-
+			m.locks[id] = lk
 			m.mtx.Unlock()
 
-			break lockLoop
+			if lk.ttl > time.Duration(0) {
+				go m.renew(lk)
+			}
+			return nil
+		}
+
+		// set a timer for the leftover ttl
+		var ttl <-chan time.Time
+		if lk.ttl > time.Duration(0) {
+			ttl = time.After(lk.ttl - time.Since(lk.time))
+		}
+		release := lk.release
+
+		m.mtx.Unlock()
+
+		// wait for the lock to be released or expire
+		select {
+		case <-release:
 		case <-ttl:
-			// ttl exceeded
-			_ = m.Unlock(id)
-			// TODO: check the ttl again above
-			ttl = nil
-			// try acquire
-			continue
 		case <-wait:
 			return sync.ErrLockTimeout
 		}
 	}
+}
+
+// renew keeps the lease of the lock alive while it is held.
+func (m *memorySync) renew(lk *memoryLock) {
+	t := time.NewTicker(lk.ttl / 3)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-lk.release:
+			return
+		case <-t.C:
+		}
+
+		m.mtx.Lock()
+		if m.locks[lk.id] == lk {
+			lk.time = time.Now()
+		}
+		m.mtx.Unlock()
+	}
+}
 
-	return nil
+// release deletes the lock and notifies waiters, m.mtx must be held.
+func (m *memorySync) release(id string, lk *memoryLock) {
+	if m.locks[id] == lk {
+		delete(m.locks, id)
+	}
+
+	select {
+	case <-lk.release:
+	default:
+		close(lk.release)
+	}
+}
+
+// unlock releases the lock if it is still held as lk.
+func (m *memorySync) unlock(id string, lk *memoryLock) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.release(id, lk)
 }
 
 func (m *memorySync) Unlock(id string) error {
@@ -172,17 +217,37 @@ func (m *memorySync) Unlock(id string) error {
 		return nil
 	}
 
-	// delete the lock
-	delete(m.locks, id)
+	m.release(id, lk)
+	return nil
+}
 
-	select {
-	case <-lk.release:
-		return nil
-	default:
-		close(lk.release)
+// Token returns the fencing token of the held lock.
+func (m *memorySync) Token(id string) (uint64, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	lk, ok := m.locks[id]
+	if !ok {
+		return 0, ErrLockNotFound
 	}
+	return lk.fence, nil
+}
 
-	return nil
+// Lost returns a channel closed once the held lock is released.
+func (m *memorySync) Lost(id string) (<-chan struct{}, error) {
+	m.mtx.RLock()
+	lk, ok := m.locks[id]
+	m.mtx.RUnlock()
+	if !ok {
+		return nil, ErrLockNotFound
+	}
+
+	lost := make(chan struct{})
+	go func() {
+		<-lk.release
+		close(lost)
+	}()
+	return lost, nil
 }
 
 func (m *memorySync) String() string {
@@ -198,5 +263,6 @@ func NewSync(opts ...sync.Option) sync.Sync {
 	return &memorySync{
 		options: options,
 		locks:   make(map[string]*memoryLock),
+		fences:  make(map[string]uint64),
 	}
 }
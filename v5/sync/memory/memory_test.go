@@ -0,0 +1,11 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/open-micro/plugins/v5/sync/fence/synctest"
+)
+
+func TestConformance(t *testing.T) {
+	synctest.Run(t, NewSync(), synctest.Options{})
+}
@@ -0,0 +1,461 @@
+// Package natsjs is a NATS JetStream key value implementation of lock and leader election
+package natsjs
+
+import (
+	"encoding/base32"
+	"errors"
+	"fmt"
+	gosync "sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	log "go-micro.org/v5/logger"
+	"go-micro.org/v5/sync"
+)
+
+var (
+	DefaultBucket = "micro-sync"
+	DefaultPrefix = "micro/sync/"
+	// DefaultTTL is the TTL of the buckets created for locks, the lease of
+	// every lock in them. Leases are renewed while the lock is held, the TTL
+	// only bounds how long the lock outlives a holder which went away.
+	DefaultTTL = 30 * time.Second
+	// retryInterval bounds waiting for a lease to expire, which the bucket
+	// doesn't notify watchers of.
+	retryInterval = 250 * time.Millisecond
+)
+
+var (
+	ErrLockNotFound = errors.New("lock not found")
+	// ErrLockLost is returned by Unlock when the lease expired and the lock
+	// may have been acquired by someone else.
+	ErrLockLost = errors.New("lock lost")
+	// ErrLockTTL is returned by Lock for a sync.LockTTL other than the TTL
+	// of the bucket, the lease of every lock in it.
+	ErrLockTTL = errors.New("lock ttl differs from the bucket ttl")
+	// ErrNoBucketTTL is returned for an existing bucket without a TTL, whose
+	// leases would never expire.
+	ErrNoBucketTTL = errors.New("bucket has no ttl")
+)
+
+type natsSync struct {
+	options sync.Options
+	name    string
+
+	mtx   gosync.Mutex
+	conn  *nats.Conn
+	kv    nats.KeyValue
+	ttl   time.Duration
+	locks map[string]*natsLock
+}
+
+type natsLock struct {
+	key   string
+	owner string
+	// TTL of the bucket
+	ttl time.Duration
+	// revision the lock was acquired at, the fencing token
+	fence uint64
+
+	mtx gosync.Mutex
+	// revision of the latest renewal
+	revision uint64
+
+	once gosync.Once
+	// closed when the lock is released or lost
+	exit chan struct{}
+	// closed when the lease could not be renewed
+	lost chan struct{}
+}
+
+type natsLeader struct {
+	id    string
+	s     *natsSync
+	lock  *natsLock
+	once  gosync.Once
+	state chan bool
+	// closed once the lock is no longer watched
+	done chan struct{}
+}
+
+func (n *natsSync) Init(opts ...sync.Option) error {
+	for _, o := range opts {
+		o(&n.options)
+	}
+
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+		n.kv = nil
+	}
+	return nil
+}
+
+func (n *natsSync) Options() sync.Options {
+	return n.options
+}
+
+// bucket connects on first use and returns the bucket of the locks and its
+// TTL. Leases expire server side, a lock key is removed by the bucket once
+// it was not renewed within the TTL.
+func (n *natsSync) bucket() (nats.KeyValue, time.Duration, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.kv != nil {
+		return n.kv, n.ttl, nil
+	}
+
+	nopts := nats.GetDefaultOptions()
+	if len(n.options.Nodes) > 0 {
+		nopts.Servers = n.options.Nodes
+	}
+	if n.options.TLSConfig != nil {
+		nopts.Secure = true
+		nopts.TLSConfig = n.options.TLSConfig
+	}
+
+	conn, err := nopts.Connect()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	kv, ttl, err := openBucket(conn, n.name)
+	if err != nil {
+		conn.Close()
+		return nil, 0, err
+	}
+
+	n.conn = conn
+	n.kv = kv
+	n.ttl = ttl
+	return kv, ttl, nil
+}
+
+// openBucket returns the bucket, creating it with DefaultTTL. An existing
+// bucket must have a TTL, it is not changed.
+func openBucket(conn *nats.Conn, name string) (nats.KeyValue, time.Duration, error) {
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	kv, err := js.KeyValue(name)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:      name,
+			Description: "Locks managed by go-micro",
+			History:     1,
+			TTL:         DefaultTTL,
+		})
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	status, err := kv.Status()
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl := status.TTL()
+	if ttl <= 0 {
+		return nil, 0, fmt.Errorf("%w: %s, use a bucket created with a ttl", ErrNoBucketTTL, name)
+	}
+	return kv, ttl, nil
+}
+
+func (n *natsSync) key(id string) string {
+	// keys are restricted to a few characters
+	return base32.StdEncoding.EncodeToString([]byte(n.options.Prefix + id))
+}
+
+// acquire tries to take the lock once with a create, which only succeeds
+// while the key is absent. It returns the revision the lock was taken at, or
+// how long to wait for the current lease to expire.
+func (n *natsSync) acquire(kv nats.KeyValue, key, owner string, ttl time.Duration) (uint64, time.Duration, error) {
+	rev, err := kv.Create(key, []byte(owner))
+	if err == nil {
+		return rev, 0, nil
+	} else if !errors.Is(err, nats.ErrKeyExists) {
+		return 0, 0, err
+	}
+
+	entry, err := kv.Get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		// released or expired in the meantime
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+
+	// the timestamp is the server's, skew only delays the next attempt
+	wait := time.Until(entry.Created().Add(ttl))
+	if wait < retryInterval {
+		wait = retryInterval
+	}
+	if wait > ttl {
+		wait = ttl
+	}
+	return 0, wait, nil
+}
+
+// lock acquires the lock. The lease is the TTL of the bucket, a TTL of the
+// lock options must be the same.
+func (n *natsSync) lock(key string, options sync.LockOptions) (*natsLock, error) {
+	kv, ttl, err := n.bucket()
+	if err != nil {
+		return nil, err
+	}
+	if options.TTL > 0 && options.TTL != ttl {
+		return nil, fmt.Errorf("%w: %v, the bucket has %v", ErrLockTTL, options.TTL, ttl)
+	}
+
+	owner := uuid.New().String()
+
+	var deadline <-chan time.Time
+	if options.Wait > 0 {
+		timer := time.NewTimer(options.Wait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	// watch the key before the first attempt so no release is missed
+	w, err := kv.Watch(key, nats.UpdatesOnly())
+	if err != nil {
+		return nil, err
+	}
+	defer w.Stop()
+
+	for {
+		rev, wait, err := n.acquire(kv, key, owner, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if rev > 0 {
+			lk := &natsLock{
+				key:      key,
+				owner:    owner,
+				ttl:      ttl,
+				fence:    rev,
+				revision: rev,
+				exit:     make(chan struct{}),
+				lost:     make(chan struct{}),
+			}
+			go n.renew(kv, lk)
+			return lk, nil
+		}
+
+		if wait == 0 {
+			continue
+		}
+
+		// wait for a release or for the lease to expire
+		expiry := time.NewTimer(wait)
+		select {
+		case <-w.Updates():
+		case <-expiry.C:
+		case <-deadline:
+			expiry.Stop()
+			return nil, sync.ErrLockTimeout
+		}
+		expiry.Stop()
+	}
+}
+
+// renew keeps the lease of the lock alive until it is released.
+func (n *natsSync) renew(kv nats.KeyValue, lk *natsLock) {
+	t := time.NewTicker(lk.ttl / 3)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-lk.exit:
+			return
+		case <-t.C:
+		}
+
+		lk.mtx.Lock()
+		// an update resets the age of the key, a lease which expired was
+		// removed and fails the revision check like a lease taken over
+		rev, err := kv.Update(lk.key, []byte(lk.owner), lk.revision)
+		if err == nil {
+			lk.revision = rev
+		}
+		lk.mtx.Unlock()
+
+		if errors.Is(err, nats.ErrKeyNotFound) || isWrongRevision(err) {
+			log.Errorf("sync: lock %s lost", lk.key)
+			close(lk.lost)
+			lk.stop()
+			return
+		} else if err != nil {
+			// the lease may still be valid, try again on the next tick
+			log.Errorf("sync: error renewing lock %s: %v", lk.key, err)
+		}
+	}
+}
+
+func isWrongRevision(err error) bool {
+	var apiErr *nats.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence
+}
+
+func (lk *natsLock) stop() {
+	lk.once.Do(func() {
+		close(lk.exit)
+	})
+}
+
+func (n *natsSync) release(lk *natsLock) error {
+	lk.stop()
+
+	kv, _, err := n.bucket()
+	if err != nil {
+		return err
+	}
+
+	lk.mtx.Lock()
+	defer lk.mtx.Unlock()
+	// delete the key only while it is still ours
+	err = kv.Delete(lk.key, nats.LastRevision(lk.revision))
+	if isWrongRevision(err) {
+		return ErrLockLost
+	}
+	return err
+}
+
+func (n *natsSync) Lock(id string, opts ...sync.LockOption) error {
+	var options sync.LockOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	lk, err := n.lock(n.key(id), options)
+	if err != nil {
+		return err
+	}
+
+	n.mtx.Lock()
+	n.locks[id] = lk
+	n.mtx.Unlock()
+	return nil
+}
+
+func (n *natsSync) Unlock(id string) error {
+	n.mtx.Lock()
+	lk, ok := n.locks[id]
+	delete(n.locks, id)
+	n.mtx.Unlock()
+
+	if !ok {
+		return ErrLockNotFound
+	}
+	return n.release(lk)
+}
+
+// Token returns the fencing token of the held lock, the revision of the key
+// it was acquired at.
+func (n *natsSync) Token(id string) (uint64, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	lk, ok := n.locks[id]
+	if !ok {
+		return 0, ErrLockNotFound
+	}
+	return lk.fence, nil
+}
+
+// Lost returns a channel closed once the held lock is released, or lost
+// because its lease could not be renewed.
+func (n *natsSync) Lost(id string) (<-chan struct{}, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	lk, ok := n.locks[id]
+	if !ok {
+		return nil, ErrLockNotFound
+	}
+	return lk.exit, nil
+}
+
+func (n *natsSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
+	var options sync.LeaderOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	lk, err := n.lock(n.key("leader/"+id), sync.LockOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	l := &natsLeader{
+		id:    id,
+		s:     n,
+		lock:  lk,
+		state: make(chan bool, 1),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(l.done)
+		// exit is closed on release too, so resigning ends the watch
+		select {
+		case <-lk.lost:
+		case <-lk.exit:
+		}
+		l.signal()
+	}()
+	return l, nil
+}
+
+func (l *natsLeader) signal() {
+	l.once.Do(func() {
+		l.state <- true
+		close(l.state)
+	})
+}
+
+// Status is signalled once leadership is lost or resigned.
+func (l *natsLeader) Status() chan bool {
+	return l.state
+}
+
+func (l *natsLeader) Resign() error {
+	defer l.signal()
+	return l.s.release(l.lock)
+}
+
+// Token returns the fencing token of the leadership.
+func (l *natsLeader) Token() uint64 {
+	return l.lock.fence
+}
+
+func (n *natsSync) String() string {
+	return "natsjs"
+}
+
+// NewSync returns a sync keeping its locks in DefaultBucket.
+func NewSync(opts ...sync.Option) sync.Sync {
+	return NewBucketSync(DefaultBucket, opts...)
+}
+
+// NewBucketSync returns a sync keeping its locks in the bucket. Syncs
+// sharing locks have to use the same bucket.
+func NewBucketSync(bucket string, opts ...sync.Option) sync.Sync {
+	options := sync.Options{
+		Prefix: DefaultPrefix,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &natsSync{
+		options: options,
+		name:    bucket,
+		locks:   make(map[string]*natsLock),
+	}
+}
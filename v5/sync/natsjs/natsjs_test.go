@@ -0,0 +1,170 @@
+package natsjs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	nserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/open-micro/plugins/v5/sync/fence/synctest"
+	"go-micro.org/v5/sync"
+)
+
+func startNatsServer(t *testing.T) string {
+	t.Helper()
+
+	server, err := nserver.NewServer(&nserver.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Start()
+	t.Cleanup(server.Shutdown)
+
+	if !server.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	return server.ClientURL()
+}
+
+func TestConformance(t *testing.T) {
+	addr := startNatsServer(t)
+
+	conn, err := nats.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lease of the suite is the bucket TTL
+	if _, err := js.CreateKeyValue(&nats.KeyValueConfig{Bucket: "synctest", History: 1, TTL: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewBucketSync("synctest", sync.Nodes(addr))
+	synctest.Run(t, s, synctest.Options{TTL: time.Second})
+}
+
+func TestExpiredLease(t *testing.T) {
+	addr := startNatsServer(t)
+
+	conn, err := nats.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// leases expire with the bucket TTL, by the server's clock
+	kv, err := js.CreateKeyValue(&nats.KeyValueConfig{Bucket: "short", History: 1, TTL: 500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewBucketSync("short", sync.Nodes(addr)).(*natsSync)
+
+	// a holder which went away without unlocking
+	key := s.key("expired")
+	if _, err := kv.Create(key, []byte("gone")); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := s.Lock("expired", sync.LockWait(5*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 400*time.Millisecond {
+		t.Fatal("lock taken before the lease expired")
+	}
+
+	// held past the TTL while renewed
+	time.Sleep(time.Second)
+	if _, err := kv.Create(key, []byte("other")); err == nil {
+		t.Fatal("expected the renewed lease to be held")
+	}
+	if err := s.Unlock("expired"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBucketTTL(t *testing.T) {
+	addr := startNatsServer(t)
+
+	conn, err := nats.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bucket created has the default TTL
+	s := NewSync(sync.Nodes(addr)).(*natsSync)
+	if _, ttl, err := s.bucket(); err != nil {
+		t.Fatal(err)
+	} else if ttl != DefaultTTL {
+		t.Fatalf("expected ttl %v, got %v", DefaultTTL, ttl)
+	}
+
+	// lock TTLs other than the bucket's are refused
+	if err := s.Lock("ttl", sync.LockTTL(time.Second)); !errors.Is(err, ErrLockTTL) {
+		t.Fatalf("expected ErrLockTTL, got %v", err)
+	}
+	if err := s.Lock("ttl", sync.LockTTL(DefaultTTL)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unlock("ttl"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a bucket without a TTL is refused, not changed
+	if _, err := js.CreateKeyValue(&nats.KeyValueConfig{Bucket: "nottl", History: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s = NewBucketSync("nottl", sync.Nodes(addr)).(*natsSync)
+	if _, _, err := s.bucket(); !errors.Is(err, ErrNoBucketTTL) {
+		t.Fatalf("expected ErrNoBucketTTL, got %v", err)
+	}
+	info, err := js.StreamInfo("KV_nottl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Config.MaxAge != 0 {
+		t.Fatalf("expected the bucket unchanged, got max age %v", info.Config.MaxAge)
+	}
+}
+
+func TestLeaderResign(t *testing.T) {
+	s := NewSync(sync.Nodes(startNatsServer(t)))
+
+	l, err := s.Leader("leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Resign(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-l.Status():
+	case <-time.After(time.Second):
+		t.Fatal("expected status change after resign")
+	}
+	select {
+	case <-l.(*natsLeader).done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the leader to stop watching its lock after resign")
+	}
+}
@@ -0,0 +1,373 @@
+// Package redis is a redis implementation of lock and leader election
+package redis
+
+import (
+	"context"
+	"errors"
+	gosync "sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	log "go-micro.org/v5/logger"
+	"go-micro.org/v5/sync"
+)
+
+var (
+	DefaultPrefix = "micro/sync/"
+	// DefaultTTL is the lease of locks locked without a TTL. Leases are
+	// renewed while the lock is held, the TTL only bounds how long the lock
+	// outlives a holder which went away.
+	DefaultTTL = 30 * time.Second
+	// retryInterval bounds waiting for a lock between release notifications.
+	retryInterval = 250 * time.Millisecond
+)
+
+var (
+	ErrLockNotFound = errors.New("lock not found")
+	// ErrLockLost is returned by Unlock when the lease expired and the lock
+	// may have been acquired by someone else.
+	ErrLockLost = errors.New("lock lost")
+	// ErrInvalidTTL is returned when locking with a TTL under a millisecond,
+	// the resolution of leases.
+	ErrInvalidTTL = errors.New("lock ttl under 1ms")
+)
+
+var (
+	// lockScript sets the lock to the token if it is free and returns the
+	// next fencing token, or 0 if the lock is held.
+	lockScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+	// renewScript extends the lease if the lock is still held with the token.
+	renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+	// unlockScript deletes the lock if it is held with the token and notifies
+	// the waiters.
+	unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	redis.call('PUBLISH', KEYS[1], 'released')
+	return 1
+end
+return 0
+`)
+)
+
+type redisSync struct {
+	options sync.Options
+	client  redis.UniversalClient
+
+	mtx   gosync.Mutex
+	locks map[string]*redisLock
+}
+
+type redisLock struct {
+	key   string
+	token string
+	fence uint64
+	ttl   time.Duration
+
+	once gosync.Once
+	// closed when the lock is released or lost
+	exit chan struct{}
+	// closed when the lease could not be renewed
+	lost chan struct{}
+}
+
+type redisLeader struct {
+	id    string
+	s     *redisSync
+	lock  *redisLock
+	once  gosync.Once
+	state chan bool
+	// closed once the lock is no longer watched
+	done chan struct{}
+}
+
+func (r *redisSync) key(id string) string {
+	// the hash tag keeps the lock and its fencing counter in one slot
+	return "{" + r.options.Prefix + id + "}"
+}
+
+func (r *redisSync) Init(opts ...sync.Option) error {
+	for _, o := range opts {
+		o(&r.options)
+	}
+	if len(r.options.Prefix) == 0 {
+		r.options.Prefix = DefaultPrefix
+	}
+	if r.client != nil {
+		if err := r.client.Close(); err != nil {
+			log.Errorf("sync: error closing redis client: %v", err)
+		}
+	}
+	r.client = newUniversalClient(r.options)
+	return nil
+}
+
+func (r *redisSync) Options() sync.Options {
+	return r.options
+}
+
+// acquire tries to set the lock once and returns its fencing token.
+func (r *redisSync) acquire(ctx context.Context, key, token string, ttl time.Duration) (uint64, error) {
+	fence, err := lockScript.Run(ctx, r.client, []string{key, key + ":fence"}, token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(fence), nil
+}
+
+func (r *redisSync) lock(key string, options sync.LockOptions) (*redisLock, error) {
+	ctx := context.Background()
+
+	ttl := options.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	} else if ttl < time.Millisecond {
+		return nil, ErrInvalidTTL
+	}
+	token := uuid.New().String()
+
+	var deadline <-chan time.Time
+	if options.Wait > 0 {
+		timer := time.NewTimer(options.Wait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	// listen for releases before the first attempt so none are missed
+	sub := r.client.Subscribe(ctx, key)
+	defer sub.Close()
+	released := sub.Channel()
+
+	for {
+		fence, err := r.acquire(ctx, key, token, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if fence > 0 {
+			lk := &redisLock{
+				key:   key,
+				token: token,
+				fence: fence,
+				ttl:   ttl,
+				exit:  make(chan struct{}),
+				lost:  make(chan struct{}),
+			}
+			go r.renew(lk)
+			return lk, nil
+		}
+
+		select {
+		case <-released:
+		case <-time.After(retryInterval):
+		case <-deadline:
+			return nil, sync.ErrLockTimeout
+		}
+	}
+}
+
+// renew keeps the lease of the lock alive until it is released.
+func (r *redisSync) renew(lk *redisLock) {
+	t := time.NewTicker(lk.ttl / 3)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-lk.exit:
+			return
+		case <-t.C:
+		}
+
+		ok, err := renewScript.Run(context.Background(), r.client, []string{lk.key}, lk.token, lk.ttl.Milliseconds()).Int64()
+		if err != nil {
+			// the lease may still be valid, try again on the next tick
+			log.Errorf("sync: error renewing lock %s: %v", lk.key, err)
+			continue
+		}
+		if ok == 0 {
+			log.Errorf("sync: lock %s lost", lk.key)
+			close(lk.lost)
+			lk.stop()
+			return
+		}
+	}
+}
+
+func (lk *redisLock) stop() {
+	lk.once.Do(func() {
+		close(lk.exit)
+	})
+}
+
+func (r *redisSync) release(lk *redisLock) error {
+	lk.stop()
+	ok, err := unlockScript.Run(context.Background(), r.client, []string{lk.key}, lk.token).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (r *redisSync) Lock(id string, opts ...sync.LockOption) error {
+	var options sync.LockOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	lk, err := r.lock(r.key(id), options)
+	if err != nil {
+		return err
+	}
+
+	r.mtx.Lock()
+	r.locks[id] = lk
+	r.mtx.Unlock()
+	return nil
+}
+
+func (r *redisSync) Unlock(id string) error {
+	r.mtx.Lock()
+	lk, ok := r.locks[id]
+	delete(r.locks, id)
+	r.mtx.Unlock()
+
+	if !ok {
+		return ErrLockNotFound
+	}
+	return r.release(lk)
+}
+
+// Token returns the fencing token of the held lock. Tokens increase with
+// every grant of the lock, so writes guarded with them can reject a stale
+// holder.
+func (r *redisSync) Token(id string) (uint64, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	lk, ok := r.locks[id]
+	if !ok {
+		return 0, ErrLockNotFound
+	}
+	return lk.fence, nil
+}
+
+// Lost returns a channel closed once the held lock is released, or lost
+// because its lease could not be renewed.
+func (r *redisSync) Lost(id string) (<-chan struct{}, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	lk, ok := r.locks[id]
+	if !ok {
+		return nil, ErrLockNotFound
+	}
+	return lk.exit, nil
+}
+
+func (r *redisSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
+	var options sync.LeaderOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	lk, err := r.lock(r.key("leader/"+id), sync.LockOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	l := &redisLeader{
+		id:    id,
+		s:     r,
+		lock:  lk,
+		state: make(chan bool, 1),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(l.done)
+		// exit is closed on release too, so resigning ends the watch
+		select {
+		case <-lk.lost:
+		case <-lk.exit:
+		}
+		l.signal()
+	}()
+	return l, nil
+}
+
+func (l *redisLeader) signal() {
+	l.once.Do(func() {
+		l.state <- true
+		close(l.state)
+	})
+}
+
+// Status is signalled once leadership is lost or resigned.
+func (l *redisLeader) Status() chan bool {
+	return l.state
+}
+
+func (l *redisLeader) Resign() error {
+	defer l.signal()
+	return l.s.release(l.lock)
+}
+
+// Token returns the fencing token of the leadership.
+func (l *redisLeader) Token() uint64 {
+	return l.lock.fence
+}
+
+func (r *redisSync) String() string {
+	return "redis"
+}
+
+func NewSync(opts ...sync.Option) sync.Sync {
+	options := sync.Options{
+		Prefix: DefaultPrefix,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &redisSync{
+		options: options,
+		client:  newUniversalClient(options),
+		locks:   make(map[string]*redisLock),
+	}
+}
+
+func newUniversalClient(o sync.Options) redis.UniversalClient {
+	if len(o.Nodes) <= 1 {
+		addr := "redis://127.0.0.1:6379"
+		if len(o.Nodes) > 0 {
+			addr = o.Nodes[0]
+		}
+
+		redisOptions, err := redis.ParseURL(addr)
+		if err != nil {
+			redisOptions = &redis.Options{Addr: addr}
+		}
+		if o.TLSConfig != nil {
+			redisOptions.TLSConfig = o.TLSConfig
+		}
+
+		return redis.NewClient(redisOptions)
+	}
+
+	return redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:     o.Nodes,
+		TLSConfig: o.TLSConfig,
+	})
+}
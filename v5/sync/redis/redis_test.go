@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/open-micro/plugins/v5/sync/fence/synctest"
+	"go-micro.org/v5/sync"
+)
+
+func newTestSync(t *testing.T) sync.Sync {
+	url := os.Getenv("REDIS_URL")
+	if len(url) == 0 {
+		t.Skip("REDIS_URL not defined")
+	}
+	return NewSync(sync.Nodes(url), sync.Prefix("micro-test/sync/"))
+}
+
+func TestLock(t *testing.T) {
+	s := newTestSync(t)
+	other := newTestSync(t)
+
+	if err := s.Lock("lock", sync.LockTTL(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.(*redisSync).Token("lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// held with another token
+	if err := other.Unlock("lock"); err != ErrLockNotFound {
+		t.Fatalf("expected lock not found, got %v", err)
+	}
+	if err := other.Lock("lock", sync.LockWait(100*time.Millisecond)); err != sync.ErrLockTimeout {
+		t.Fatalf("expected lock timeout, got %v", err)
+	}
+
+	// the lease is renewed past its ttl while held
+	time.Sleep(1500 * time.Millisecond)
+	if err := other.Lock("lock", sync.LockWait(100*time.Millisecond)); err != sync.ErrLockTimeout {
+		t.Fatalf("expected lock timeout, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- other.Lock("lock", sync.LockWait(5*time.Second))
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := s.Unlock("lock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := other.(*redisSync).Token("lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second <= first {
+		t.Fatalf("expected fencing token to increase, got %d after %d", second, first)
+	}
+	if err := other.Unlock("lock"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInvalidTTL(t *testing.T) {
+	// rejected before redis is reached
+	s := NewSync(sync.Nodes("redis://127.0.0.1:1"))
+	if err := s.Lock("lock", sync.LockTTL(time.Microsecond)); err != ErrInvalidTTL {
+		t.Fatalf("expected invalid ttl, got %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeader(t *testing.T) {
+	s := newTestSync(t)
+
+	l, err := s.Leader("leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-l.Status():
+		t.Fatal("unexpected status change")
+	default:
+	}
+
+	if err := l.Resign(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.Status():
+	case <-time.After(time.Second):
+		t.Fatal("expected status change after resign")
+	}
+	select {
+	case <-l.(*redisLeader).done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the leader to stop watching its lock after resign")
+	}
+
+	// leadership can be acquired again
+	l, err = s.Leader("leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Resign(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	synctest.Run(t, newTestSync(t), synctest.Options{})
+}